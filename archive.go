@@ -0,0 +1,294 @@
+package dbf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// An archive written by Table.Archive holds, after archiveMagic:
+//
+//	nrec, chunk rows (uint32), column count and widths (uint16)
+//	the header, as a stream
+//	each chunk of rows, one block per column
+//	whatever follows the last record, as a stream
+//	a flag byte, 1 if a memo file follows as a stream
+//	CRC-32s of the table and memo files (uint32)
+//
+// A block is a uint32 length and that many bytes of gzip data; a stream is a
+// run of blocks ended by one of length 0.  Integers are little-endian.
+var archiveMagic = []byte("DBFARC\x00\x01")
+
+// archiveChunk is roughly how many bytes of the table are compressed at a
+// time, which bounds the memory Archive and Restore use.
+const archiveChunk = 4 << 20
+
+// Archive writes a compact copy of the table to w, from which Restore
+// recreates the table and memo files byte for byte.  Records are split into
+// columns, the deleted flag and each field, and each column is compressed on
+// its own: the values of one field resemble each other far more than
+// neighbouring fields do, so old tables typically shrink several times
+// better than by compressing the file whole.  Records are compressed a few
+// megabytes at a time, so tables of any size can be archived.  A table
+// shorter than its header describes can't be archived, and neither can one
+// whose record length WithLenient overruled, since it couldn't be restored
+// as it is.
+func (t *Table) Archive(w io.Writer) error {
+	if t.headerRecordlen != t.recordlen {
+		return fmt.Errorf("header gives a record length of %d bytes, but the fields take up %d", t.headerRecordlen, t.datalen)
+	}
+	end := t.recordOffset(t.nrec)
+	if t.size < end {
+		return fmt.Errorf("table is truncated: header promises %d records, which take up %d bytes", t.nrec, end)
+	}
+	widths := []int{1}
+	for _, f := range t.fields {
+		widths = append(widths, int(f.Len))
+	}
+	if pad := int(t.recordlen) - t.datalen; pad > 0 {
+		widths = append(widths, pad)
+	}
+	rows := archiveChunk / int(t.recordlen)
+	if rows == 0 {
+		rows = 1
+	}
+
+	aw := &archiveWriter{w: bufio.NewWriter(w)}
+	aw.zw, _ = gzip.NewWriterLevel(&aw.buf, gzip.BestCompression)
+	aw.write(archiveMagic)
+	aw.uint(uint32(t.nrec), uint32(rows), uint16(len(widths)))
+	for _, n := range widths {
+		aw.uint(uint16(n))
+	}
+
+	sum := crc32.NewIEEE()
+	src := io.TeeReader(io.NewSectionReader(t.src, 0, t.size), sum)
+	aw.stream(io.LimitReader(src, int64(t.headerlen)))
+	buf := make([]byte, rows*int(t.recordlen))
+	col := make([]byte, len(buf))
+	for done := 0; done < t.nrec && aw.err == nil; done += rows {
+		n := rows
+		if t.nrec-done < n {
+			n = t.nrec - done
+		}
+		if _, err := io.ReadFull(src, buf[:n*int(t.recordlen)]); err != nil {
+			return err
+		}
+		pos := 0
+		for _, width := range widths {
+			col = col[:0]
+			for i := 0; i < n; i++ {
+				off := i*int(t.recordlen) + pos
+				col = append(col, buf[off:off+width]...)
+			}
+			aw.block(col)
+			pos += width
+		}
+	}
+	aw.stream(src)
+
+	memoSum := crc32.NewIEEE()
+	if t.memo == nil {
+		aw.write([]byte{0})
+	} else {
+		aw.write([]byte{1})
+		aw.stream(io.TeeReader(io.NewSectionReader(t.memo.r, 0, math.MaxInt64), memoSum))
+	}
+	aw.uint(sum.Sum32(), memoSum.Sum32())
+	if aw.err != nil {
+		return aw.err
+	}
+	return aw.w.Flush()
+}
+
+// An archiveWriter writes the parts of an archive, remembering the first
+// error so that the caller needs only check at the end.
+type archiveWriter struct {
+	w   *bufio.Writer
+	buf bytes.Buffer
+	zw  *gzip.Writer
+	err error
+}
+
+func (aw *archiveWriter) write(p []byte) {
+	if aw.err == nil {
+		_, aw.err = aw.w.Write(p)
+	}
+}
+
+func (aw *archiveWriter) uint(vs ...interface{}) {
+	for _, v := range vs {
+		if aw.err == nil {
+			aw.err = binary.Write(aw.w, binary.LittleEndian, v)
+		}
+	}
+}
+
+// block compresses p and writes it as a block.
+func (aw *archiveWriter) block(p []byte) {
+	if aw.err != nil {
+		return
+	}
+	aw.buf.Reset()
+	aw.zw.Reset(&aw.buf)
+	if _, aw.err = aw.zw.Write(p); aw.err != nil {
+		return
+	}
+	if aw.err = aw.zw.Close(); aw.err != nil {
+		return
+	}
+	aw.uint(uint32(aw.buf.Len()))
+	aw.write(aw.buf.Bytes())
+}
+
+// stream writes everything r holds as a stream.
+func (aw *archiveWriter) stream(r io.Reader) {
+	buf := make([]byte, archiveChunk)
+	for aw.err == nil {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			aw.block(buf[:n])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil && aw.err == nil {
+			aw.err = err
+		}
+	}
+	aw.uint(uint32(0))
+}
+
+// Restore recreates the table written by Table.Archive from r, writing the
+// table file to dbf and its memo file, if it had one, to memo.  memo may be
+// nil if the table had no memo file; it is an error if it did.  The files
+// are checked against the checksums recorded when the archive was written,
+// and an archive that has been tampered with or damaged is an error, though
+// by then some of it may have been written.
+func Restore(r io.Reader, dbf, memo io.Writer) error {
+	ar := &archiveReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(ar.r, magic); err != nil || !bytes.Equal(magic, archiveMagic) {
+		return errors.New("not a dbf archive")
+	}
+	var nrec, rows uint32
+	var ncols uint16
+	ar.uint(&nrec, &rows, &ncols)
+	widths := make([]uint16, ncols)
+	ar.uint(widths)
+	if ar.err != nil {
+		return ar.err
+	} else if rows == 0 {
+		return errors.New("damaged dbf archive: chunks of 0 records")
+	}
+	recordlen := 0
+	for _, n := range widths {
+		recordlen += int(n)
+	}
+
+	sum := crc32.NewIEEE()
+	bw := bufio.NewWriter(dbf)
+	w := io.MultiWriter(bw, sum)
+	ar.stream(w)
+	cols := make([][]byte, ncols)
+	rec := make([]byte, recordlen)
+	for done := uint32(0); done < nrec && ar.err == nil; done += rows {
+		n := rows
+		if nrec-done < n {
+			n = nrec - done
+		}
+		for j, width := range widths {
+			if cols[j] = ar.block(); ar.err == nil && len(cols[j]) != int(n)*int(width) {
+				ar.err = fmt.Errorf("damaged dbf archive: column %d holds %d bytes, expected %d", j, len(cols[j]), int(n)*int(width))
+			}
+		}
+		for i := 0; i < int(n) && ar.err == nil; i++ {
+			pos := 0
+			for j, width := range widths {
+				pos += copy(rec[pos:], cols[j][i*int(width):(i+1)*int(width)])
+			}
+			_, ar.err = w.Write(rec)
+		}
+	}
+	ar.stream(w)
+
+	var hasMemo [1]byte
+	if ar.err == nil {
+		_, ar.err = io.ReadFull(ar.r, hasMemo[:])
+	}
+	memoSum := crc32.NewIEEE()
+	if hasMemo[0] == 1 {
+		if memo == nil {
+			return errors.New("archived table has a memo file, but no writer was given for it")
+		}
+		ar.stream(io.MultiWriter(memo, memoSum))
+	}
+	var want, wantMemo uint32
+	ar.uint(&want, &wantMemo)
+	if ar.err != nil {
+		return ar.err
+	} else if want != sum.Sum32() || wantMemo != memoSum.Sum32() {
+		return errors.New("damaged dbf archive: restored files don't match their checksums")
+	}
+	return bw.Flush()
+}
+
+// An archiveReader reads the parts of an archive, remembering the first
+// error.
+type archiveReader struct {
+	r   *bufio.Reader
+	zr  *gzip.Reader
+	err error
+}
+
+func (ar *archiveReader) uint(vs ...interface{}) {
+	for _, v := range vs {
+		if ar.err == nil {
+			ar.err = binary.Read(ar.r, binary.LittleEndian, v)
+		}
+	}
+	if ar.err == io.EOF {
+		ar.err = io.ErrUnexpectedEOF
+	}
+}
+
+// block reads and decompresses a block.  It returns nil for the block that
+// ends a stream.
+func (ar *archiveReader) block() []byte {
+	var n uint32
+	if ar.uint(&n); ar.err != nil || n == 0 {
+		return nil
+	}
+	lr := io.LimitReader(ar.r, int64(n))
+	if ar.zr == nil {
+		ar.zr, ar.err = gzip.NewReader(lr)
+	} else {
+		ar.err = ar.zr.Reset(lr)
+	}
+	if ar.err != nil {
+		return nil
+	}
+	ar.zr.Multistream(false)
+	var p []byte
+	if p, ar.err = ioutil.ReadAll(ar.zr); ar.err == nil {
+		_, ar.err = io.Copy(ioutil.Discard, lr)
+	}
+	return p
+}
+
+// stream copies a stream to w.
+func (ar *archiveReader) stream(w io.Writer) {
+	for ar.err == nil {
+		p := ar.block()
+		if p == nil {
+			return
+		}
+		_, ar.err = w.Write(p)
+	}
+}
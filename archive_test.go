@@ -0,0 +1,85 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	var recs []string
+	for i := 0; i < 5000; i++ {
+		recs = append(recs, fmt.Sprintf(" %6d%-10s%8.2f", i, []string{"north", "south", "east"}[i%3], float64(i%100)/4))
+	}
+	recs[7] = "*" + recs[7][1:]
+	data := buildTable([]Field{mustField("ID", 'N', 6, 0), mustField("REGION", 'C', 10, 0), mustField("AMOUNT", 'N', 8, 2)}, recs...)
+	data = append(data, "trailing junk"...)
+
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var archive bytes.Buffer
+	if err = tbl.Archive(&archive); err != nil {
+		t.Fatalf("Archive: %s", err)
+	}
+	if archive.Len()*4 > len(data) {
+		t.Errorf("archive takes up %d bytes of a %d byte table", archive.Len(), len(data))
+	}
+	var restored bytes.Buffer
+	if err = Restore(bytes.NewReader(archive.Bytes()), &restored, nil); err != nil {
+		t.Fatalf("Restore: %s", err)
+	}
+	if !bytes.Equal(restored.Bytes(), data) {
+		t.Errorf("restored table differs from the original")
+	}
+
+	damaged := append([]byte(nil), archive.Bytes()...)
+	damaged[len(damaged)-1] ^= 1
+	if err = Restore(bytes.NewReader(damaged), &restored, nil); err == nil {
+		t.Errorf("expected an error for a damaged archive")
+	}
+	if err = Restore(strings.NewReader("not an archive"), &restored, nil); err == nil {
+		t.Errorf("expected an error for input that isn't an archive")
+	}
+}
+
+func TestArchiveMemo(t *testing.T) {
+	dbt := make([]byte, 2*512)
+	dbt[16] = 0x03
+	copy(dbt[512:], "hello world\x1A\x1A")
+	data := memoTable(0x83, "         1", "          ")
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), WithMemoBytes(dbt))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var archive bytes.Buffer
+	if err = tbl.Archive(&archive); err != nil {
+		t.Fatalf("Archive: %s", err)
+	}
+
+	var restored, memo bytes.Buffer
+	if err = Restore(bytes.NewReader(archive.Bytes()), &restored, &memo); err != nil {
+		t.Fatalf("Restore: %s", err)
+	}
+	if !bytes.Equal(restored.Bytes(), data) || !bytes.Equal(memo.Bytes(), dbt) {
+		t.Errorf("restored files differ from the originals")
+	}
+	if err = Restore(bytes.NewReader(archive.Bytes()), &restored, nil); err == nil {
+		t.Errorf("expected an error when the memo file has nowhere to go")
+	}
+}
+
+func TestArchiveShortRecordLength(t *testing.T) {
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0)}, "   1", "   2")
+	data[10], data[11] = 0, 0
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), WithLenient())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var buf bytes.Buffer
+	if err = tbl.Archive(&buf); err == nil || !strings.Contains(err.Error(), "record length of 0") {
+		t.Fatalf("expected an error for a record length of 0, got %v", err)
+	}
+}
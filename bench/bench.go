@@ -0,0 +1,207 @@
+// Package bench generates synthetic tables and measures how fast the dbf
+// package reads, decodes and exports them, to catch performance regressions
+// and to help size hardware for a workload.
+package bench
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"time"
+
+	"github.com/eentzel/dbf"
+)
+
+// A Config describes a synthetic table.
+type Config struct {
+	Records int // number of records
+	Fields  int // number of fields, cycling through C, N, F, D and L
+	Width   int // width of the character fields, 10 if 0
+}
+
+// Generate builds a table as described by c, filled with varied but
+// deterministic values.
+func Generate(c Config) ([]byte, error) {
+	if c.Width == 0 {
+		c.Width = 10
+	}
+	if c.Width > 254 {
+		return nil, fmt.Errorf("character fields can't be %d bytes wide", c.Width)
+	}
+	fields := make([]dbf.Field, c.Fields)
+	for j := range fields {
+		var err error
+		name := fmt.Sprintf("F%d", j)
+		switch j % 5 {
+		case 0:
+			fields[j], err = dbf.NewField(name, 'C', uint8(c.Width), 0)
+		case 1:
+			fields[j], err = dbf.NewField(name, 'N', 10, 0)
+		case 2:
+			fields[j], err = dbf.NewField(name, 'F', 12, 3)
+		case 3:
+			fields[j], err = dbf.NewField(name, 'D', 8, 0)
+		case 4:
+			fields[j], err = dbf.NewField(name, 'L', 1, 0)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buf buffer
+	var opts []dbf.WriterOption
+	if c.Fields > 255 {
+		opts = append(opts, dbf.ClipperFields())
+	}
+	w, err := dbf.NewWriter(&buf, fields, opts...)
+	if err != nil {
+		return nil, err
+	}
+	day := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < c.Records; i++ {
+		rec := make(dbf.Record, c.Fields)
+		for j := range fields {
+			name := fmt.Sprintf("F%d", j)
+			switch j % 5 {
+			case 0:
+				s := fmt.Sprintf("value %d", i*31+j)
+				if len(s) > c.Width {
+					s = s[:c.Width]
+				}
+				rec[name] = s
+			case 1:
+				rec[name] = int64(i*7 + j)
+			case 2:
+				rec[name] = float64(i) / 8
+			case 3:
+				rec[name] = day.AddDate(0, 0, i%3650)
+			case 4:
+				rec[name] = i%2 == 0
+			}
+		}
+		if err = w.Write(rec); err != nil {
+			return nil, err
+		}
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.data, nil
+}
+
+// A Result is the outcome of one measurement.
+type Result struct {
+	Name     string
+	Records  int
+	Bytes    int64 // size of the table
+	Duration time.Duration
+	Allocs   uint64 // heap allocations
+	Alloced  uint64 // bytes allocated
+}
+
+func (r Result) String() string {
+	secs := r.Duration.Seconds()
+	if secs == 0 || r.Records == 0 {
+		return fmt.Sprintf("%s: %d records in %s", r.Name, r.Records, r.Duration)
+	}
+	return fmt.Sprintf("%s: %d records in %s, %.0f records/s, %.1f MB/s, %d allocs/record, %d B/record",
+		r.Name, r.Records, r.Duration, float64(r.Records)/secs, float64(r.Bytes)/secs/1e6,
+		r.Allocs/uint64(r.Records), r.Alloced/uint64(r.Records))
+}
+
+// Run measures, on the table in data, random access through Reader.Read,
+// sequential reading through Table.Iterate, and CSV export through
+// Table.ToCSV.
+func Run(data []byte) ([]Result, error) {
+	r, err := dbf.NewReaderFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	cases := []struct {
+		name string
+		fn   func() error
+	}{
+		{"read", func() error {
+			for i := 0; i < r.Len(); i++ {
+				if _, err := r.Record(i); err != nil && err != dbf.ErrDeleted {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"iterate", func() error {
+			it := r.Iterate()
+			for {
+				if _, err := it.Next(); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+		}},
+		{"csv", func() error {
+			return r.ToCSV(ioutil.Discard)
+		}},
+	}
+	var results []Result
+	for _, c := range cases {
+		res, err := measure(c.name, r.Len(), int64(len(data)), c.fn)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// measure times fn and counts the memory it allocates.
+func measure(name string, records int, size int64, fn func() error) (Result, error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	if err := fn(); err != nil {
+		return Result{}, fmt.Errorf("%s: %s", name, err)
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return Result{
+		Name:     name,
+		Records:  records,
+		Bytes:    size,
+		Duration: elapsed,
+		Allocs:   after.Mallocs - before.Mallocs,
+		Alloced:  after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
+
+// buffer is an in-memory io.WriteSeeker for the Writer.
+type buffer struct {
+	data []byte
+	pos  int
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	if need := b.pos + len(p); need > len(b.data) {
+		b.data = append(b.data, make([]byte, need-len(b.data))...)
+	}
+	copy(b.data[b.pos:], p)
+	b.pos += len(p)
+	return len(p), nil
+}
+
+func (b *buffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 1:
+		offset += int64(b.pos)
+	case 2:
+		offset += int64(len(b.data))
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position %d", offset)
+	}
+	b.pos = int(offset)
+	return offset, nil
+}
@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/eentzel/dbf"
+)
+
+func TestGenerate(t *testing.T) {
+	data, err := Generate(Config{Records: 100, Fields: 12})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r, err := dbf.NewReaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if r.Len() != 100 || len(r.Fields()) != 12 {
+		t.Fatalf("generated %d records of %d fields", r.Len(), len(r.Fields()))
+	}
+	results, err := Run(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(results) != 3 || results[0].Records != 100 {
+		t.Fatalf("unexpected results %v", results)
+	}
+}
+
+func benchmarkTable(b *testing.B) *dbf.Reader {
+	data, err := Generate(Config{Records: 10000, Fields: 20})
+	if err != nil {
+		b.Fatalf("%s", err)
+	}
+	r, err := dbf.NewReaderFromBytes(data)
+	if err != nil {
+		b.Fatalf("%s", err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	return r
+}
+
+func BenchmarkRead(b *testing.B) {
+	r := benchmarkTable(b)
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < r.Len(); i++ {
+			if _, err := r.Record(i); err != nil {
+				b.Fatalf("%s", err)
+			}
+		}
+	}
+}
+
+func BenchmarkIterate(b *testing.B) {
+	r := benchmarkTable(b)
+	for n := 0; n < b.N; n++ {
+		it := r.Iterate()
+		for {
+			if _, err := it.Next(); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatalf("%s", err)
+			}
+		}
+	}
+}
+
+func BenchmarkCSV(b *testing.B) {
+	r := benchmarkTable(b)
+	for n := 0; n < b.N; n++ {
+		if err := r.ToCSV(ioutil.Discard); err != nil {
+			b.Fatalf("%s", err)
+		}
+	}
+}
+
+func BenchmarkSelect(b *testing.B) {
+	r := benchmarkTable(b)
+	view, err := r.Select(r.FieldNames()[0])
+	if err != nil {
+		b.Fatalf("%s", err)
+	}
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < view.Len(); i++ {
+			if _, err := view.Record(i); err != nil {
+				b.Fatalf("%s", err)
+			}
+		}
+	}
+}
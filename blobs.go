@@ -0,0 +1,116 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ExtractBlobs writes the binary contents of the memo file to dir, one file
+// per value: every general (G) and picture (P) field, and the memo fields
+// that hold binary rather than text.  It is how embedded documents and
+// images are rescued from FoxPro applications.  Deleted records, blank
+// fields and text memos are skipped.
+//
+// Files are named by nameTemplate, in which {record} is replaced by the
+// record number, {field} by the field name and {ext} by an extension
+// guessed from the contents ("bin" if they aren't recognized).  The template
+// must include {record} and {field}, so that no two values share a file; ""
+// means "{record}_{field}.{ext}".  dir is created if it doesn't exist.
+// ExtractBlobs returns the paths of the files it wrote.
+func (t *Table) ExtractBlobs(dir, nameTemplate string) ([]string, error) {
+	if nameTemplate == "" {
+		nameTemplate = "{record}_{field}.{ext}"
+	}
+	if !strings.Contains(nameTemplate, "{record}") || !strings.Contains(nameTemplate, "{field}") {
+		return nil, fmt.Errorf("name template %q must include {record} and {field}", nameTemplate)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for i := 0; i < t.nrec; i++ {
+		buf, err := t.readRaw(i)
+		if err != nil {
+			return paths, err
+		}
+		if buf[0] == '*' {
+			continue
+		}
+		pos := 1
+		for j, f := range t.fields {
+			name, raw := t.FieldName(j), buf[pos:pos+int(f.Len)]
+			pos += int(f.Len)
+			if !f.isMemo() || !t.isSelected(j) {
+				continue
+			}
+			data, err := t.readBlob(f, name, raw)
+			if err != nil {
+				return paths, fmt.Errorf("record %d, field %s: %s", i, name, err)
+			} else if len(data) == 0 {
+				continue
+			}
+			path := filepath.Join(dir, strings.NewReplacer(
+				"{record}", strconv.Itoa(i),
+				"{field}", name,
+				"{ext}", blobExt(data),
+			).Replace(nameTemplate))
+			if err := ioutil.WriteFile(path, data, 0644); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// readBlob returns the contents of a G, P or M field if they are binary,
+// and nil for a blank field or a text memo.
+func (t *Table) readBlob(f Field, name string, raw []byte) ([]byte, error) {
+	if c, ok := t.crypts[name]; ok {
+		var err error
+		if raw, err = c.Decrypt(raw); err != nil {
+			return nil, err
+		}
+	}
+	v, err := t.readMemo(raw, t.limits.limit(name, f))
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := v.([]byte); ok {
+		return b, nil
+	} else if s, _ := v.(string); f.Type != 'M' {
+		return []byte(s), nil
+	}
+	return nil, nil
+}
+
+// blobMagic maps the leading bytes of common embedded files to the
+// extension they are written with.
+var blobMagic = []struct {
+	magic, ext string
+}{
+	{"\x89PNG\r\n\x1a\n", "png"},
+	{"\xFF\xD8\xFF", "jpg"},
+	{"GIF8", "gif"},
+	{"BM", "bmp"},
+	{"%PDF", "pdf"},
+	{"PK\x03\x04", "zip"},
+	{"\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1", "ole"},
+	{"{\\rtf", "rtf"},
+}
+
+// blobExt guesses the extension of a blob from its contents.
+func blobExt(data []byte) string {
+	for _, m := range blobMagic {
+		if bytes.HasPrefix(data, []byte(m.magic)) {
+			return m.ext
+		}
+	}
+	return "bin"
+}
@@ -0,0 +1,69 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExtractBlobs(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nimage")
+	fpt := make([]byte, 512+4*64)
+	binary.BigEndian.PutUint16(fpt[6:], 64)
+	for i, b := range []struct {
+		typ  byte
+		data []byte
+	}{{1, []byte("text")}, {0, png}, {2, []byte("object")}, {2, []byte("binary memo")}} {
+		off := 512 + 64*i
+		copy(fpt[off:], []byte{0, 0, 0, b.typ, 0, 0, 0, byte(len(b.data))})
+		copy(fpt[off+8:], b.data)
+	}
+
+	fields := []Field{mustField("ID", 'N', 1, 0), mustField("NOTES", 'M', 10, 0), mustField("PIC", 'G', 10, 0)}
+	data := buildTable(fields,
+		" 1         8         9",
+		" 2        11          ",
+		"*3        10        10",
+	)
+	data[0] = 0xF5
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), WithMemo(bytes.NewReader(fpt)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	if rec, err := tbl.Record(0); err != nil || !reflect.DeepEqual(rec["PIC"], png) {
+		t.Fatalf("expected the general field to read as its contents, got %v, %v", rec, err)
+	}
+
+	dir, err := ioutil.TempDir("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths, err := tbl.ExtractBlobs(filepath.Join(dir, "out"), "")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := map[string][]byte{"0_PIC.png": png, "1_NOTES.bin": []byte("binary memo")}
+	if len(paths) != len(expected) {
+		t.Fatalf("expected %d files, got %v", len(expected), paths)
+	}
+	for _, path := range paths {
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if want, ok := expected[filepath.Base(path)]; !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s holds %q", filepath.Base(path), got)
+		}
+	}
+
+	if _, err := tbl.ExtractBlobs(dir, "{field}.{ext}"); err == nil {
+		t.Errorf("expected an error for a template without {record}")
+	}
+}
@@ -0,0 +1,135 @@
+package dbf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A RecordBuilder holds a record being changed and keeps track of which of
+// its fields have been given new values, so that Editor.Apply writes only
+// those fields' bytes and leaves the rest of the record untouched.
+type RecordBuilder struct {
+	base  Record
+	rec   Record
+	dirty map[string]bool
+	err   error // from setting a field the record doesn't have
+}
+
+// NewRecordBuilder returns a RecordBuilder starting from the values in rec,
+// which it doesn't modify.
+func NewRecordBuilder(rec Record) *RecordBuilder {
+	b := &RecordBuilder{base: make(Record, len(rec)), rec: make(Record, len(rec)), dirty: map[string]bool{}}
+	for name, v := range rec {
+		b.base[name], b.rec[name] = v, v
+	}
+	return b
+}
+
+// Edit returns a RecordBuilder starting from record i as stored, whether or
+// not it is deleted.
+func (t *Table) Edit(i int) (*RecordBuilder, error) {
+	raw, err := t.readRaw(i)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := t.decodeRecord(i, raw)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecordBuilder(rec), nil
+}
+
+// Set gives field name the value v.  Setting a field back to its starting
+// value undoes the change.  Setting a field the starting record doesn't have
+// is an error, which Apply reports.
+func (b *RecordBuilder) Set(name string, v interface{}) {
+	if _, ok := b.base[name]; !ok {
+		if b.err == nil {
+			b.err = fmt.Errorf("record has no field %q", name)
+		}
+		return
+	}
+	b.rec[name] = v
+	b.dirty[name] = !sameValue(v, b.base[name])
+	if !b.dirty[name] {
+		delete(b.dirty, name)
+	}
+}
+
+// Get returns the current value of field name.
+func (b *RecordBuilder) Get(name string) interface{} {
+	return b.rec[name]
+}
+
+// Changed returns the names of the fields that have been given new values,
+// in alphabetical order, for instance to tell which index keys need
+// updating, since Apply doesn't maintain index files.
+func (b *RecordBuilder) Changed() []string {
+	var names []string
+	for name := range b.dirty {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Record returns the current values of the record.
+func (b *RecordBuilder) Record() Record {
+	rec := make(Record, len(b.rec))
+	for name, v := range b.rec {
+		rec[name] = v
+	}
+	return rec
+}
+
+// Apply writes the changed fields of b to record i, and sets the table's
+// modification date to today.  Unlike Update, it doesn't read the record
+// first: only the byte ranges of the changed fields are written, adjacent
+// fields together, and readers of the Editor's table see all of them or
+// none.  Values are encoded as by Update, and nothing is written if any of
+// them doesn't fit.  Once applied, the changes become b's new starting point.
+func (e *Editor) Apply(i int, b *RecordBuilder) error {
+	t := e.Table
+	if i < 0 || i >= t.nrec {
+		return fmt.Errorf("record %d is out of range, table has %d records", i, t.nrec)
+	} else if b.err != nil {
+		return b.err
+	}
+	for name := range b.dirty {
+		if t.fieldIndex(name) < 0 {
+			return fmt.Errorf("table has no field %q", name)
+		}
+	}
+	if len(b.dirty) == 0 {
+		return nil
+	}
+	var spans []span
+	pos := int64(1)
+	for j, f := range t.fields {
+		name := t.FieldName(j)
+		if b.dirty[name] {
+			enc, err := t.encodeValue(f, name, b.rec[name])
+			if err != nil {
+				return fmt.Errorf("field %s: %s", name, err)
+			}
+			off := t.recordOffset(i) + pos
+			if n := len(spans); n > 0 && spans[n-1].off+int64(len(spans[n-1].b)) == off {
+				spans[n-1].b = append(spans[n-1].b, enc...)
+			} else {
+				spans = append(spans, span{off, enc})
+			}
+		}
+		pos += int64(f.Len)
+	}
+	if err := writeSpans(e.w, spans); err != nil {
+		return err
+	}
+	t.count(MetricRecordsWritten, 1)
+	before := b.base
+	b.base = b.Record()
+	b.dirty = map[string]bool{}
+	if err := e.touch(); err != nil {
+		return err
+	}
+	return t.record("update", i, before, b.Record())
+}
@@ -0,0 +1,80 @@
+package dbf
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// writeLog records the writes made to a file.
+type writeLog struct {
+	*os.File
+	writes []int64
+}
+
+func (w *writeLog) WriteAt(p []byte, off int64) (int, error) {
+	w.writes = append(w.writes, off, int64(len(p)))
+	return w.File.WriteAt(p, off)
+}
+
+func TestEditorApply(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0), mustField("CITY", 'C', 4, 0)}
+	data := buildTable(fields, "   1alphaRome", "   2bravoOslo")
+	f.Write(data)
+	headerlen := int64(len(data) - 2*13 - 1)
+
+	w := &writeLog{File: f}
+	e, err := NewEditor(w)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	b, err := e.Edit(1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	b.Set("ID", 2)
+	b.Set("NAME", "zulu")
+	b.Set("CITY", "Oslo")
+	if changed := b.Changed(); !reflect.DeepEqual(changed, []string{"NAME"}) {
+		t.Fatalf("expected only NAME changed, got %v", changed)
+	}
+	if err = e.Apply(1, b); err != nil {
+		t.Fatalf("%s", err)
+	}
+	// The record write, then the modification date.
+	if want := []int64{headerlen + 13 + 4, 5, 1, 3}; !reflect.DeepEqual(w.writes, want) {
+		t.Fatalf("expected writes (offset, length) %v, got %v", want, w.writes)
+	}
+	if len(b.Changed()) != 0 {
+		t.Fatalf("changes left after Apply: %v", b.Changed())
+	}
+
+	b.Set("NAME", "too long")
+	if err = e.Apply(1, b); err == nil {
+		t.Fatalf("expected an error for a value that doesn't fit")
+	}
+	b.Set("NAME", "zulu")
+	if len(b.Changed()) != 0 {
+		t.Fatalf("setting a field back didn't undo the change: %v", b.Changed())
+	}
+	stray := NewRecordBuilder(Record{"ID": int64(2), "NAME": "zulu", "CITY": "Oslo"})
+	stray.Set("COLOUR", "red")
+	if err = e.Apply(1, stray); err == nil {
+		t.Fatalf("expected an error for setting a field the record doesn't have")
+	}
+	stray = NewRecordBuilder(Record{"COLOUR": "blue"})
+	stray.Set("COLOUR", "red")
+	if err = e.Apply(1, stray); err == nil {
+		t.Fatalf("expected an error for applying a field the table doesn't have")
+	}
+	if rec, err := e.Record(1); err != nil || rec["NAME"] != "zulu" || rec["CITY"] != "Oslo" {
+		t.Fatalf("wrong record after Apply: %v, %v", rec, err)
+	}
+}
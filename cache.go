@@ -0,0 +1,122 @@
+package dbf
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// A Cache shares open tables between goroutines, for servers that answer
+// many requests against the same few files.  Concurrent opens of the same
+// file wait for a single open, and later ones reuse its Table until the file
+// changes.  The zero Cache is ready to use and opens tables without options.
+type Cache struct {
+	opts    []Option
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// NewCache returns a Cache that opens tables with the given options.
+func NewCache(opts ...Option) *Cache {
+	return &Cache{opts: opts}
+}
+
+type cacheEntry struct {
+	ready chan struct{} // closed once t and err are set
+	t     *Table
+	err   error
+	size  int64 // of the file when it was opened
+	mod   time.Time
+	refs  int
+	stale bool // evicted, to be closed once no longer in use
+}
+
+// Open returns the table stored in the named file, opening it as Open does
+// unless the cache already holds it.  The file is checked on every call, and
+// a file whose size or modification time has changed is opened afresh;
+// changes that touch neither, or only the memo file, aren't noticed.  The
+// Table is shared, so it must not be closed: call release once done with it
+// instead, after which an evicted Table is closed by the last user to
+// release it.
+func (c *Cache) Open(name string) (t *Table, release func(), err error) {
+	if abs, err := filepath.Abs(name); err == nil {
+		name = abs
+	}
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	e := c.entries[name]
+	if e != nil && (e.size != fi.Size() || !e.mod.Equal(fi.ModTime())) {
+		c.evict(name, e)
+		e = nil
+	}
+	if e == nil {
+		e = &cacheEntry{ready: make(chan struct{}), size: fi.Size(), mod: fi.ModTime()}
+		if c.entries == nil {
+			c.entries = make(map[string]*cacheEntry)
+		}
+		c.entries[name] = e
+		e.refs++
+		c.mu.Unlock()
+
+		t, err := Open(name, c.opts...)
+		c.mu.Lock()
+		e.t, e.err = t, err
+		if err != nil && c.entries[name] == e {
+			delete(c.entries, name) // let the next caller try again
+		}
+		close(e.ready)
+	} else {
+		e.refs++
+	}
+	c.mu.Unlock()
+
+	<-e.ready
+	var once sync.Once
+	release = func() {
+		once.Do(func() { c.release(e) })
+	}
+	if e.err != nil {
+		release()
+		return nil, nil, e.err
+	}
+	return e.t, release, nil
+}
+
+// evict removes e from the cache, closing its table unless it's in use.
+// c.mu must be held.
+func (c *Cache) evict(name string, e *cacheEntry) error {
+	delete(c.entries, name)
+	e.stale = true
+	if e.refs == 0 && e.t != nil {
+		return e.t.Close()
+	}
+	return nil
+}
+
+func (c *Cache) release(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.refs--
+	if e.refs == 0 && e.stale && e.t != nil {
+		e.t.Close()
+	}
+}
+
+// Close evicts every table from the cache.  Tables still in use are closed
+// as they are released.  The cache remains usable, and opens tables afresh.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var first error
+	for name, e := range c.entries {
+		if err := c.evict(name, e); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
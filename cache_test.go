@@ -0,0 +1,77 @@
+package dbf
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(testData)
+	f.Close()
+
+	c := NewCache()
+	tables := make([]*Table, 8)
+	releases := make([]func(), len(tables))
+	var wg sync.WaitGroup
+	for i := range tables {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if tables[i], releases[i], err = c.Open(f.Name()); err != nil {
+				t.Errorf("%s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	old := tables[0]
+	for i, tbl := range tables {
+		if tbl != old {
+			t.Fatalf("Open %d returned a different Table", i)
+		}
+	}
+	for _, release := range releases[1:] {
+		release()
+	}
+
+	// Changing the file evicts the cached table, which stays usable until
+	// its last user releases it.
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(f.Name(), later, later); err != nil {
+		t.Fatalf("%s", err)
+	}
+	tbl, release, err := c.Open(f.Name())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer release()
+	if tbl == old {
+		t.Fatalf("Open returned the table of the old file")
+	}
+	if _, err = old.Record(0); err != nil {
+		t.Fatalf("evicted table in use was closed: %s", err)
+	}
+	releases[0]()
+	releases[0]()
+	if _, err = old.Record(0); err == nil {
+		t.Fatalf("expected the evicted table to be closed once released")
+	}
+
+	if _, _, err = c.Open(f.Name() + ".missing"); err == nil {
+		t.Fatalf("expected an error opening a missing file")
+	}
+	if err = c.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = tbl.Record(0); err != nil {
+		t.Fatalf("table in use was closed with the cache: %s", err)
+	}
+}
@@ -0,0 +1,239 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A FoxPro .cdx file holds several indexes, or tags, each a B-tree of
+// 512-byte nodes addressed by their offset in the file.  Each tag starts
+// with a 1024-byte header:
+//
+//	0    root node (uint32)
+//	12   key length (uint16)
+//	14   options: 0x01 unique, 0x08 has a FOR clause, 0x40 compound
+//	502  1 if the tag is descending
+//	510  length of the key and FOR expressions (uint16)
+//	512  key expression, then FOR expression, NUL-terminated
+//
+// The header at offset 0 is that of the compound index's tag directory,
+// whose keys are the tag names and whose record numbers are the offsets of
+// the tags' headers.  An interior node holds:
+//
+//	0    attributes: 0x01 root, 0x02 leaf
+//	2    number of keys (uint16)
+//	12   entries of a key, a record number and a child node (big-endian)
+//
+// A leaf node compresses its keys:
+//
+//	14   record number mask (uint32)
+//	18   duplicate count and trailing count masks (bytes)
+//	20   bits for record number, duplicate count and trailing count
+//	23   bytes per entry
+//	24   entries, each a little-endian bit field of the record number, the
+//	     bytes the key shares with the previous key and the trailing blanks
+//	     it drops
+//
+// while the remaining bytes of each key are packed from the end of the node
+// backwards.  Character keys are padded with spaces; numeric and date keys
+// are float64s transformed to sort bytewise, and padded with zero bytes.
+// Integers are little-endian except where noted.
+const (
+	cdxNode   = 512
+	cdxHeader = 1024
+)
+
+// cdxTag is the header of a tag, or of the tag directory.
+type cdxTag struct {
+	root       int64
+	keyLen     int
+	unique     bool
+	filtered   bool
+	compound   bool
+	descending bool
+	expr       string
+}
+
+func readCDXTag(r io.ReaderAt, off int64) (cdxTag, error) {
+	var h [cdxHeader]byte
+	if err := readFullAt(r, h[:], off); err != nil {
+		return cdxTag{}, fmt.Errorf("can't read tag header at offset %d: %s", off, err)
+	}
+	tag := cdxTag{
+		root:       int64(binary.LittleEndian.Uint32(h[0:])),
+		keyLen:     int(binary.LittleEndian.Uint16(h[12:])),
+		unique:     h[14]&0x01 != 0,
+		filtered:   h[14]&0x08 != 0,
+		compound:   h[14]&0x40 != 0,
+		descending: binary.LittleEndian.Uint16(h[502:]) != 0,
+		expr:       cString(h[512:]),
+	}
+	if tag.keyLen == 0 || 12+tag.keyLen+8 > cdxNode {
+		return cdxTag{}, fmt.Errorf("tag header at offset %d has a key length of %d", off, tag.keyLen)
+	}
+	return tag, nil
+}
+
+// openCDX reads the tags of a .cdx file.  A file that isn't compound holds
+// a single index, tagged name.
+func (t *Table) openCDX(r io.ReaderAt, name string) ([]*Index, error) {
+	dir, err := readCDXTag(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !dir.compound {
+		x, err := t.cdxIndex(r, name, dir)
+		return []*Index{x}, err
+	}
+
+	// The tag directory is itself an index, of tag names.
+	x := &Index{t: t, keyLen: dir.keyLen, root: dir.root, compare: bytes.Compare}
+	x.node = func(off int64) (*indexNode, error) {
+		return readCDXNode(r, off, dir.keyLen, ' ')
+	}
+	it, err := x.seek(nil)
+	if err != nil {
+		return nil, err
+	}
+	var xs []*Index
+	for {
+		k, off, err := it.entry()
+		if err == io.EOF {
+			return xs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		tag, err := readCDXTag(r, int64(off))
+		if err != nil {
+			return nil, err
+		}
+		x, err := t.cdxIndex(r, strings.TrimRight(string(k), " \x00"), tag)
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+}
+
+// cdxIndex makes an Index of a tag.  FoxPro doesn't record the type of a
+// tag's keys, so they are taken to be numbers if the key expression is a
+// single field of a numeric or date type, and characters otherwise.
+func (t *Table) cdxIndex(r io.ReaderAt, name string, tag cdxTag) (*Index, error) {
+	if tag.descending {
+		return nil, fmt.Errorf("tag %s is descending, which isn't supported", name)
+	}
+	x := &Index{
+		t:        t,
+		tag:      name,
+		expr:     tag.expr,
+		keyLen:   tag.keyLen,
+		unique:   tag.unique,
+		filtered: tag.filtered,
+		root:     tag.root,
+		compare:  bytes.Compare,
+	}
+	x.encode = x.charKey
+	fill := byte(' ')
+	if t.numericExpr(tag.expr) {
+		if tag.keyLen != 8 {
+			return nil, fmt.Errorf("tag %s has numeric keys %d bytes long instead of 8", name, tag.keyLen)
+		}
+		fill = 0
+		x.numeric = true
+		x.encode = func(v interface{}) ([]byte, error) {
+			f, err := x.numericKey(v)
+			if err != nil {
+				return nil, err
+			}
+			k := make([]byte, 8)
+			binary.BigEndian.PutUint64(k, orderedFloat(f))
+			return k, nil
+		}
+	}
+	x.node = func(off int64) (*indexNode, error) {
+		return readCDXNode(r, off, tag.keyLen, fill)
+	}
+	return x, nil
+}
+
+// numericExpr reports whether the key expression is a single field, maybe
+// qualified by an alias, whose keys are numbers.
+func (t *Table) numericExpr(expr string) bool {
+	i := t.exprField(expr)
+	return i >= 0 && strings.IndexByte("NFDTBY", t.fields[i].Type) >= 0
+}
+
+// exprField returns the position of the field that the key expression
+// consists of, maybe qualified by an alias, or -1 if it is anything else.
+func (t *Table) exprField(expr string) int {
+	expr = strings.TrimSpace(expr)
+	if i := strings.LastIndexAny(expr, ".>"); i >= 0 {
+		expr = expr[i+1:]
+	}
+	for i := range t.fields {
+		if strings.EqualFold(t.FieldName(i), expr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func readCDXNode(r io.ReaderAt, off int64, keyLen int, fill byte) (*indexNode, error) {
+	if off <= 0 {
+		return nil, fmt.Errorf("index points to offset %d", off)
+	}
+	var buf [cdxNode]byte
+	if err := readFullAt(r, buf[:], off); err != nil {
+		return nil, fmt.Errorf("can't read node at offset %d: %s", off, err)
+	}
+	nkeys := int(binary.LittleEndian.Uint16(buf[2:]))
+	n := &indexNode{}
+	if buf[0]&0x02 == 0 {
+		entry := keyLen + 8
+		if 12+nkeys*entry > cdxNode {
+			return nil, fmt.Errorf("node at offset %d claims %d keys, which don't fit", off, nkeys)
+		}
+		n.children = []int64{}
+		for i := 0; i < nkeys; i++ {
+			e := buf[12+i*entry:]
+			n.keys = append(n.keys, append([]byte(nil), e[:keyLen]...))
+			n.children = append(n.children, int64(binary.BigEndian.Uint32(e[keyLen+4:])))
+		}
+		return n, nil
+	}
+
+	recMask := uint64(binary.LittleEndian.Uint32(buf[14:]))
+	dupMask, trailMask := int(buf[18]), int(buf[19])
+	recBits, dupBits := uint(buf[20]), uint(buf[21])
+	width := int(buf[23])
+	if width == 0 || width > 8 || 24+nkeys*width > cdxNode {
+		return nil, fmt.Errorf("leaf at offset %d has %d keys of %d bytes, which don't fit", off, nkeys, width)
+	}
+	end := cdxNode
+	var prev []byte
+	for i := 0; i < nkeys; i++ {
+		var v uint64
+		for j := width - 1; j >= 0; j-- {
+			v = v<<8 | uint64(buf[24+i*width+j])
+		}
+		dup := int(v>>recBits) & dupMask
+		trail := int(v>>(recBits+dupBits)) & trailMask
+		stored := keyLen - dup - trail
+		if stored < 0 || dup > len(prev) || end-stored < 24+nkeys*width {
+			return nil, fmt.Errorf("leaf at offset %d is corrupt at key %d", off, i)
+		}
+		end -= stored
+		k := make([]byte, 0, keyLen)
+		k = append(append(k, prev[:dup]...), buf[end:end+stored]...)
+		for len(k) < keyLen {
+			k = append(k, fill)
+		}
+		n.keys = append(n.keys, k)
+		n.recnos = append(n.recnos, int(v&recMask))
+		prev = k
+	}
+	return n, nil
+}
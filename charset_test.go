@@ -0,0 +1,70 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCharset(t *testing.T) {
+	data := buildTable([]Field{mustField("NAME", 'C', 8, 0)}, " M\x81ller  ", " \xe1\xe0\xe2     ")
+	data[29] = 0x65 // CP866
+
+	for _, tc := range []struct {
+		opts     []Option
+		expected []string
+	}{
+		{nil, []string{"M\x81ller", "\xe1\xe0\xe2"}},
+		{[]Option{WithCharset(CP850)}, []string{"Müller", "ßÓÔ"}},
+		{[]Option{WithDetectedCharset()}, []string{"MБller", "срт"}},
+	} {
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), tc.opts...)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		for i, expected := range tc.expected {
+			if rec, err := tbl.Record(i); err != nil || rec["NAME"] != expected {
+				t.Errorf("expected %q, got %v, %v", expected, rec, err)
+			}
+		}
+	}
+	if CharsetFor(0x03) != CP1252 || CharsetFor(0x00) != nil {
+		t.Fatalf("wrong charset for language driver ID")
+	}
+}
+
+func TestCharsetEncode(t *testing.T) {
+	if b, err := CP850.Encode("Müller"); err != nil || string(b) != "M\x81ller" {
+		t.Fatalf("Encode returned %q, %v", b, err)
+	}
+	if _, err := CP850.Encode("срт"); err == nil {
+		t.Fatalf("expected an error encoding Cyrillic in CP850")
+	}
+}
+
+func TestCharsetEasternEurope(t *testing.T) {
+	for _, tc := range []struct {
+		ldid     byte
+		c        *Charset
+		raw      string
+		expected string
+	}{
+		{0x66, CP865, "\x9b\x9d\x86", "øØå"},
+		{0x69, Mazovia, "\x86\x92\xa4\xa7\xa2", "ąłńżó"},
+		{0x23, CP852, "\xa5\x88", "ął"},
+		{0xCC, CP1257, "\xe0\xfe\xeb", "ąžė"},
+		{0, KOI8R, "\xd2\xd5\xd3\xd3\xcb\xc9\xca", "русский"},
+	} {
+		if tc.ldid != 0 && CharsetFor(tc.ldid) != tc.c {
+			t.Errorf("language driver %#x: expected %s, got %v", tc.ldid, tc.c.Name(), CharsetFor(tc.ldid))
+		}
+		if s := tc.c.Decode([]byte(tc.raw)); s != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.c.Name(), tc.expected, s)
+		}
+		if b, err := tc.c.Encode(tc.expected); err != nil || string(b) != tc.raw {
+			t.Errorf("%s: Encode returned %q, %v", tc.c.Name(), b, err)
+		}
+		if charsetNamed(tc.c.Name()) != tc.c {
+			t.Errorf("%s: not found by name", tc.c.Name())
+		}
+	}
+}
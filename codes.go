@@ -0,0 +1,60 @@
+package dbf
+
+import (
+	"sort"
+	"strings"
+)
+
+// Codes maps the codes stored in a field, such as "1" and "2" or "M" and
+// "F", to the values they stand for, such as "Male" and "Female" or the
+// constants of a Go enum type.
+type Codes map[string]interface{}
+
+// WithCodes makes the table decode field name through codes: a field whose
+// stored text, stripped of padding, is one of the codes reads as the value
+// it stands for, and any other is decoded as usual.  Editor.Update stores
+// values back as their code.
+func WithCodes(name string, codes Codes) Option {
+	return func(t *Table) {
+		if t.codes == nil {
+			t.codes = make(map[string]Codes)
+		}
+		t.codes[name] = codes
+	}
+}
+
+// WriteCodes makes a Writer store values of field name as their code, so
+// that records decoded through WithCodes can be written back.
+func WriteCodes(name string, codes Codes) WriterOption {
+	return func(c *writerConfig) {
+		if c.codes == nil {
+			c.codes = make(map[string]Codes)
+		}
+		c.codes[name] = codes
+	}
+}
+
+// value returns the value the code in raw stands for, or v, the value raw
+// decodes to, if it isn't a code.
+func (c Codes) value(raw []byte, v interface{}) interface{} {
+	if mapped, ok := c[strings.TrimSpace(string(raw))]; ok {
+		return mapped
+	}
+	return v
+}
+
+// code returns the code that stands for v, or v itself if there is none.
+// If several codes stand for v, the lowest one is used.
+func (c Codes) code(v interface{}) interface{} {
+	var codes []string
+	for code, mapped := range c {
+		if sameValue(mapped, v) {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return v
+	}
+	sort.Strings(codes)
+	return codes[0]
+}
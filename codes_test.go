@@ -0,0 +1,51 @@
+package dbf
+
+import (
+	"reflect"
+	"testing"
+)
+
+type status int
+
+const (
+	statusActive status = iota + 1
+	statusClosed
+)
+
+func TestCodes(t *testing.T) {
+	fields := []Field{mustField("SEX", 'C', 1, 0), mustField("STATUS", 'N', 1, 0)}
+	data := buildTable(fields, " M1", " F2", " X9")
+	sexes := Codes{"M": "Male", "F": "Female"}
+	statuses := Codes{"1": statusActive, "2": statusClosed}
+
+	r, err := NewReaderFromBytes(data, WithCodes("SEX", sexes), WithCodes("STATUS", statuses))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []Record{
+		{"SEX": "Male", "STATUS": statusActive},
+		{"SEX": "Female", "STATUS": statusClosed},
+		{"SEX": "X", "STATUS": int64(9)},
+	}
+	var ws writeSeeker
+	w, err := NewWriter(&ws, fields, WriteCodes("SEX", sexes), WriteCodes("STATUS", statuses))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for i, want := range expected {
+		rec, err := r.Read(uint16(i))
+		if err != nil || !reflect.DeepEqual(rec, want) {
+			t.Fatalf("Read(%d) returned %#v, %v, expected %#v", i, rec, err, want)
+		}
+		if err = w.Write(rec); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	headerlen := len(data) - 3*3 - 1
+	if records := string(ws.buf[headerlen : len(ws.buf)-1]); records != " M1 F2 X9" {
+		t.Fatalf("wrong records written: %q", records)
+	}
+}
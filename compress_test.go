@@ -0,0 +1,28 @@
+package dbf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func TestCompressedStream(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(testData)
+	zw.Close()
+
+	for _, in := range [][]byte{buf.Bytes(), testData} {
+		r, err := NewStreamReader(bytes.NewReader(in))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		rec, err := r.Next()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if rec["Name"] != "Abbotsbury" {
+			t.Fatalf("wrong Name: got %v, expected Abbotsbury", rec["Name"])
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package dbf
+
+// A computed column is derived from the stored fields of each record.
+type computed struct {
+	name string
+	fn   func(Record) (interface{}, error)
+}
+
+// WithComputed adds a virtual column to the table.  fn is called with every
+// record as it's decoded, and its result is stored in the record under name,
+// as if it were a real field:
+//
+//	dbf.WithComputed("FULLNAME", func(rec dbf.Record) (interface{}, error) {
+//		return rec["FIRST"].(string) + " " + rec["LAST"].(string), nil
+//	})
+//
+// Computed columns are evaluated in the order they were added, so each can
+// build on the ones before it.  They are listed by FieldNames after the stored
+// fields.
+func WithComputed(name string, fn func(Record) (interface{}, error)) Option {
+	return func(t *Table) {
+		t.computed = append(t.computed, computed{name, fn})
+	}
+}
+
+func (t *Table) addComputed(rec Record) error {
+	for _, c := range t.computed {
+		v, err := c.fn(rec)
+		if err != nil {
+			return err
+		}
+		rec[c.name] = v
+	}
+	return nil
+}
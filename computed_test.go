@@ -0,0 +1,32 @@
+package dbf
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithComputed(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(testData),
+		WithComputed("UPPER", func(rec Record) (interface{}, error) {
+			return strings.ToUpper(rec["Name"].(string)), nil
+		}),
+		WithComputed("LABEL", func(rec Record) (interface{}, error) {
+			return rec["UPPER"].(string) + "!", nil
+		}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []string{"OBJECTID", "Name", "Shape_Leng", "UPPER", "LABEL"}
+	if !reflect.DeepEqual(r.FieldNames(), expected) {
+		t.Fatalf("wrong FieldNames(): got %v, expected %v", r.FieldNames(), expected)
+	}
+	rec, err := r.Read(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec["LABEL"] != "ABBOTSBURY!" {
+		t.Fatalf("wrong computed value: %v", rec["LABEL"])
+	}
+}
@@ -0,0 +1,54 @@
+package dbf
+
+import "fmt"
+
+// A FieldCrypt undoes a vendor's encryption or obfuscation of a field.  It
+// works on the field's raw bytes: Decrypt is applied before a value is
+// decoded, and Encrypt reverses it.  Neither may modify its argument.
+type FieldCrypt interface {
+	Decrypt(raw []byte) ([]byte, error)
+	Encrypt(plain []byte) ([]byte, error)
+}
+
+// WithFieldCrypt makes the table decrypt field name with c as records are
+// read.
+func WithFieldCrypt(name string, c FieldCrypt) Option {
+	return func(t *Table) {
+		if t.crypts == nil {
+			t.crypts = make(map[string]FieldCrypt)
+		}
+		t.crypts[name] = c
+	}
+}
+
+// WriteFieldCrypt makes a Writer encrypt field name with c, so that records
+// read through WithFieldCrypt are written back as they were stored.  c must
+// not change the length of what it encrypts.
+func WriteFieldCrypt(name string, c FieldCrypt) WriterOption {
+	return func(wc *writerConfig) {
+		if wc.crypts == nil {
+			wc.crypts = make(map[string]FieldCrypt)
+		}
+		wc.crypts[name] = c
+	}
+}
+
+// XORCrypt is the repeating-key XOR that many proprietary applications use
+// to hide account numbers and the like.  An empty key is an error rather
+// than a way to leave the field as it is.
+type XORCrypt []byte
+
+func (key XORCrypt) Decrypt(raw []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("XOR key is empty")
+	}
+	out := make([]byte, len(raw))
+	for i, b := range raw {
+		out[i] = b ^ key[i%len(key)]
+	}
+	return out, nil
+}
+
+func (key XORCrypt) Encrypt(plain []byte) ([]byte, error) {
+	return key.Decrypt(plain)
+}
@@ -0,0 +1,85 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWithFieldCrypt(t *testing.T) {
+	key := XORCrypt("k3y")
+	secret, _ := key.Encrypt([]byte("12345"))
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("ACCOUNT", 'C', 5, 0)}, "  10"+string(secret))
+
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)), WithFieldCrypt("ACCOUNT", key))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec, err := tbl.Record(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec["ACCOUNT"] != "12345" || rec["ID"] != int64(10) {
+		t.Fatalf("wrong decrypted record: %v", rec)
+	}
+
+	tbl, err = OpenTable(bytes.NewReader(table), int64(len(table)), WithFieldCrypt("ACCOUNT", XORCrypt(nil)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = tbl.Record(0); err == nil {
+		t.Fatalf("expected an error for an empty XOR key")
+	}
+	if _, err = XORCrypt("").Encrypt([]byte("12345")); err == nil {
+		t.Fatalf("expected an error encrypting with an empty XOR key")
+	}
+}
+
+func TestWriteFieldCrypt(t *testing.T) {
+	key := XORCrypt("k3y")
+	first, _ := key.Encrypt([]byte("12345"))
+	second, _ := key.Encrypt([]byte("67890"))
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("ACCOUNT", 'C', 5, 0)}
+	table := buildTable(fields, "  10"+string(first), "  20"+string(second))
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)), WithFieldCrypt("ACCOUNT", key))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	var ws writeSeeker
+	w, err := NewWriter(&ws, fields, WriteFieldCrypt("ACCOUNT", key))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for i, account := range []string{"12345", "99999"} {
+		raw, err := tbl.RawRecord(i)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		rec, err := tbl.Record(i)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		rec["ACCOUNT"] = account
+		if err = w.WriteFrom(raw, rec); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	out, err := OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	changed, _ := key.Encrypt([]byte("99999"))
+	for i, expected := range []string{string(first), string(changed)} {
+		raw, err := out.RawRecord(i)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got := string(raw[4:]); got != expected {
+			t.Errorf("record %d stored ACCOUNT as %q, expected %q", i, got, expected)
+		}
+	}
+}
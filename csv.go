@@ -0,0 +1,167 @@
+package dbf
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// A CSVOption configures ToCSV.
+type CSVOption func(*csvConfig)
+
+type csvConfig struct {
+	mapping  Mapping
+	comma    rune
+	noHeader bool
+	locale   *Locale
+	resume   Checkpoint
+	every    int
+	save     func(Checkpoint) error
+}
+
+// A Checkpoint records how far an export has got, so that it can be resumed
+// after an interruption: the number of the next record to export, and the
+// number of bytes of output written before it.
+type Checkpoint struct {
+	Record int
+	Offset int64
+}
+
+// CSVCheckpoints makes ToCSV call save with a Checkpoint every n records,
+// once the rows before it have been written to w, and once more at the end.
+// To resume an interrupted export, truncate the output to the Offset of the
+// last checkpoint saved, and pass it to CSVResume.
+func CSVCheckpoints(n int, save func(Checkpoint) error) CSVOption {
+	return func(c *csvConfig) {
+		c.every, c.save = n, save
+	}
+}
+
+// CSVResume continues an export from cp, as saved by CSVCheckpoints: it
+// starts at record cp.Record, counts output from cp.Offset, and leaves out
+// the header row if cp isn't at the start.
+func CSVResume(cp Checkpoint) CSVOption {
+	return func(c *csvConfig) {
+		c.resume = cp
+	}
+}
+
+// cell formats value v of field name of t for export.
+func (c *csvConfig) cell(t *Table, name string, v interface{}) string {
+	if s, ok := t.format(name, v); ok {
+		return s
+	} else if c.locale != nil {
+		return c.locale.Format(v)
+	}
+	return normalize(v)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// CSVMapping selects, renames and converts the exported columns.  By
+// default every field is exported under its own name.
+func CSVMapping(m Mapping) CSVOption {
+	return func(c *csvConfig) {
+		c.mapping = m
+	}
+}
+
+// CSVComma sets the field delimiter, ',' by default.
+func CSVComma(r rune) CSVOption {
+	return func(c *csvConfig) {
+		c.comma = r
+	}
+}
+
+// CSVNoHeader leaves out the header row of column names.
+func CSVNoHeader() CSVOption {
+	return func(c *csvConfig) {
+		c.noHeader = true
+	}
+}
+
+// CSVLocale writes numbers and dates by the conventions of l, as
+// Locale.Format does, instead of as Record.URLValues does.  A comma as
+// decimal separator is usually paired with CSVComma(';').
+func CSVLocale(l Locale) CSVOption {
+	return func(c *csvConfig) {
+		c.locale = &l
+	}
+}
+
+// ToCSV streams the records of the table that aren't deleted to w as CSV,
+// preceded by a header row of column names.  Values are formatted as by
+// Record.URLValues: dates as ISO-8601 (YYYY-MM-DD), logicals as true or
+// false, and blank dates and unknown values as empty cells, unless the
+// table has formatters for them, see WithFormatter, or CSVLocale says
+// otherwise.  It stops at the first record that can't be decoded.
+func (t *Table) ToCSV(w io.Writer, opts ...CSVOption) error {
+	c := csvConfig{comma: ','}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.mapping == nil {
+		c.mapping = MappingFor(t)
+	} else if err := c.mapping.Check(t); err != nil {
+		return err
+	}
+
+	out := &countingWriter{w, c.resume.Offset}
+	cw := csv.NewWriter(out)
+	cw.Comma = c.comma
+	if !c.noHeader && c.resume == (Checkpoint{}) {
+		if err := cw.Write(c.mapping.Columns()); err != nil {
+			return err
+		}
+	}
+	// checkpoint saves the position before record next.
+	checkpoint := func(next int) error {
+		if c.save == nil {
+			return nil
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		return c.save(Checkpoint{next, out.n})
+	}
+	it := t.iterateFrom(c.resume.Record)
+	cells := make([]string, len(c.mapping))
+	for rows := 1; ; rows++ {
+		rec, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		row, err := c.mapping.Row(rec)
+		if err != nil {
+			return &RecordError{it.RecNo(), t.recordOffset(it.RecNo()), err}
+		}
+		for i, v := range row {
+			cells[i] = c.cell(t, c.mapping[i].Field, v)
+		}
+		if err = cw.Write(cells); err != nil {
+			return err
+		}
+		if c.every > 0 && rows%c.every == 0 {
+			if err = checkpoint(it.next); err != nil {
+				return err
+			}
+		}
+	}
+	if err := checkpoint(t.nrec); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
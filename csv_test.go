@@ -0,0 +1,95 @@
+package dbf
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestToCSV(t *testing.T) {
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 6, 0), mustField("SINCE", 'D', 8, 0), mustField("OK", 'L', 1, 0)},
+		"   1a, b  20110726T",
+		"*  2bravo 20110726F",
+		"   3charl         ?",
+	)
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	var buf bytes.Buffer
+	if err = tbl.ToCSV(&buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "ID,NAME,SINCE,OK\n1,\"a, b\",2011-07-26,true\n3,charl,,\n"
+	if buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	m := Mapping{{Field: "NAME", Column: "name"}, {Field: "ID", Column: "id"}}
+	if err = tbl.ToCSV(&buf, CSVMapping(m), CSVComma(';'), CSVNoHeader()); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected = "a, b;1\ncharl;3\n"; buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestToCSVLocale(t *testing.T) {
+	data := buildTable([]Field{mustField("QTY", 'N', 8, 0), mustField("PRICE", 'N', 10, 2), mustField("SINCE", 'D', 8, 0)},
+		"  1234567 -12345.5020110726",
+		"        5      0.25        ",
+	)
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var buf bytes.Buffer
+	if err = tbl.ToCSV(&buf, CSVComma(';'), CSVLocale(Locale{",", ".", "02.01.2006"})); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := "QTY;PRICE;SINCE\n1.234.567;-12.345,5;26.07.2011\n5;0,25;\n"; buf.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestToCSVResume(t *testing.T) {
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)},
+		"   1alpha", "*  2bravo", "   3charl", "   4delta")
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var full bytes.Buffer
+	if err = tbl.ToCSV(&full); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	var buf bytes.Buffer
+	var saved []Checkpoint
+	interrupted := errors.New("interrupted")
+	err = tbl.ToCSV(&buf, CSVCheckpoints(1, func(cp Checkpoint) error {
+		if len(saved) == 2 {
+			buf.WriteString("partial ro") // written after the last checkpoint
+			return interrupted
+		}
+		saved = append(saved, cp)
+		return nil
+	}))
+	if err != interrupted {
+		t.Fatalf("expected the export to be interrupted, got %v", err)
+	}
+	last := saved[len(saved)-1]
+	if last.Record != 3 || last.Offset != int64(len("ID,NAME\n1,alpha\n3,charl\n")) {
+		t.Fatalf("wrong checkpoint %+v", last)
+	}
+
+	buf.Truncate(int(last.Offset))
+	if err = tbl.ToCSV(&buf, CSVResume(last)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if buf.String() != full.String() {
+		t.Fatalf("resumed export differs:\n%s\nexpected:\n%s", buf.String(), full.String())
+	}
+}
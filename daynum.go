@@ -0,0 +1,53 @@
+package dbf
+
+import (
+	"bytes"
+	"math"
+	"time"
+)
+
+// JulianEpoch is day 0 of the Julian day numbers, 24 November 4714 BC in
+// the proleptic Gregorian calendar, so that WithDayNumbers(name,
+// JulianEpoch) reads a field of Julian day numbers.
+var JulianEpoch = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -2440588)
+
+// WithDayNumbers makes the table decode numeric field name, which some
+// generators use to store dates as a count of days, as the time.Time that
+// many days after epoch.  A fractional part is read as a time of day.  A
+// blank field decodes to the zero Time, as a blank date does, and
+// Editor.Update converts dates back to day numbers.
+func WithDayNumbers(name string, epoch time.Time) Option {
+	return func(t *Table) {
+		if t.epochs == nil {
+			t.epochs = make(map[string]time.Time)
+		}
+		t.epochs[name] = epoch
+	}
+}
+
+// dayNumber converts v, decoded from the raw bytes of a numeric field, to
+// the date it counts the days to.
+func dayNumber(epoch time.Time, raw []byte, v interface{}) interface{} {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return time.Time{}
+	}
+	days, ok := toFloat(v)
+	if !ok {
+		return v
+	}
+	whole := math.Floor(days)
+	return epoch.AddDate(0, 0, int(whole)).Add(time.Duration((days - whole) * float64(24*time.Hour)))
+}
+
+// daysSince is the inverse of dayNumber.  Fields without decimal places get
+// whole days.
+func daysSince(epoch, d time.Time, f Field) interface{} {
+	if d.IsZero() {
+		return nil
+	}
+	days := float64(d.Unix()-epoch.Unix()) / (24 * 60 * 60)
+	if f.DecimalPlaces == 0 {
+		return int(math.Floor(days))
+	}
+	return days
+}
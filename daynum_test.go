@@ -0,0 +1,45 @@
+package dbf
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDayNumbers(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	fields := []Field{mustField("JD", 'N', 7, 0), mustField("DAYS", 'N', 8, 2)}
+	f.Write(buildTable(fields, " 245576915181.50", "                "))
+
+	epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	e, err := NewEditor(f, WithDayNumbers("JD", JulianEpoch), WithDayNumbers("DAYS", epoch))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec, err := e.Record(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if d := rec["JD"].(time.Time); !d.Equal(time.Date(2011, 7, 26, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("JD decoded to %v", d)
+	}
+	if d := rec["DAYS"].(time.Time); !d.Equal(time.Date(2011, 7, 26, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("DAYS decoded to %v", d)
+	}
+	if rec, err = e.Record(1); err != nil || !rec["JD"].(time.Time).IsZero() {
+		t.Errorf("blank day number decoded to %v, %v", rec["JD"], err)
+	}
+
+	if err = e.Update(1, Record{"JD": time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if raw, _ := e.RawRecord(1); string(raw) != " 2451545        " {
+		t.Errorf("updated record is %q", raw)
+	}
+}
@@ -0,0 +1,138 @@
+package dbf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// A dBase 7 level 7 header puts a 32-byte language driver name and 4
+// reserved bytes after the fixed header, and describes each field in 48
+// bytes: a 32-byte name, then type, length and decimal count.  The field
+// properties that follow the 0x0D terminator are skipped.
+const (
+	level7Fields     = 68
+	level7Descriptor = 48
+)
+
+// isLevel7 reports whether the table is a dBase 7 one, whose binary field
+// types store numbers big-endian and transformed to sort bytewise.
+func isLevel7(version byte) bool {
+	return version == 0x04 || version == 0x8C
+}
+
+// readLevel7 parses the field descriptors of a level 7 header.  ok is false
+// if hdr, the whole header, doesn't hold any.
+func readLevel7(hdr []byte, version byte) (fields []Field, names []string, ok bool, err error) {
+	pos := uint32(1)
+	for off := level7Fields; off < len(hdr); off += level7Descriptor {
+		if hdr[off] == 0x0D {
+			return fields, names, true, nil
+		} else if off+level7Descriptor > len(hdr) {
+			break
+		}
+		d := hdr[off : off+level7Descriptor]
+		n := 0
+		for n < 32 && d[n] != 0 {
+			n++
+		}
+		f := Field{Type: d[32], Len: d[33], DecimalPlaces: d[34], Offset: pos}
+		copy(f.Name[:len(f.Name)-1], d[:n])
+		if err = f.validate(version); err != nil {
+			return nil, nil, true, err
+		}
+		pos += uint32(f.Len)
+		fields = append(fields, f)
+		names = append(names, string(d[:n]))
+	}
+	return nil, nil, false, nil
+}
+
+// level7Len gives the width of each dBase 7 binary field type.
+var level7Len = map[byte]int{'I': 4, '+': 4, 'O': 8, '@': 8}
+
+// decodeLevel7 decodes the binary field types of dBase 7: I and +
+// (autoincrement) become an int32, O a float64 and @ a time.Time in UTC.  A
+// field of zero bytes has never been set and decodes to nil.
+func decodeLevel7(f Field, raw []byte) (interface{}, error) {
+	if len(raw) != level7Len[f.Type] {
+		return nil, fmt.Errorf("field type '%c' must be %d bytes wide, not %d", f.Type, level7Len[f.Type], len(raw))
+	}
+	blank := true
+	for _, b := range raw {
+		blank = blank && b == 0
+	}
+	if blank {
+		return nil, nil
+	}
+	switch f.Type {
+	case 'I', '+':
+		return int32(binary.BigEndian.Uint32(raw) ^ 1<<31), nil
+	case 'O':
+		b := binary.BigEndian.Uint64(raw)
+		if b&(1<<63) != 0 {
+			b ^= 1 << 63
+		} else {
+			b = ^b
+		}
+		return math.Float64frombits(b), nil
+	}
+	// A timestamp is two longs, the Julian day number and the milliseconds
+	// since midnight, stored like I fields.
+	day := int32(binary.BigEndian.Uint32(raw) ^ 1<<31)
+	ms := int32(binary.BigEndian.Uint32(raw[4:]) ^ 1<<31)
+	return JulianEpoch.AddDate(0, 0, int(day)).Add(time.Duration(ms) * time.Millisecond), nil
+}
+
+// encodeLevel7 is the inverse of decodeLevel7.  nil and the zero Time are
+// stored as zero bytes.
+func encodeLevel7(f Field, v interface{}) ([]byte, error) {
+	buf := make([]byte, level7Len[f.Type])
+	if len(buf) != int(f.Len) {
+		return nil, fmt.Errorf("field type '%c' must be %d bytes wide, not %d", f.Type, len(buf), f.Len)
+	}
+	if t, ok := v.(time.Time); v == nil || ok && t.IsZero() {
+		return buf, nil
+	}
+	switch f.Type {
+	case 'I', '+':
+		n, ok := widen(v).(int64)
+		if !ok {
+			return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+		} else if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("%d overflows field type '%c'", n, f.Type)
+		}
+		binary.BigEndian.PutUint32(buf, uint32(int32(n))^1<<31)
+	case 'O':
+		x, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+		}
+		binary.BigEndian.PutUint64(buf, orderedFloat(x))
+	case '@':
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+		}
+		secs := t.Unix() - JulianEpoch.Unix()
+		day := secs / 86400
+		if secs < 0 && secs%86400 != 0 {
+			day--
+		}
+		ms := (secs-day*86400)*1000 + int64(t.Nanosecond())/1e6
+		binary.BigEndian.PutUint32(buf, uint32(int32(day))^1<<31)
+		binary.BigEndian.PutUint32(buf[4:], uint32(int32(ms))^1<<31)
+	}
+	return buf, nil
+}
+
+// orderedFloat transforms the bits of x so that, stored big-endian, floats
+// sort bytewise in numeric order, as in O fields and FoxPro index keys.
+func orderedFloat(x float64) uint64 {
+	b := math.Float64bits(x)
+	if b&(1<<63) == 0 {
+		return b | 1<<63
+	}
+	return ^b
+}
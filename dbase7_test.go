@@ -0,0 +1,99 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// buildLevel7 assembles a dBase 7 table with a level 7 header, followed by
+// a field properties structure the reader must skip.
+func buildLevel7(fields []Field, names []string, records ...[]byte) []byte {
+	var descriptors bytes.Buffer
+	recordlen := 1
+	for i, f := range fields {
+		d := make([]byte, level7Descriptor)
+		copy(d, names[i])
+		d[32], d[33], d[34] = f.Type, f.Len, f.DecimalPlaces
+		descriptors.Write(d)
+		recordlen += int(f.Len)
+	}
+	descriptors.WriteByte(0x0D)
+	descriptors.Write(make([]byte, 14)) // empty field properties structure
+
+	hdr := make([]byte, level7Fields)
+	hdr[0], hdr[1], hdr[2], hdr[3] = 0x04, 124, 1, 2
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(records)))
+	binary.LittleEndian.PutUint16(hdr[8:], uint16(level7Fields+descriptors.Len()))
+	binary.LittleEndian.PutUint16(hdr[10:], uint16(recordlen))
+	data := append(hdr, descriptors.Bytes()...)
+	for _, rec := range records {
+		data = append(append(data, ' '), rec...)
+	}
+	return append(data, 0x1A)
+}
+
+func TestLevel7(t *testing.T) {
+	fields := []Field{mustField("ID", '+', 4, 0), mustField("NAME", 'C', 5, 0),
+		mustField("BAL", 'O', 8, 0), mustField("QTY", 'I', 4, 0), mustField("AT", '@', 8, 0)}
+	names := []string{"ID", "NAME", "OUTSTANDING_BALANCE", "QUANTITY_ON_HAND", "LAST_UPDATED_AT"}
+	at := time.Date(2001, 2, 3, 4, 5, 6, 7e6, time.UTC)
+	values := [][]interface{}{
+		{int32(1), "alpha", 12.5, int32(-3), at},
+		{int32(2), "bravo", -0.25, int32(70000), nil},
+	}
+	var records [][]byte
+	for _, vals := range values {
+		var rec []byte
+		for i, f := range fields {
+			b, err := encode(0x04, f, vals[i])
+			if f.Type == 'C' {
+				b, err = encodeField(f, vals[i])
+			}
+			if err != nil {
+				t.Fatalf("encoding %v: %s", vals[i], err)
+			}
+			rec = append(rec, b...)
+		}
+		records = append(records, rec)
+	}
+
+	data := buildLevel7(fields, names, records...)
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := tbl.FieldNames(); !reflect.DeepEqual(got, names) {
+		t.Errorf("FieldNames() returned %v, expected %v", got, names)
+	}
+	for i, vals := range values {
+		rec, err := tbl.Record(i)
+		if err != nil {
+			t.Fatalf("Record(%d): %s", i, err)
+		}
+		for j, name := range names {
+			if !reflect.DeepEqual(rec[name], vals[j]) {
+				t.Errorf("Record(%d)[%s] = %#v, expected %#v", i, name, rec[name], vals[j])
+			}
+		}
+	}
+
+	// The encodings are meant to sort bytewise in numeric order.
+	for _, f := range []Field{fields[2], fields[3]} {
+		lo, _ := encodeLevel7(f, int32(-5))
+		hi, _ := encodeLevel7(f, int32(3))
+		if bytes.Compare(lo, hi) >= 0 {
+			t.Errorf("type %c: -5 encoded as % x, not below 3 encoded as % x", f.Type, lo, hi)
+		}
+	}
+	if _, err = encodeLevel7(fields[3], int64(1)<<40); err == nil {
+		t.Errorf("expected an error for a value that overflows an I field")
+	}
+
+	// Binary types are only known to dBase 7.
+	if err := fields[3].validate(0x03); err == nil {
+		t.Errorf("expected an error for an I field in a dBase III table")
+	}
+}
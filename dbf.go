@@ -9,22 +9,22 @@ package dbf
 // table rows, one at a time
 
 import (
-	"bufio"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+// A Reader is the original, random-access interface to a table.  It is a thin
+// wrapper around a Table, whose methods it shares.  Reads don't take the
+// embedded Mutex, which is only kept for callers that lock the Reader
+// themselves.
 type Reader struct {
-	r                io.ReadSeeker
-	year, month, day int
-	Length           int // number of records
-	fields           []Field
-	headerlen        uint16 // in bytes
-	recordlen        uint16 // length of each record, in bytes
+	*Table
+	Length int // number of records
 	sync.Mutex
 }
 
@@ -38,62 +38,101 @@ type header struct {
 	Recordlen  uint16 // length of each record, in bytes
 }
 
-func NewReader(r io.ReadSeeker) (*Reader, error) {
-	var h header
-	if _, err := r.Seek(0, 0); err != nil {
+// NewReader opens the table in r.  If r also implements io.ReaderAt, as
+// *os.File and *bytes.Reader do, records are read at their offsets and
+// concurrent reads proceed in parallel; otherwise they take turns on r's
+// seek position.
+func NewReader(r io.ReadSeeker, opts ...Option) (*Reader, error) {
+	size, err := r.Seek(0, 2)
+	if err != nil {
 		return nil, err
 	}
-	err := binary.Read(r, binary.LittleEndian, &h)
+	t, err := OpenTable(readerAt(r), size, opts...)
 	if err != nil {
 		return nil, err
-	} else if h.Version != 0x03 {
-		return nil, fmt.Errorf("unexepected file version: %d\n", h.Version)
 	}
+	return &Reader{Table: t, Length: t.nrec}, nil
+}
 
-	var fields []Field
-	if _, err := r.Seek(0x20, 0); err != nil {
+// NewReaderAt opens a table from a source that supports random access but not
+// seeking, such as an object storage client, given its total size in bytes.
+func NewReaderAt(r io.ReaderAt, size int64, opts ...Option) (*Reader, error) {
+	t, err := OpenTable(r, size, opts...)
+	if err != nil {
 		return nil, err
 	}
-	var offset uint16
-	for offset = 0x20; offset < h.Headerlen-1; offset += 32 {
-		f := Field{}
-		binary.Read(r, binary.LittleEndian, &f)
-		if err = f.validate(); err != nil {
-			return nil, err
-		}
-		fields = append(fields, f)
-	}
+	return &Reader{Table: t, Length: t.nrec}, nil
+}
 
-	br := bufio.NewReader(r)
-	if eoh, err := br.ReadByte(); err != nil {
-		return nil, err
-	} else if eoh != 0x0D {
-		return nil, fmt.Errorf("Header was supposed to be %d bytes long, but found byte %#x at that offset instead of expected byte 0x0D\n", h.Headerlen, eoh)
-	}
+// NewReaderFromBytes opens a table held in memory, such as an uploaded file.
+// Records are decoded straight out of data, without seeking or copying, so
+// data must not be modified while the Reader is in use.
+func NewReaderFromBytes(data []byte, opts ...Option) (*Reader, error) {
+	return NewReaderAt(memSource(data), int64(len(data)), opts...)
+}
 
-	return &Reader{r, 1900 + int(h.Year),
-		int(h.Month), int(h.Day), int(h.Nrec), fields,
-		h.Headerlen, h.Recordlen, *new(sync.Mutex)}, nil
+// memSource is a table held in memory.  readRaw slices records out of it
+// rather than copying them.
+type memSource []byte
+
+func (m memSource) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 || off >= int64(len(m)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
 }
 
-func (r *Reader) ModDate() (int, int, int) {
-	return r.year, r.month, r.day
+// readerAt returns r itself if it supports reading at an offset, and wraps it
+// in a seekReaderAt otherwise.
+func readerAt(r io.ReadSeeker) io.ReaderAt {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return ra
+	}
+	return &seekReaderAt{r: r}
 }
 
-func (r *Reader) FieldName(i int) (name string) {
-	return strings.TrimRight(string(r.fields[i].Name[:]), "\x00")
+// seekReaderAt adapts an io.ReadSeeker to io.ReaderAt, taking turns on the
+// shared seek position.
+type seekReaderAt struct {
+	sync.Mutex
+	r io.ReadSeeker
 }
 
-func (r *Reader) FieldNames() (names []string) {
-	for i := range r.fields {
-		names = append(names, r.FieldName(i))
+func (s *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, err := s.r.Seek(off, 0); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(s.r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
 	}
-	return
+	return n, err
 }
 
-func (f *Field) validate() error {
+func (f *Field) validate(version byte) error {
 	switch f.Type {
-	case 'C', 'N', 'F':
+	case 'C', 'N', 'F', 'D', 'L', 'M':
+		return nil
+	}
+	if f.Type == '0' && isVFP(version) { // _NullFlags
+		return nil
+	}
+	if (f.Type == 'G' || f.Type == 'P') && (version == 0xF5 || isVFP(version)) {
+		return nil
+	}
+	if _, ok := level7Len[f.Type]; ok && isLevel7(version) {
+		return nil
+	}
+	if _, ok := vfpLen[f.Type]; ok && isVFP(version) {
+		return nil
+	}
+	if _, ok := registeredType(version, f.Type); ok {
 		return nil
 	}
 	return fmt.Errorf("Sorry, dbf library doesn't recognize field type '%c'", f.Type)
@@ -111,56 +150,54 @@ type Field struct {
 	_ [14]byte
 }
 
+// ErrDeleted is returned when reading a record that is flagged as deleted.
+// ScanDeleted visits such records.
+var ErrDeleted = errors.New("record is deleted")
+
 // http://play.golang.org/p/-CUbdWc6zz
 type Record map[string]interface{}
 
 func (r *Reader) Read(i uint16) (rec Record, err error) {
-	r.Lock()
-	defer r.Unlock()
-
-	offset := int64(r.headerlen + r.recordlen*i)
-	r.r.Seek(offset, 0)
-
-	var deleted byte
-	if err = binary.Read(r.r, binary.LittleEndian, &deleted); err != nil {
-		return nil, err
-	} else if deleted == '*' {
-		return nil, fmt.Errorf("record %d is deleted", i)
-	} else if deleted != ' ' {
-		return nil, fmt.Errorf("record %d contained an unexpected value in the deleted flag: %h", i, deleted)
-	}
+	return r.Record(int(i))
+}
 
-	rec = make(Record)
-	for i, f := range r.fields {
-		buf := make([]byte, f.Len)
-		if err = binary.Read(r.r, binary.LittleEndian, &buf); err != nil {
-			return nil, err
+// decodeField converts the raw contents of a field to its Go value.  Numbers
+// become an int64, or a float64 if the field has decimal places or the value
+// a decimal point.  Dates become a time.Time in UTC, the zero Time if the
+// date is blank.  Logicals become a bool, or nil if they were never set ('?'
+// or blank).
+func decodeField(f Field, buf []byte) (interface{}, error) {
+	fieldVal := strings.TrimSpace(string(buf))
+	switch f.Type {
+	case 'F':
+		if len(fieldVal) == 0 {
+			return float64(0), nil
 		}
-
-		fieldVal := strings.TrimSpace(string(buf))
-		fieldName := r.FieldName(i)
-
-		switch f.Type {
-		case 'F':
-			if len(fieldVal) == 0 {
-				rec[fieldName] = float64(0)
-			} else {
-				rec[fieldName], err = strconv.ParseFloat(fieldVal, 64)
-			}
-		case 'N':
-			if len(fieldVal) == 0 {
-				rec[fieldName] = int(0)
-			} else if f.DecimalPlaces > 0 {
-				rec[fieldName], err = strconv.ParseFloat(fieldVal, 64)
-			} else {
-				rec[fieldName], err = strconv.Atoi(fieldVal)
-			}
-		default:
-			rec[fieldName] = fieldVal
+		return strconv.ParseFloat(fieldVal, 64)
+	case 'N':
+		if len(fieldVal) == 0 {
+			return int64(0), nil
+		} else if f.DecimalPlaces > 0 || strings.IndexByte(fieldVal, '.') >= 0 {
+			return strconv.ParseFloat(fieldVal, 64)
+		}
+		return strconv.ParseInt(fieldVal, 10, 64)
+	case 'D':
+		if len(fieldVal) == 0 || fieldVal == "00000000" {
+			return time.Time{}, nil
 		}
-		if err != nil {
-			return nil, err
+		return time.Parse("20060102", fieldVal)
+	case 'L':
+		switch fieldVal {
+		case "T", "t", "Y", "y":
+			return true, nil
+		case "F", "f", "N", "n":
+			return false, nil
+		case "?", "":
+			return nil, nil
 		}
+		return nil, fmt.Errorf("invalid logical value %q", fieldVal)
+	case '0':
+		return append([]byte(nil), buf...), nil // _NullFlags bitmap
 	}
-	return rec, nil
+	return fieldVal, nil
 }
@@ -2,10 +2,14 @@ package dbf
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func ExampleUsage() {
@@ -15,7 +19,7 @@ func ExampleUsage() {
 	// record is map[string]interface{}
 }
 
-var testFile = bytes.NewReader([]byte{
+var testData = []byte{
 	// Header:
 	0x03, 0x6F, 0x07, 0x1A, 0x0D, 0x21, 0x00, 0x00, 0x81, 0x00, 0x55, 0x00, 0x00, 0x00, 0x00, 0x00,
 	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
@@ -41,10 +45,46 @@ var testFile = bytes.NewReader([]byte{
 
 	// Shape_Leng:
 	0x20, 0x30, 0x2E, 0x30, 0x35, 0x32, 0x34, 0x36, 0x37,
-})
+}
+
+var testFile = bytes.NewReader(testData)
 
 var reader *Reader
 
+// buildTable assembles a dBase III table from its fields and the raw
+// contents of its records, deleted flag included.
+func buildTable(fields []Field, records ...string) []byte {
+	recordlen := 1
+	for _, f := range fields {
+		recordlen += int(f.Len)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, header{
+		Version: 0x03, Year: 111, Month: 7, Day: 26,
+		Nrec:      uint32(len(records)),
+		Headerlen: uint16(32 + 32*len(fields) + 1),
+		Recordlen: uint16(recordlen),
+	})
+	buf.Write(make([]byte, 32-buf.Len()))
+	for _, f := range fields {
+		binary.Write(&buf, binary.LittleEndian, f)
+	}
+	buf.WriteByte(0x0D)
+	for _, rec := range records {
+		buf.WriteString(rec)
+	}
+	buf.WriteByte(0x1A)
+	return buf.Bytes()
+}
+
+func mustField(name string, typ byte, length, decimals uint8) Field {
+	f, err := NewField(name, typ, length, decimals)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
 func init() {
 	var err error
 	reader, err = NewReader(testFile)
@@ -68,6 +108,14 @@ func TestFieldNames(t *testing.T) {
 	}
 }
 
+func TestFields(t *testing.T) {
+	expected := []FieldInfo{{"OBJECTID", 'N', 11, 0, "int64"}, {"Name", 'C', 50, 0, ""}, {"Shape_Leng", 'F', 9, 4, "float64"}}
+	actual := reader.Fields()
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("wrong Fields(): got %+v, expected %+v", actual, expected)
+	}
+}
+
 func TestFieldTypes(t *testing.T) {
 	var badFieldType = bytes.NewReader([]byte{
 		// Header:
@@ -87,7 +135,7 @@ func TestFieldTypes(t *testing.T) {
 
 func TestOneRead(t *testing.T) {
 	expected := Record{
-		"OBJECTID":   1,
+		"OBJECTID":   int64(1),
 		"Name":       "Abbotsbury",
 		"Shape_Leng": 0.052467,
 	}
@@ -101,6 +149,116 @@ func TestOneRead(t *testing.T) {
 }
 
 func TestConcurrentReads(t *testing.T) {
-	go TestOneRead(t)
-	go TestOneRead(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			TestOneRead(t)
+		}()
+	}
+	wg.Wait()
+}
+
+// seekOnly hides the ReadAt method of a *bytes.Reader.
+type seekOnly struct {
+	io.ReadSeeker
+}
+
+func TestNewReaderSeeker(t *testing.T) {
+	if _, ok := reader.src.(*bytes.Reader); !ok {
+		t.Errorf("expected a *bytes.Reader to be read at offsets, got %T", reader.src)
+	}
+	r, err := NewReader(seekOnly{bytes.NewReader(testData)})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, ok := r.src.(*seekReaderAt); !ok {
+		t.Errorf("expected a plain io.ReadSeeker to be wrapped, got %T", r.src)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rec, err := r.Read(0); err != nil || rec["OBJECTID"] != int64(1) {
+				t.Errorf("Read(0) returned %v, %v", rec, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewReaderAt(t *testing.T) {
+	r, err := NewReaderAt(bytes.NewReader(testData), int64(len(testData)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(r.FieldNames(), reader.FieldNames()) {
+		t.Fatalf("wrong FieldNames(): got %v, expected %v", r.FieldNames(), reader.FieldNames())
+	}
+}
+
+func TestNewReaderFromBytes(t *testing.T) {
+	r, err := NewReaderFromBytes(scanTable)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec, err := r.Read(3); err != nil || rec["NAME"] != "delta" {
+		t.Fatalf("Read(3) returned %v, %v", rec, err)
+	}
+	raw, err := r.RawRecord(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	raw[1] = 'X'
+	if rec, err := r.Read(0); err != nil || rec["ID"] != int64(1) {
+		t.Fatalf("modifying a raw record changed the table: %v, %v", rec, err)
+	}
+
+	truncated := scanTable[:len(scanTable)-5]
+	if r, err = NewReaderFromBytes(truncated); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = r.Read(3); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF reading a truncated record, got %v", err)
+	}
+}
+
+func TestDateField(t *testing.T) {
+	table := buildTable([]Field{mustField("DOB", 'D', 8, 0)}, " 19840317", "         ", " 00000000")
+	r, err := NewReader(bytes.NewReader(table))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []time.Time{time.Date(1984, 3, 17, 0, 0, 0, 0, time.UTC), {}, {}}
+	for i, e := range expected {
+		rec, err := r.Read(uint16(i))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !rec["DOB"].(time.Time).Equal(e) {
+			t.Errorf("Read(%d) returned DOB %v, expected %v", i, rec["DOB"], e)
+		}
+	}
+}
+
+func TestLogicalField(t *testing.T) {
+	table := buildTable([]Field{mustField("PAID", 'L', 1, 0)}, " T", " n", " ?", "  ", " X")
+	r, err := NewReader(bytes.NewReader(table))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for i, expected := range []interface{}{true, false, nil, nil} {
+		rec, err := r.Read(uint16(i))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if rec["PAID"] != expected {
+			t.Errorf("Read(%d) returned PAID %#v, expected %#v", i, rec["PAID"], expected)
+		}
+	}
+	if _, err = r.Read(4); err == nil {
+		t.Errorf("expected an error for an invalid logical value")
+	}
 }
@@ -0,0 +1,592 @@
+// Package dbfsql is a read-only database/sql driver for directories of DBF
+// tables.  Each .dbf file in the directory is a table named after the file,
+// without its extension:
+//
+//	db, err := sql.Open("dbf", "/data/accounts")
+//	rows, err := db.Query("SELECT NAME, BALANCE FROM customers WHERE BALANCE > ? ORDER BY NAME", 100)
+//
+// Only single-table SELECT statements are understood, with WHERE, ORDER BY,
+// LIMIT and OFFSET clauses; there are no joins, aggregates or expressions
+// in the select list.  Table and column names are matched
+// case-insensitively.  Values are bound to ? placeholders in order, or to
+// :name placeholders with sql.Named.
+//
+// A table's structural .cdx index is opened with it, and a tag keyed on a
+// single column that the WHERE clause compares with a value, with =, <, <=,
+// > or >=, in a condition every row must meet, is used to read only the
+// records in range rather than the whole table.  Rows found through an index
+// come in its order unless the statement has an ORDER BY clause.  A
+// statement prefixed with EXPLAIN returns a single PLAN column describing
+// which it does instead of running.
+//
+// Values are those decoded by package dbf, converted to the types
+// database/sql expects: integers become int64, currency becomes float64,
+// and blank dates, decoded as the zero time, become NULL.  Deleted records
+// are skipped.
+package dbfsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eentzel/dbf"
+)
+
+func init() {
+	sql.Register("dbf", Driver{})
+}
+
+// errReadOnly is returned for anything that would change the tables.
+var errReadOnly = errors.New("dbfsql: the driver is read-only")
+
+// Driver is the driver registered as "dbf".  Its data source name is the
+// path of a directory.
+type Driver struct{}
+
+// Open returns a connection to the tables in the directory dsn.  Tables
+// are opened as they are queried, and shared by the connection's queries
+// until it is closed.
+func (Driver) Open(dsn string) (driver.Conn, error) {
+	fi, err := os.Stat(dsn)
+	if err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("dbfsql: %s is not a directory", dsn)
+	}
+	return &conn{dir: dsn, cache: dbf.NewCache()}, nil
+}
+
+type conn struct {
+	dir   string
+	cache *dbf.Cache
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	q, err := parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("dbfsql: %s", err)
+	}
+	return &stmt{c, q}, nil
+}
+
+func (c *conn) Close() error {
+	return c.cache.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errReadOnly
+}
+
+// open opens the table called name.
+func (c *conn) open(name string) (*dbf.Table, func(), error) {
+	infos, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, fi := range infos {
+		if !fi.IsDir() && strings.EqualFold(fi.Name(), name+".dbf") {
+			return c.cache.Open(filepath.Join(c.dir, fi.Name()))
+		}
+	}
+	return nil, nil, fmt.Errorf("dbfsql: no table %q in %s", name, c.dir)
+}
+
+type stmt struct {
+	c *conn
+	q *query
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return s.q.params
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errReadOnly
+}
+
+// QueryContext binds args to the statement's placeholders, by position or,
+// for arguments made with sql.Named, by name, and runs it.  Values are
+// bound rather than substituted into the statement, so a value can't
+// change what the statement does.
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	values := make([]driver.Value, s.q.params)
+	for _, arg := range args {
+		i := arg.Ordinal - 1
+		if arg.Name != "" {
+			i = -1
+			for j, name := range s.q.names {
+				if strings.EqualFold(name, arg.Name) {
+					i = j
+				}
+			}
+			if i < 0 {
+				return nil, fmt.Errorf("dbfsql: the statement has no placeholder :%s", arg.Name)
+			}
+		}
+		if i < 0 || i >= len(values) {
+			return nil, fmt.Errorf("dbfsql: argument %d has no placeholder", arg.Ordinal)
+		}
+		values[i] = arg.Value
+	}
+	return s.Query(values)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	q := s.q
+	t, release, err := s.c.open(q.table)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.query(t, args)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	r.release = release
+	return r, nil
+}
+
+// query runs the statement against t.
+func (s *stmt) query(t *dbf.Table, args []driver.Value) (*rows, error) {
+	q := s.q
+	names := t.FieldNames()
+	resolve := func(col string) (string, error) {
+		for _, name := range names {
+			if strings.EqualFold(name, col) {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("dbfsql: table %s has no column %q", q.table, col)
+	}
+
+	// Decode only the columns the statement refers to.
+	r := &rows{}
+	if q.columns == nil {
+		r.columns = names
+	}
+	for _, col := range q.columns {
+		name, err := resolve(col)
+		if err != nil {
+			return nil, err
+		}
+		r.columns = append(r.columns, name)
+	}
+	needed := map[string]bool{}
+	for _, name := range r.columns {
+		needed[name] = true
+	}
+	var refs []string
+	if q.where != nil {
+		refs = q.where.columns()
+	}
+	for _, o := range q.orderBy {
+		refs = append(refs, o.column)
+	}
+	for _, col := range refs {
+		name, err := resolve(col)
+		if err != nil {
+			return nil, err
+		}
+		needed[name] = true
+	}
+	var selected []string
+	for _, name := range names {
+		if needed[name] {
+			selected = append(selected, name)
+		}
+	}
+	view, err := t.Select(selected...)
+	if err != nil {
+		return nil, err
+	}
+
+	pl := s.plan(t, args)
+	if q.explain {
+		return explain(pl), nil
+	}
+	if pl.tag == "" {
+		r.it = view.Iterate()
+	} else {
+		x, err := view.Index(pl.tag)
+		if err != nil {
+			return nil, err
+		}
+		if r.it, err = x.IterateRange(pl.from, pl.to); err != nil {
+			return nil, fmt.Errorf("dbfsql: %s", err)
+		}
+	}
+	r.next = func() (map[string]driver.Value, error) {
+		for {
+			rec, err := r.it.Next()
+			if err != nil {
+				return nil, err
+			}
+			row := make(map[string]driver.Value, len(rec))
+			for name, v := range rec {
+				row[strings.ToUpper(name)] = value(v)
+			}
+			if q.where != nil {
+				ok, err := q.where.eval(row, args)
+				if err != nil {
+					return nil, fmt.Errorf("dbfsql: %s", err)
+				} else if !ok {
+					continue
+				}
+			}
+			return row, nil
+		}
+	}
+	if q.orderBy != nil {
+		if err := r.sort(q.orderBy); err != nil {
+			return nil, err
+		}
+	}
+	r.limit, r.offset = q.limit, q.offset
+	return r, nil
+}
+
+type rows struct {
+	columns []string
+	it      interface{ Next() (dbf.Record, error) } // a table or index iterator
+	next    func() (map[string]driver.Value, error)
+	sorted  []map[string]driver.Value // all the rows, if they are ordered
+	limit   int                       // rows left to return, -1 for no limit
+	offset  int                       // rows left to skip before the first
+	release func()
+}
+
+// explain returns the row describing plan p.
+func explain(p plan) *rows {
+	row := map[string]driver.Value{"PLAN": p.String()}
+	return &rows{
+		columns: []string{"PLAN"},
+		next: func() (map[string]driver.Value, error) {
+			if row == nil {
+				return nil, io.EOF
+			}
+			defer func() { row = nil }()
+			return row, nil
+		},
+		limit: -1,
+	}
+}
+
+// sort reads all the matching rows and orders them.
+func (r *rows) sort(by []order) error {
+	r.sorted = []map[string]driver.Value{}
+	for {
+		row, err := r.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		r.sorted = append(r.sorted, row)
+	}
+	var cmpErr error
+	sort.SliceStable(r.sorted, func(i, j int) bool {
+		for _, o := range by {
+			a, b := r.sorted[i][strings.ToUpper(o.column)], r.sorted[j][strings.ToUpper(o.column)]
+			c, err := compareValues(a, b)
+			if err != nil && cmpErr == nil {
+				cmpErr = err
+			}
+			if c != 0 {
+				return c < 0 != o.desc
+			}
+		}
+		return false
+	})
+	if cmpErr != nil {
+		return fmt.Errorf("dbfsql: can't order rows: %s", cmpErr)
+	}
+	r.next = func() (map[string]driver.Value, error) {
+		if len(r.sorted) == 0 {
+			return nil, io.EOF
+		}
+		row := r.sorted[0]
+		r.sorted = r.sorted[1:]
+		return row, nil
+	}
+	return nil
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.limit == 0 {
+		return io.EOF
+	}
+	for ; r.offset > 0; r.offset-- {
+		if _, err := r.next(); err != nil {
+			return err
+		}
+	}
+	row, err := r.next()
+	if err != nil {
+		return err
+	}
+	if r.limit > 0 {
+		r.limit--
+	}
+	for i, name := range r.columns {
+		dest[i] = row[strings.ToUpper(name)]
+	}
+	return nil
+}
+
+// value converts a decoded value to one of the types of driver.Value.
+func value(v interface{}) driver.Value {
+	switch v := v.(type) {
+	case nil, int64, float64, bool, []byte, string:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case dbf.Currency:
+		return v.Float64()
+	case time.Time:
+		if v.IsZero() {
+			return nil
+		}
+		return v
+	case *big.Int:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+type logic struct {
+	and  bool
+	l, r expr
+}
+
+func (e logic) eval(row map[string]driver.Value, args []driver.Value) (bool, error) {
+	l, err := e.l.eval(row, args)
+	if err != nil || l != e.and {
+		return l, err
+	}
+	return e.r.eval(row, args)
+}
+
+func (e logic) columns() []string {
+	return append(e.l.columns(), e.r.columns()...)
+}
+
+type not struct {
+	e expr
+}
+
+func (e not) eval(row map[string]driver.Value, args []driver.Value) (bool, error) {
+	ok, err := e.e.eval(row, args)
+	return !ok, err
+}
+
+func (e not) columns() []string {
+	return e.e.columns()
+}
+
+type isNull struct {
+	column string
+	negate bool
+}
+
+func (e isNull) eval(row map[string]driver.Value, args []driver.Value) (bool, error) {
+	return (row[strings.ToUpper(e.column)] == nil) != e.negate, nil
+}
+
+func (e isNull) columns() []string {
+	return []string{e.column}
+}
+
+// A comparison compares a column with an operand.  Comparisons with NULL
+// are false.
+type comparison struct {
+	column string
+	op     string
+	v      operand
+}
+
+func (e comparison) eval(row map[string]driver.Value, args []driver.Value) (bool, error) {
+	a, b := row[strings.ToUpper(e.column)], e.v.value(args)
+	if a == nil || b == nil {
+		return false, nil
+	}
+	if e.op == "LIKE" {
+		pattern, ok := b.(string)
+		if !ok {
+			return false, fmt.Errorf("LIKE takes a string pattern, not %T", b)
+		}
+		s, ok := a.(string)
+		if !ok {
+			s = fmt.Sprint(a)
+		}
+		return like(pattern).MatchString(s), nil
+	}
+	c, err := compareValues(a, b)
+	if err != nil {
+		return false, fmt.Errorf("can't compare %s with %v: %s", e.column, b, err)
+	}
+	switch e.op {
+	case "=":
+		return c == 0, nil
+	case "<>", "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+func (e comparison) columns() []string {
+	return []string{e.column}
+}
+
+// like compiles a LIKE pattern, in which % matches any run of characters
+// and _ any single character.
+func like(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?s)^")
+	for _, c := range pattern {
+		switch c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// compareValues orders two values, which must both be numbers, strings,
+// bools or times; a time may be compared with a string holding a date in
+// the form 2006-01-02 or 20060102.  NULL sorts first.
+func compareValues(a, b driver.Value) (int, error) {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0, nil
+		case a == nil:
+			return -1, nil
+		}
+		return 1, nil
+	}
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		if !ok {
+			return 0, fmt.Errorf("%T is not a number", b)
+		}
+		return compareFloats(x, y), nil
+	}
+	switch x := a.(type) {
+	case time.Time:
+		y, err := toTime(b)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case x.Before(y):
+			return -1, nil
+		case x.After(y):
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		if _, ok := b.(time.Time); ok {
+			c, err := compareValues(b, a)
+			return -c, err
+		}
+		y, ok := b.(string)
+		if !ok {
+			return 0, fmt.Errorf("%T is not a string", b)
+		}
+		return strings.Compare(x, y), nil
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, fmt.Errorf("%T is not a bool", b)
+		}
+		switch {
+		case x == y:
+			return 0, nil
+		case !x:
+			return -1, nil
+		}
+		return 1, nil
+	}
+	return 0, fmt.Errorf("values of type %T can't be compared", a)
+}
+
+func number(v driver.Value) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func compareFloats(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func toTime(v driver.Value) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range []string{"2006-01-02", "20060102"} {
+			if d, err := time.Parse(layout, v); err == nil {
+				return d, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a date", v)
+	}
+	return time.Time{}, fmt.Errorf("%T is not a date", v)
+}
@@ -0,0 +1,246 @@
+package dbfsql
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eentzel/dbf"
+	"github.com/eentzel/dbf/dbftest"
+)
+
+// openDir writes a table to a temporary directory and opens it.  The
+// returned func closes the database and removes the directory.
+func openDir(t *testing.T) (*sql.DB, func()) {
+	return openDirWith(t, nil)
+}
+
+// openDirWith is like openDir, but also writes files to the directory,
+// such as an index of the table.
+func openDirWith(t *testing.T, extra map[string][]byte) (*sql.DB, func()) {
+	dir, err := ioutil.TempDir("", "dbfsql")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	fx := dbftest.MustBuild([]dbf.Field{
+		dbftest.Field("NAME", 'C', 10, 0),
+		dbftest.Field("QTY", 'N', 5, 0),
+		dbftest.Field("PRICE", 'N', 8, 2),
+		dbftest.Field("SOLD", 'D', 8, 0),
+		dbftest.Field("ACTIVE", 'L', 1, 0),
+	},
+		dbf.Record{"NAME": "apple", "QTY": 10, "PRICE": 0.5, "SOLD": time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), "ACTIVE": true},
+		dbf.Record{"NAME": "banana", "QTY": 25, "PRICE": 0.25, "ACTIVE": false},
+		dbf.Record{"NAME": "cherry", "QTY": 3, "PRICE": 4.75, "SOLD": time.Date(2021, 7, 9, 0, 0, 0, 0, time.UTC), "ACTIVE": true},
+		dbf.Record{"NAME": "date", "QTY": 25, "PRICE": 3, "ACTIVE": true},
+	)
+	files := map[string][]byte{"FRUIT.DBF": fx.DBF}
+	for name, data := range extra {
+		files[name] = data
+	}
+	for name, data := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("%s", err)
+		}
+	}
+	db, err := sql.Open("dbf", dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("%s", err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// names runs a query selecting NAME and returns the names in order.
+func names(t *testing.T, db *sql.DB, query string, args ...interface{}) []string {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %s", query, err)
+	}
+	defer rows.Close()
+	got := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("%s: %s", query, err)
+		}
+		got = append(got, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("%s: %s", query, err)
+	}
+	return got
+}
+
+func TestQuery(t *testing.T) {
+	db, done := openDir(t)
+	defer done()
+	for _, c := range []struct {
+		query string
+		args  []interface{}
+		want  []string
+	}{
+		{"SELECT name FROM fruit", nil, []string{"apple", "banana", "cherry", "date"}},
+		{"select NAME from Fruit where QTY = 25", nil, []string{"banana", "date"}},
+		{"SELECT NAME FROM FRUIT WHERE QTY > ? AND PRICE < ?", []interface{}{5, 1}, []string{"apple", "banana"}},
+		{"SELECT NAME FROM FRUIT WHERE NOT (QTY = 25 OR ACTIVE = FALSE)", nil, []string{"apple", "cherry"}},
+		{"SELECT NAME FROM FRUIT WHERE NAME LIKE '%an%' OR NAME LIKE 'd_te'", nil, []string{"banana", "date"}},
+		{"SELECT NAME FROM FRUIT WHERE NAME NOT LIKE '%e'", nil, []string{"banana", "cherry"}},
+		{"SELECT NAME FROM FRUIT WHERE SOLD IS NULL", nil, []string{"banana", "date"}},
+		{"SELECT NAME FROM FRUIT WHERE SOLD >= '2021-01-01'", nil, []string{"cherry"}},
+		{"SELECT NAME FROM FRUIT WHERE PRICE <> -1.5 ORDER BY QTY DESC, NAME DESC LIMIT 3", nil, []string{"date", "banana", "apple"}},
+		{"SELECT NAME FROM FRUIT ORDER BY PRICE;", nil, []string{"banana", "apple", "date", "cherry"}},
+		{"SELECT NAME FROM FRUIT LIMIT 0", nil, []string{}},
+		{"SELECT NAME FROM FRUIT ORDER BY NAME DESC LIMIT 2 OFFSET 1", nil, []string{"cherry", "banana"}},
+		{"SELECT NAME FROM FRUIT WHERE QTY > 5 OFFSET 2", nil, []string{"date"}},
+		{"SELECT NAME FROM FRUIT OFFSET 9", nil, []string{}},
+		{"SELECT NAME FROM FRUIT WHERE NAME = 'it''s'", nil, []string{}},
+		{"SELECT NAME FROM FRUIT WHERE QTY > :min AND PRICE < :max OR QTY = :min", []interface{}{sql.Named("max", 1), sql.Named("min", 5)}, []string{"apple", "banana"}},
+		{"SELECT NAME FROM FRUIT WHERE NAME = ?", []interface{}{"x' OR NAME <> 'x"}, []string{}},
+	} {
+		if got := names(t, db, c.query, c.args...); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	db, done := openDir(t)
+	defer done()
+	rows, err := db.Query("SELECT * FROM FRUIT WHERE NAME = 'apple' OR NAME = 'banana'")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want := []string{"NAME", "QTY", "PRICE", "SOLD", "ACTIVE"}; !reflect.DeepEqual(cols, want) {
+		t.Errorf("got columns %q, want %q", cols, want)
+	}
+	var got [][]interface{}
+	for rows.Next() {
+		row := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatalf("%s", err)
+		}
+		got = append(got, row)
+	}
+	want := [][]interface{}{
+		{"apple", int64(10), 0.5, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"banana", int64(25), 0.25, nil, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	db, done := openDir(t)
+	defer done()
+	for query, want := range map[string]string{
+		"SELECT NAME FROM NOSUCH":                               "no table",
+		"SELECT COLOR FROM FRUIT":                               "no column",
+		"SELECT NAME FROM FRUIT ORDER BY COLOR":                 "no column",
+		"SELECT NAME FROM FRUIT WHERE NAME > 3":                 "can't compare",
+		"SELECT NAME FROM FRUIT WHERE":                          "expected a name",
+		"SELECT NAME FROM FRUIT LIMIT x":                        "LIMIT",
+		"SELECT NAME FROM FRUIT OFFSET -1":                      "OFFSET",
+		"SELECT NAME FROM FRUIT WHERE NAME = 'a":                "unterminated",
+		"SELECT NAME, FROM FRUIT":                               "expected FROM",
+		"SELECT NAME FROM FRUIT GROUP BY NAME":                  "unexpected",
+		"DELETE FROM FRUIT":                                     "expected SELECT",
+		"SELECT NAME FROM FRUIT WHERE (QTY = 1":                 "expected )",
+		"SELECT NAME FROM FRUIT WHERE NAME LIKE 1":              "string pattern",
+		"SELECT NAME FROM FRUIT WHERE QTY BETWEEN 1":            "comparison",
+		"SELECT NAME FROM FRUIT WHERE QTY > ? AND PRICE < :max": "can't be mixed",
+	} {
+		rows, err := db.Query(query)
+		if err == nil {
+			for rows.Next() {
+			}
+			err = rows.Err()
+			rows.Close()
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want one containing %q", query, err, want)
+		}
+	}
+	if _, err := db.Exec("SELECT NAME FROM FRUIT"); err != errReadOnly {
+		t.Errorf("Exec: got %v, want %v", err, errReadOnly)
+	}
+	if _, err := sql.Open("dbf", "/no/such/dir"); err != nil {
+		t.Errorf("sql.Open shouldn't connect yet: %s", err)
+	}
+}
+
+// cdxIndex builds a .cdx file holding a single index on expr, whose keys
+// all fit one leaf node.
+func cdxIndex(keyLen int, expr string, keys []string, recnos []int) []byte {
+	data := make([]byte, 1024+512)
+	binary.LittleEndian.PutUint32(data[0:], 1024)
+	binary.LittleEndian.PutUint16(data[12:], uint16(keyLen))
+	copy(data[512:], expr)
+
+	// Keys are stored whole, with 16 bits for record numbers and 4 each
+	// for the counts of duplicate and trailing bytes, which are 0.
+	node := data[1024:]
+	node[0] = 0x03
+	binary.LittleEndian.PutUint16(node[2:], uint16(len(keys)))
+	binary.LittleEndian.PutUint32(node[4:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(node[8:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(node[14:], 0xFFFF)
+	node[18], node[19], node[20], node[21], node[22], node[23] = 0x0F, 0x0F, 16, 4, 4, 3
+	end := 512
+	for i, k := range keys {
+		k = fmt.Sprintf("%-*s", keyLen, k)
+		end -= keyLen
+		copy(node[end:], k)
+		node[24+3*i], node[25+3*i] = byte(recnos[i]), byte(recnos[i]>>8)
+	}
+	return data
+}
+
+func TestIndex(t *testing.T) {
+	// The index leaves out cherry, so that the rows read through it can be
+	// told apart from those found by reading the table.
+	cdx := cdxIndex(10, "NAME", []string{"apple", "banana", "date"}, []int{1, 2, 4})
+	db, done := openDirWith(t, map[string][]byte{"FRUIT.CDX": cdx})
+	defer done()
+	for _, c := range []struct {
+		query string
+		args  []interface{}
+		plan  string
+		want  []string
+	}{
+		{"SELECT NAME FROM FRUIT WHERE NAME = ?", []interface{}{"banana"}, "SEARCH FRUIT USING INDEX FRUIT (NAME = 'banana')", []string{"banana"}},
+		{"SELECT NAME FROM FRUIT WHERE NAME > 'b' AND QTY > 1", nil, "SEARCH FRUIT USING INDEX FRUIT (NAME >= 'b')", []string{"banana", "date"}},
+		{"SELECT NAME FROM FRUIT WHERE NAME < 'c' AND NAME >= 'apple'", nil, "SEARCH FRUIT USING INDEX FRUIT (NAME >= 'apple' AND NAME <= 'c')", []string{"apple", "banana"}},
+		{"SELECT NAME FROM FRUIT WHERE NAME > 'b' OR QTY > 5", nil, "SCAN FRUIT", []string{"apple", "banana", "cherry", "date"}},
+		{"SELECT NAME FROM FRUIT WHERE QTY < 5", nil, "SCAN FRUIT", []string{"cherry"}},
+		{"SELECT NAME FROM FRUIT WHERE NAME = 'a much longer name'", nil, "SCAN FRUIT", []string{}},
+	} {
+		if got := names(t, db, "EXPLAIN "+c.query, c.args...); !reflect.DeepEqual(got, []string{c.plan}) {
+			t.Errorf("EXPLAIN %s: got %q, want %q", c.query, got, c.plan)
+		}
+		if got := names(t, db, c.query, c.args...); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %q, want %q", c.query, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package dbfsql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A query is a parsed SELECT statement.
+type query struct {
+	explain bool // describe the plan instead of running the statement
+	table   string
+	columns []string // nil for SELECT *
+	where   expr     // nil if there is no WHERE clause
+	orderBy []order
+	limit   int      // -1 if there is no LIMIT clause
+	offset  int      // rows to skip before the first one returned
+	params  int      // number of placeholders
+	names   []string // of the :name placeholders, nil for ? ones
+}
+
+type order struct {
+	column string
+	desc   bool
+}
+
+// An expr is a condition of a WHERE clause.
+type expr interface {
+	eval(row map[string]driver.Value, args []driver.Value) (bool, error)
+	columns() []string
+}
+
+type token struct {
+	kind byte // 'i'dentifier, 'n'umber, 's'tring, 'p'laceholder name, 'o'perator or punctuation, 0 at the end
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			toks = append(toks, token{'i', s[i:j]})
+			i = j
+		case c == ':' && i+1 < len(s) && (s[i+1] == '_' || unicode.IsLetter(rune(s[i+1]))):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			toks = append(toks, token{'p', s[i+1 : j]})
+			i = j
+		case unicode.IsDigit(c) || c == '.' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1])):
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{'n', s[i:j]})
+			i = j
+		case c == '\'' || c == '"':
+			// Strings are single-quoted and identifiers may be
+			// double-quoted; either quote is escaped by doubling it.
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j == len(s) {
+					return nil, fmt.Errorf("unterminated %c at offset %d", c, i)
+				} else if s[j] == s[i] && j+1 < len(s) && s[j+1] == s[i] {
+					b.WriteByte(s[i])
+					j += 2
+				} else if s[j] == s[i] {
+					break
+				} else {
+					b.WriteByte(s[j])
+					j++
+				}
+			}
+			kind := byte('s')
+			if c == '"' {
+				kind = 'i'
+			}
+			toks = append(toks, token{kind, b.String()})
+			i = j + 1
+		default:
+			op := s[i : i+1]
+			if i+1 < len(s) {
+				switch two := s[i : i+2]; two {
+				case "<=", ">=", "<>", "!=":
+					op = two
+				}
+			}
+			if !strings.Contains("*,()?;=<>-", op[:1]) || op == "!" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", op, i)
+			}
+			toks = append(toks, token{'o', op})
+			i += len(op)
+		}
+	}
+	return append(toks, token{}), nil
+}
+
+type parser struct {
+	toks   []token
+	pos    int
+	params int
+	names  []string // of the :name placeholders seen so far
+}
+
+// parse parses a statement of the form
+//
+//	[EXPLAIN] SELECT * | column, ... FROM table
+//	    [WHERE condition] [ORDER BY column [ASC | DESC], ...]
+//	    [LIMIT n] [OFFSET n]
+//
+// where conditions combine comparisons of a column with a literal or a
+// placeholder, LIKE patterns and IS [NOT] NULL tests with AND, OR, NOT and
+// parentheses.  Placeholders are either all ?, bound in order, or all
+// :name, bound by name; a name may appear more than once.
+func parse(s string) (*query, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	q := &query{limit: -1}
+	q.explain = p.acceptKeyword("EXPLAIN")
+	if err = p.keyword("SELECT"); err != nil {
+		return nil, err
+	}
+	if p.accept('o', "*") {
+		q.columns = nil
+	} else {
+		for {
+			col, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			q.columns = append(q.columns, col)
+			if !p.accept('o', ",") {
+				break
+			}
+		}
+	}
+	if err = p.keyword("FROM"); err != nil {
+		return nil, err
+	}
+	if q.table, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if p.acceptKeyword("WHERE") {
+		if q.where, err = p.or(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err = p.keyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			col, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			o := order{column: col}
+			if p.acceptKeyword("DESC") {
+				o.desc = true
+			} else {
+				p.acceptKeyword("ASC")
+			}
+			q.orderBy = append(q.orderBy, o)
+			if !p.accept('o', ",") {
+				break
+			}
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		if q.limit, err = p.count("LIMIT"); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("OFFSET") {
+		if q.offset, err = p.count("OFFSET"); err != nil {
+			return nil, err
+		}
+	}
+	p.accept('o', ";")
+	if t := p.peek(); t.kind != 0 {
+		return nil, fmt.Errorf("unexpected %q at the end of the statement", t.text)
+	}
+	q.params, q.names = p.params, p.names
+	return q, nil
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != 0 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(kind byte, text string) bool {
+	if t := p.peek(); t.kind == kind && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptKeyword(kw string) bool {
+	if t := p.peek(); t.kind == 'i' && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) keyword(kw string) error {
+	if !p.acceptKeyword(kw) {
+		return fmt.Errorf("expected %s, found %q", kw, p.peek().text)
+	}
+	return nil
+}
+
+// count parses the number of rows following keyword kw.
+func (p *parser) count(kw string) (int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != 'n' || err != nil || n < 0 {
+		return 0, fmt.Errorf("%s takes a count of rows, not %q", kw, t.text)
+	}
+	return n, nil
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.kind != 'i' {
+		return "", fmt.Errorf("expected a name, found %q", t.text)
+	}
+	return t.text, nil
+}
+
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	for err == nil && p.acceptKeyword("OR") {
+		var r expr
+		if r, err = p.and(); err == nil {
+			l = logic{false, l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) and() (expr, error) {
+	l, err := p.unary()
+	for err == nil && p.acceptKeyword("AND") {
+		var r expr
+		if r, err = p.unary(); err == nil {
+			l = logic{true, l, r}
+		}
+	}
+	return l, err
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.acceptKeyword("NOT") {
+		e, err := p.unary()
+		return not{e}, err
+	}
+	if p.accept('o', "(") {
+		e, err := p.or()
+		if err == nil && !p.accept('o', ")") {
+			err = fmt.Errorf("expected ), found %q", p.peek().text)
+		}
+		return e, err
+	}
+	col, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if p.acceptKeyword("IS") {
+		negate := p.acceptKeyword("NOT")
+		if err = p.keyword("NULL"); err != nil {
+			return nil, err
+		}
+		return isNull{col, negate}, nil
+	}
+	if p.acceptKeyword("NOT") {
+		if err = p.keyword("LIKE"); err != nil {
+			return nil, err
+		}
+		v, err := p.operand()
+		return not{comparison{col, "LIKE", v}}, err
+	}
+	op := "LIKE"
+	if !p.acceptKeyword("LIKE") {
+		t := p.next()
+		switch t.text {
+		case "=", "<>", "!=", "<", "<=", ">", ">=":
+			op = t.text
+		default:
+			return nil, fmt.Errorf("expected a comparison after %s, found %q", col, t.text)
+		}
+	}
+	v, err := p.operand()
+	return comparison{col, op, v}, err
+}
+
+// An operand is a literal, or placeholder param if it is at least 0.
+type operand struct {
+	lit   driver.Value
+	param int
+}
+
+func (p *parser) operand() (operand, error) {
+	neg := p.accept('o', "-")
+	t := p.next()
+	switch {
+	case t.kind == 'n':
+		if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			if neg {
+				n = -n
+			}
+			return operand{n, -1}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("bad number %q", t.text)
+		}
+		if neg {
+			f = -f
+		}
+		return operand{f, -1}, nil
+	case neg:
+	case t.kind == 's':
+		return operand{t.text, -1}, nil
+	case t.kind == 'o' && t.text == "?":
+		if p.names != nil {
+			return operand{}, fmt.Errorf("? and :name placeholders can't be mixed")
+		}
+		p.params++
+		return operand{nil, p.params - 1}, nil
+	case t.kind == 'p':
+		if p.params > len(p.names) {
+			return operand{}, fmt.Errorf("? and :name placeholders can't be mixed")
+		}
+		for i, name := range p.names {
+			if name == t.text {
+				return operand{nil, i}, nil
+			}
+		}
+		p.names = append(p.names, t.text)
+		p.params++
+		return operand{nil, p.params - 1}, nil
+	case t.kind == 'i' && strings.EqualFold(t.text, "TRUE"):
+		return operand{true, -1}, nil
+	case t.kind == 'i' && strings.EqualFold(t.text, "FALSE"):
+		return operand{false, -1}, nil
+	}
+	return operand{}, fmt.Errorf("expected a value, found %q", t.text)
+}
+
+func (o operand) value(args []driver.Value) driver.Value {
+	if o.param >= 0 {
+		return args[o.param]
+	}
+	return o.lit
+}
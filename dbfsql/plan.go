@@ -0,0 +1,148 @@
+package dbfsql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eentzel/dbf"
+)
+
+// A plan is how a statement finds its rows: by reading the whole table, or
+// through an index on a column the WHERE clause compares with a value.
+// Either way every row is checked against the WHERE clause, so the index
+// need only narrow down the records to read.
+type plan struct {
+	table    string
+	tag      string // "" to read the whole table
+	column   string
+	from, to interface{} // bounds of the keys to read, nil if open
+	equal    bool        // from and to come from a single = comparison
+}
+
+// plan picks the first column of the WHERE clause that is compared with a
+// value, in a condition all rows must meet, and that an index of t is keyed
+// on.  Unique indexes and those with a FOR clause leave records out, so
+// they aren't used.
+func (s *stmt) plan(t *dbf.Table, args []driver.Value) plan {
+	p := plan{table: s.q.table}
+	if s.q.where == nil {
+		return p
+	}
+	types := map[string]dbf.FieldInfo{}
+	for _, f := range t.Fields() {
+		types[strings.ToUpper(f.Name)] = f
+	}
+	conds := conjuncts(s.q.where)
+	for _, c := range conds {
+		for _, tag := range t.IndexTags() {
+			x, err := t.Index(tag)
+			if err != nil || x.Unique() || x.Filtered() || !strings.EqualFold(x.Field(), c.column) {
+				continue
+			}
+			p.tag, p.column = x.Tag(), x.Field()
+			for _, c := range conds {
+				if !strings.EqualFold(c.column, p.column) {
+					continue
+				}
+				v, ok := indexKey(types[strings.ToUpper(c.column)], c.v.value(args))
+				if !ok {
+					continue
+				}
+				switch c.op {
+				case "=":
+					if p.from == nil && p.to == nil {
+						p.from, p.to, p.equal = v, v, true
+					}
+				case ">", ">=":
+					if p.from == nil {
+						p.from = v
+					}
+				case "<", "<=":
+					if p.to == nil {
+						p.to = v
+					}
+				}
+			}
+			if p.from != nil || p.to != nil {
+				return p
+			}
+			p.tag, p.column = "", ""
+		}
+	}
+	return p
+}
+
+// conjuncts returns the comparisons that all rows matching e must meet.
+func conjuncts(e expr) []comparison {
+	switch e := e.(type) {
+	case logic:
+		if e.and {
+			return append(conjuncts(e.l), conjuncts(e.r)...)
+		}
+	case comparison:
+		if e.op != "LIKE" && e.op != "<>" && e.op != "!=" {
+			return []comparison{e}
+		}
+	}
+	return nil
+}
+
+// indexKey converts v to a key of an index on field f, if keys ordered as
+// the index orders them are ordered as the driver compares values.  That
+// holds of numbers and dates, and of character keys if they are plain
+// ASCII, which sorts the same in any code page.
+func indexKey(f dbf.FieldInfo, v driver.Value) (interface{}, bool) {
+	switch f.Type {
+	case 'C':
+		s, ok := v.(string)
+		if !ok || len(s) > f.Len {
+			return nil, false
+		}
+		for i := 0; i < len(s); i++ {
+			if s[i] < ' ' || s[i] > '~' {
+				return nil, false
+			}
+		}
+		return s, true
+	case 'N', 'F', 'I', 'B', 'Y':
+		n, ok := number(v)
+		return n, ok
+	case 'D':
+		d, err := toTime(v)
+		return d, err == nil
+	}
+	return nil, false
+}
+
+// String describes the plan, as EXPLAIN does.
+func (p plan) String() string {
+	if p.tag == "" {
+		return "SCAN " + p.table
+	}
+	var conds []string
+	switch {
+	case p.equal:
+		conds = append(conds, p.column+" = "+literal(p.from))
+	default:
+		if p.from != nil {
+			conds = append(conds, p.column+" >= "+literal(p.from))
+		}
+		if p.to != nil {
+			conds = append(conds, p.column+" <= "+literal(p.to))
+		}
+	}
+	return fmt.Sprintf("SEARCH %s USING INDEX %s (%s)", p.table, p.tag, strings.Join(conds, " AND "))
+}
+
+// literal formats a key as it would be written in a statement.
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case time.Time:
+		return "'" + v.Format("2006-01-02") + "'"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,183 @@
+// Package dbftest builds small, valid tables in memory from Go literals, so
+// that tests of code using package dbf don't need binary fixtures:
+//
+//	fx := dbftest.MustBuild([]dbf.Field{
+//		dbftest.Field("ID", 'N', 5, 0),
+//		dbftest.Field("NOTES", 'M', 10, 0),
+//	}, dbf.Record{"ID": 1, "NOTES": "first"}, dbf.Record{"ID": 2})
+//	r, err := fx.Open()
+package dbftest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"github.com/eentzel/dbf"
+)
+
+// A Fixture is a table built in memory.
+type Fixture struct {
+	DBF []byte
+	DBT []byte // dBase III memo file, nil if the table has no memo fields
+}
+
+// Open opens the fixture, with its memo file if it has one.
+func (fx *Fixture) Open(opts ...dbf.Option) (*dbf.Reader, error) {
+	if fx.DBT != nil {
+		opts = append([]dbf.Option{dbf.WithMemoBytes(fx.DBT)}, opts...)
+	}
+	return dbf.NewReaderFromBytes(fx.DBF, opts...)
+}
+
+// Field is dbf.NewField for literals: it panics if the field is invalid.
+func Field(name string, typ byte, length, decimals uint8) dbf.Field {
+	f, err := dbf.NewField(name, typ, length, decimals)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Build writes rows as a dBase III table with the given fields.  Values are
+// encoded as by dbf.Writer; fields missing from a row are left blank.  Memo
+// fields ('M') hold strings, which are stored in a dBase III memo file.
+func Build(fields []dbf.Field, rows ...dbf.Record) (*Fixture, error) {
+	// The Writer doesn't do memo fields, so they're written as numeric
+	// fields holding block numbers and retyped afterwards.
+	schema := append([]dbf.Field(nil), fields...)
+	var memos []int
+	for j, f := range schema {
+		if f.Type == 'M' {
+			schema[j].Type = 'N'
+			memos = append(memos, j)
+		}
+	}
+
+	var fx Fixture
+	if len(memos) > 0 {
+		fx.DBT = make([]byte, memoBlock)
+		fx.DBT[16] = 0x03 // dBase III
+	}
+	var buf buffer
+	w, err := dbf.NewWriter(&buf, schema)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		rec := make(dbf.Record, len(row))
+		for name, v := range row {
+			rec[name] = v
+		}
+		for _, j := range memos {
+			name := fieldName(fields[j])
+			v, ok := rec[name]
+			if !ok || v == nil || v == "" {
+				delete(rec, name)
+				continue
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("row %d: memo field %s holds a %T, not a string", i, name, v)
+			}
+			rec[name] = int64(len(fx.DBT) / memoBlock)
+			fx.DBT = appendMemo(fx.DBT, s)
+		}
+		if err = w.Write(rec); err != nil {
+			return nil, fmt.Errorf("row %d: %s", i, err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	fx.DBF = buf.data
+	if len(memos) > 0 {
+		fx.DBF[0] = 0x83 // dBase III with memo
+		for _, j := range memos {
+			fx.DBF[32+32*j+11] = 'M'
+		}
+		binary.LittleEndian.PutUint32(fx.DBT, uint32(len(fx.DBT)/memoBlock))
+	}
+	return &fx, nil
+}
+
+// MustBuild is like Build but panics if the table can't be built.
+func MustBuild(fields []dbf.Field, rows ...dbf.Record) *Fixture {
+	fx, err := Build(fields, rows...)
+	if err != nil {
+		panic(err)
+	}
+	return fx
+}
+
+// FromStructs builds a table from a slice of structs, with the schema
+// dbf.SchemaFromStruct derives from their type and one record per element,
+// converted by dbf.RecordFromStruct.
+func FromStructs(rows interface{}) (*Fixture, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected a slice of structs, got %T", rows)
+	}
+	fields, err := dbf.SchemaFromStruct(v.Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+	recs := make([]dbf.Record, v.Len())
+	for i := range recs {
+		if recs[i], err = dbf.RecordFromStruct(v.Index(i).Interface()); err != nil {
+			return nil, fmt.Errorf("row %d: %s", i, err)
+		}
+	}
+	return Build(fields, recs...)
+}
+
+const memoBlock = 512
+
+// appendMemo adds s to a dBase III memo file: the text, two 0x1A
+// terminators, and padding to the end of the block.
+func appendMemo(dbt []byte, s string) []byte {
+	dbt = append(dbt, s...)
+	dbt = append(dbt, 0x1A, 0x1A)
+	if n := len(dbt) % memoBlock; n > 0 {
+		dbt = append(dbt, make([]byte, memoBlock-n)...)
+	}
+	return dbt
+}
+
+func fieldName(f dbf.Field) string {
+	n := 0
+	for n < len(f.Name) && f.Name[n] != 0 {
+		n++
+	}
+	return string(f.Name[:n])
+}
+
+// buffer is an in-memory io.WriteSeeker for the Writer.
+type buffer struct {
+	data []byte
+	pos  int
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	if need := b.pos + len(p); need > len(b.data) {
+		b.data = append(b.data, make([]byte, need-len(b.data))...)
+	}
+	copy(b.data[b.pos:], p)
+	b.pos += len(p)
+	return len(p), nil
+}
+
+func (b *buffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 1:
+		offset += int64(b.pos)
+	case 2:
+		offset += int64(len(b.data))
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position %d", offset)
+	}
+	b.pos = int(offset)
+	return offset, nil
+}
@@ -0,0 +1,68 @@
+package dbftest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eentzel/dbf"
+)
+
+func TestBuild(t *testing.T) {
+	fx := MustBuild([]dbf.Field{
+		Field("ID", 'N', 5, 0),
+		Field("NOTES", 'M', 10, 0),
+		Field("DUE", 'D', 8, 0),
+	},
+		dbf.Record{"ID": 1, "NOTES": "first note"},
+		dbf.Record{"ID": 2},
+		dbf.Record{"ID": 3, "NOTES": string(make([]byte, 600)) + "long"},
+	)
+	if fx.DBT == nil {
+		t.Fatalf("expected a memo file")
+	}
+	r, err := fx.Open()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []string{"first note", "", string(make([]byte, 600)) + "long"}
+	for i, notes := range expected {
+		rec, err := r.Read(uint16(i))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if rec["ID"] != int64(i+1) || rec["NOTES"] != notes {
+			t.Errorf("record %d: got %v", i, rec)
+		}
+	}
+
+	if _, err = Build([]dbf.Field{Field("NOTES", 'M', 10, 0)}, dbf.Record{"NOTES": 42}); err == nil {
+		t.Errorf("expected an error for a memo that isn't a string")
+	}
+}
+
+func TestFromStructs(t *testing.T) {
+	type invoice struct {
+		Number int    `dbf:"NUM,len=6"`
+		Client string `dbf:",len=20"`
+		Due    time.Time
+	}
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	fx, err := FromStructs([]invoice{{1, "acme", day}, {2, "globex", time.Time{}}})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r, err := fx.Open()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var inv invoice
+	if err = r.ReadInto(1, &inv); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if inv.Number != 2 || inv.Client != "globex" || !inv.Due.IsZero() {
+		t.Errorf("read back %+v", inv)
+	}
+	if err = r.ReadInto(0, &inv); err != nil || !inv.Due.Equal(day) {
+		t.Errorf("read back %+v, %v", inv, err)
+	}
+}
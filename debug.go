@@ -0,0 +1,97 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// A HeaderDump sets the raw bytes of a table's header next to their parsed
+// values, as a basis for hex-level tooling and bug reports.
+type HeaderDump struct {
+	Raw         []byte // the whole header, including descriptors and terminator
+	Version     byte
+	Year        int
+	Month, Day  int
+	Records     int
+	HeaderLen   int
+	RecordLen   int
+	Descriptors []DescriptorDump
+}
+
+// A DescriptorDump is one field descriptor of a HeaderDump.
+type DescriptorDump struct {
+	Raw    []byte // the bytes of the descriptor, 32 or, in a level 7 header, 48
+	Offset int    // position of the descriptor in the file
+	Name   string
+	Field  Field
+}
+
+// DumpHeader re-reads the table's header from the underlying file.  A
+// descriptor that runs past the end of the header, as in a file whose header
+// length is too short for its fields, is an error.
+func (t *Table) DumpHeader() (*HeaderDump, error) {
+	raw := make([]byte, t.headerlen)
+	if err := readFullAt(t.src, raw, 0); err != nil {
+		return nil, err
+	}
+	var h header
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	d := &HeaderDump{
+		Raw:       raw,
+		Version:   h.Version,
+		Year:      1900 + int(h.Year),
+		Month:     int(h.Month),
+		Day:       int(h.Day),
+		Records:   int(h.Nrec),
+		HeaderLen: int(h.Headerlen),
+		RecordLen: int(h.Recordlen),
+	}
+	for i, f := range t.fields {
+		offset := t.descOffsets[i]
+		if offset+t.descLen > len(raw) {
+			return nil, fmt.Errorf("descriptor of field %s at offset %d runs past the %d-byte header", t.FieldName(i), offset, len(raw))
+		}
+		d.Descriptors = append(d.Descriptors, DescriptorDump{raw[offset : offset+t.descLen], offset, t.FieldName(i), f})
+	}
+	return d, nil
+}
+
+// String renders the dump with each structure's bytes in hex on the left and
+// their interpretation on the right.
+func (d *HeaderDump) String() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%04x  % x  version=%#02x modified=%d-%02d-%02d records=%d headerlen=%d recordlen=%d\n",
+		0, d.Raw[:12], d.Version, d.Year, d.Month, d.Day, d.Records, d.HeaderLen, d.RecordLen)
+	end := 0x20
+	if len(d.Descriptors) > 0 {
+		end = d.Descriptors[0].Offset
+	} else if isLevel7(d.Version) && len(d.Raw) > level7Fields {
+		end = level7Fields
+	}
+	dumpRows(&b, d.Raw[12:end], 12)
+	for _, desc := range d.Descriptors {
+		f := desc.Field
+		fmt.Fprintf(&b, "%04x  % x  name=%q type=%c len=%d dec=%d\n",
+			desc.Offset, desc.Raw[:16], desc.Name, f.Type, f.Len, f.DecimalPlaces)
+		dumpRows(&b, desc.Raw[16:], desc.Offset+16)
+		end = desc.Offset + len(desc.Raw)
+	}
+	fmt.Fprintf(&b, "%04x  % x  terminator\n", end, d.Raw[end:])
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// dumpRows writes raw, found at offset, in hex, 16 bytes a line.
+func dumpRows(b *bytes.Buffer, raw []byte, offset int) {
+	for len(raw) > 0 {
+		n := len(raw)
+		if n > 16 {
+			n = 16
+		}
+		fmt.Fprintf(b, "%04x  % x\n", offset, raw[:n])
+		raw, offset = raw[n:], offset+n
+	}
+}
@@ -0,0 +1,63 @@
+package dbf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDumpHeader(t *testing.T) {
+	d, err := reader.DumpHeader()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(d.Raw, testData[:129]) {
+		t.Fatalf("wrong raw header: % x", d.Raw)
+	}
+	if len(d.Descriptors) != 3 || d.Descriptors[2].Name != "Shape_Leng" || d.Descriptors[2].Offset != 0x60 {
+		t.Fatalf("wrong descriptors: %+v", d.Descriptors)
+	}
+	s := d.String()
+	expected := "0000  03 6f 07 1a 0d 21 00 00 81 00 55 00  version=0x03 modified=2011-07-26 records=8461 headerlen=129 recordlen=85"
+	if !strings.HasPrefix(s, expected) {
+		t.Fatalf("wrong String():\n%s", s)
+	}
+	if !strings.Contains(s, "\n000c  "+strings.Repeat("00 ", 15)+"00\n001c  00 00 00 00\n") {
+		t.Fatalf("header rows aren't 16 bytes wide:\n%s", s)
+	}
+	if !strings.Contains(s, `0040  4e 61 6d 65 00 00 00 00 00 00 00 43 00 00 00 00  name="Name" type=C len=50 dec=0`) {
+		t.Fatalf("descriptor missing from String():\n%s", s)
+	}
+}
+
+func TestDumpHeaderLevel7(t *testing.T) {
+	data := buildLevel7([]Field{mustField("ID", 'I', 4, 0), mustField("NAME", 'C', 5, 0)}, []string{"ID", "CUSTOMER_NAME"})
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	d, err := tbl.DumpHeader()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(d.Descriptors) != 2 || d.Descriptors[1].Offset != 68+48 || !bytes.Equal(d.Descriptors[1].Raw, data[68+48:68+96]) {
+		t.Fatalf("wrong descriptors: %+v", d.Descriptors)
+	}
+	if s := d.String(); !strings.Contains(s, `0074  43 55 53 54 4f 4d 45 52 5f 4e 41 4d 45 00 00 00  name="CUSTOMER_NAME" type=C len=5 dec=0`) ||
+		!strings.Contains(s, "00a4  0d ") {
+		t.Fatalf("wrong String():\n%s", s)
+	}
+}
+
+func TestDumpHeaderShort(t *testing.T) {
+	// A header length that ends inside the descriptors.
+	data := buildTable([]Field{mustField("A", 'C', 1, 0), mustField("B", 'C', 1, 0), mustField("C", 'C', 1, 0)})
+	data[8] = 98
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = tbl.DumpHeader(); err == nil || !strings.Contains(err.Error(), "runs past") {
+		t.Fatalf("expected an error for a descriptor past the header, got %v", err)
+	}
+}
@@ -0,0 +1,439 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// An Editor is a Table whose records can be changed in place.
+//
+// Readers of the Editor's table, including Cursors, Iterators and Scans
+// running in other goroutines and the Tables it had before an Append or
+// Pack, are isolated from its writes record by record: a record read while
+// it is being updated comes back whole, either as it was or as it is
+// afterwards, never torn between the two.  The isolation doesn't reach
+// further than single records, so a scan running across a Session's Commit
+// may see some of its records changed and not others, nor does it cover
+// Tables opened separately on the same file, or other programs, for which
+// Snapshot takes a consistent copy.
+type Editor struct {
+	*Table
+	w     io.WriterAt
+	trunc func(size int64) error // nil if the file can't be truncated
+}
+
+// NewEditor opens the table in rw, typically an *os.File opened for reading
+// and writing, for editing.  As with NewReader, reads and writes go to their
+// offsets directly if rw supports it, and take turns on its seek position
+// otherwise.
+func NewEditor(rw io.ReadWriteSeeker, opts ...Option) (*Editor, error) {
+	size, err := rw.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+	var src io.ReaderAt
+	var w io.WriterAt
+	if rwa, ok := rw.(interface {
+		io.ReaderAt
+		io.WriterAt
+	}); ok {
+		src, w = rwa, rwa
+	} else {
+		s := &seekReaderAt{r: rw}
+		src, w = s, seekWriterAt{s, rw}
+	}
+	l := &latch{}
+	opts = append(opts[:len(opts):len(opts)], withLatch(l))
+	t, err := OpenTable(src, size, opts...)
+	if err != nil {
+		return nil, err
+	}
+	e := &Editor{Table: t, w: latchedWriterAt{w, l}}
+	if tr, ok := rw.(interface {
+		Truncate(size int64) error
+	}); ok {
+		e.trunc = tr.Truncate
+	}
+	return e, nil
+}
+
+// Update changes the fields of record i to the values in rec and sets the
+// table's modification date to today.  Fields missing from rec, and fields
+// whose value is the same as the one read from the table, keep their stored
+// bytes; only the others are encoded afresh, through the table's charset and
+// field encryption.  The deleted flag is left alone.  Memo fields can't be
+// updated, though a Session can set them, and a value that doesn't fit its
+// field is an error, with nothing written.  Fields whose stored value can't
+// be decoded, such as "N/A" in a numeric field, can be updated whether or
+// not the table was opened WithLenient, which is how dirty values get fixed.
+// A name in rec that isn't one of the table's fields is an error, except for
+// the computed and provenance columns a Table adds to the records it reads,
+// which are passed over so that a record read can be written back.  Index
+// files attached to the table aren't updated, so an index on a changed field
+// goes stale.
+func (e *Editor) Update(i int, rec Record) error {
+	t := e.Table
+	var before Record
+	if t.journal != nil {
+		raw, err := t.readRaw(i)
+		if err != nil {
+			return err
+		}
+		before = t.decodeLeniently(i, raw)
+	}
+	buf, err := t.updated(i, rec)
+	if err != nil {
+		return err
+	}
+	if _, err = e.w.WriteAt(buf, t.recordOffset(i)); err != nil {
+		return err
+	}
+	t.count(MetricRecordsWritten, 1)
+	if err = e.touch(); err != nil || t.journal == nil {
+		return err
+	}
+	return t.record("update", i, before, t.decodeLeniently(i, buf))
+}
+
+// updated returns record i as Update writes it with the values in rec.
+func (t *Table) updated(i int, rec Record) ([]byte, error) {
+	if err := t.checkNames(rec); err != nil {
+		return nil, err
+	}
+	raw, err := t.readRaw(i)
+	if err != nil {
+		return nil, err
+	}
+	lenient := *t
+	lenient.lenient = true
+	lenient.logger = nil
+	broken := map[string]bool{}
+	lenient.warnFn = func(a Anomaly) {
+		broken[a.Field] = true
+	}
+	old, err := lenient.decodeRecord(i, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := append([]byte(nil), raw...)
+	pos := 1
+	for j, f := range t.fields {
+		name := t.FieldName(j)
+		v, ok := rec[name]
+		if ok && (broken[name] || !sameValue(v, old[name])) {
+			b, err := t.encodeValue(f, name, v)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s", name, err)
+			}
+			copy(buf[pos:], b)
+		}
+		pos += int(f.Len)
+	}
+	return buf, nil
+}
+
+// Append adds rec to the end of the table, as dBase's APPEND does, and
+// returns its record number.  Fields missing from rec are left blank, and
+// names and values are checked and encoded as by Update.  The end-of-file marker is written after
+// the new record, so a table with no records, with or without a marker,
+// grows like any other.  As with Pack, the Editor picks up the new length,
+// but other Tables opened on the same file before the Append are stale, and
+// so are the table's index files, which don't get the new record.
+func (e *Editor) Append(rec Record) (int, error) {
+	t := e.Table
+	buf, err := t.newRecord(rec)
+	if err != nil {
+		return -1, err
+	}
+	buf = append(buf, 0x1A)
+
+	i := t.nrec
+	if _, err := e.w.WriteAt(buf, t.recordOffset(i)); err != nil {
+		return -1, err
+	}
+	t.count(MetricRecordsWritten, 1)
+	if t.journal != nil {
+		if err := t.record("append", i, nil, t.decodeLeniently(i, buf[:t.datalen])); err != nil {
+			return -1, err
+		}
+	}
+	var count [4]byte
+	binary.LittleEndian.PutUint32(count[:], uint32(i+1))
+	if _, err := e.w.WriteAt(count[:], 4); err != nil {
+		return -1, err
+	}
+	if err := e.touch(); err != nil {
+		return -1, err
+	}
+	size := t.recordOffset(i+1) + 1
+	if t.size > size {
+		size = t.size
+	}
+	e.resize(i+1, size)
+	return i, nil
+}
+
+// resize replaces the Editor's Table with a copy that has nrec records in a
+// file of size bytes, as the Editor's writes have left it, without reading
+// the header, memo file and indexes again.  The Table it replaces doesn't
+// change, for the sake of those still reading it.
+func (e *Editor) resize(nrec int, size int64) {
+	t := *e.Table
+	t.nrec, t.size = nrec, size
+	now := time.Now()
+	t.year, t.month, t.day = now.Year(), int(now.Month()), now.Day()
+	e.Table = &t
+}
+
+// newRecord encodes rec as Append writes it.
+func (t *Table) newRecord(rec Record) ([]byte, error) {
+	if err := t.checkNames(rec); err != nil {
+		return nil, err
+	}
+	buf := bytes.Repeat([]byte{' '}, int(t.recordlen))
+	pos := 1
+	for j, f := range t.fields {
+		name := t.FieldName(j)
+		v, ok := rec[name]
+		if ok || !f.isMemo() {
+			b, err := t.encodeValue(f, name, v)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %s", name, err)
+			}
+			copy(buf[pos:], b)
+		}
+		pos += int(f.Len)
+	}
+	return buf, nil
+}
+
+// checkNames returns an error for a name in rec that is neither one of the
+// table's fields nor a column the table adds to the records it reads.
+func (t *Table) checkNames(rec Record) error {
+	for name := range rec {
+		known := t.fieldIndex(name) >= 0 || name == t.provenance
+		for _, c := range t.computed {
+			known = known || c.name == name
+		}
+		if !known {
+			return fmt.Errorf("table has no field %q", name)
+		}
+	}
+	return nil
+}
+
+// touch sets the table's modification date to today.
+func (e *Editor) touch() error {
+	now := time.Now()
+	_, err := e.w.WriteAt([]byte{byte(now.Year() - 1900), byte(now.Month()), byte(now.Day())}, 1)
+	return err
+}
+
+// MarkDeleted flags record i as deleted, as dBase's DELETE does.  The record
+// stays in the file, where Undelete can recover it, until the table is
+// packed.
+func (e *Editor) MarkDeleted(i int) error {
+	return e.setFlag(i, '*', "delete")
+}
+
+// Undelete clears the deleted flag of record i, as dBase's RECALL does.
+func (e *Editor) Undelete(i int) error {
+	return e.setFlag(i, ' ', "undelete")
+}
+
+func (e *Editor) setFlag(i int, flag byte, op string) error {
+	if i < 0 || i >= e.nrec {
+		return fmt.Errorf("record %d is out of range, table has %d records", i, e.nrec)
+	}
+	if _, err := e.w.WriteAt([]byte{flag}, e.recordOffset(i)); err != nil {
+		return err
+	}
+	if err := e.touch(); err != nil {
+		return err
+	}
+	if e.journal == nil {
+		return nil
+	}
+	raw, err := e.readRaw(i)
+	if err != nil {
+		return err
+	}
+	if flag == '*' {
+		return e.record(op, i, e.decodeLeniently(i, raw), nil)
+	}
+	return e.record(op, i, nil, e.decodeLeniently(i, raw))
+}
+
+// A PackOption configures Pack.
+type PackOption func(*packConfig)
+
+type packConfig struct {
+	repair *AnomalyReport
+}
+
+// RepairFlags makes Pack normalize deleted flags other than ' ' and '*',
+// which are otherwise carried over as they are.  Such records are kept as
+// live records, since dropping them would lose data, and each repair is
+// added to rep, logged and passed to the table's warning callback.
+func RepairFlags(rep *AnomalyReport) PackOption {
+	return func(c *packConfig) {
+		c.repair = rep
+	}
+}
+
+// packChunk is roughly how many bytes of records Pack holds in memory at
+// once.
+const packChunk = 1 << 20
+
+// Pack rewrites the table without its deleted records, as dBase's PACK
+// does, moving the remaining records up and updating the record count.
+// Records are copied a block at a time, so memory use doesn't grow with the
+// size of the table.
+// The file is truncated if it supports it, an *os.File for instance;
+// otherwise the old data is left behind the end-of-file marker.  The Editor
+// picks up the new length, but other Tables opened on the same file before
+// the Pack are stale.  Memo files are left as they are, and index files,
+// whose record numbers no longer match, have to be rebuilt by the program
+// that maintains them.
+func (e *Editor) Pack(opts ...PackOption) error {
+	var c packConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	t := e.Table
+	reclen := int(t.recordlen)
+	per := packChunk / reclen
+	if per == 0 {
+		per = 1
+	}
+	block := make([]byte, per*reclen)
+	nrec := 0
+	for start := 0; start < t.nrec; start += per {
+		n := t.nrec - start
+		if n > per {
+			n = per
+		}
+		buf := block[:n*reclen]
+		got, err := t.src.ReadAt(buf, t.recordOffset(start))
+		if got < len(buf) {
+			// The last record may be cut short of its padding.
+			if start+n < t.nrec || got < len(buf)-reclen+t.datalen {
+				i := start + got/reclen
+				if err == nil || err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return &RecordError{i, t.recordOffset(i), err}
+			}
+			for j := got; j < len(buf); j++ {
+				buf[j] = 0
+			}
+		}
+
+		// Records are moved up within the block, then written back in one
+		// go, never past the part of the file already read.
+		kept, moved := 0, false
+		for j := 0; j < n; j++ {
+			i, rec := start+j, buf[j*reclen:(j+1)*reclen]
+			if rec[0] == '*' {
+				if t.journal != nil {
+					if err := t.record("pack", i, t.decodeLeniently(i, rec[:t.datalen]), nil); err != nil {
+						return err
+					}
+				}
+				moved = true
+				continue
+			}
+			if rec[0] != ' ' && c.repair != nil {
+				c.repair.add(SeverityWarning, t.recordOffset(i), i, "", "replaced deleted flag %#x with ' ', now record %d", rec[0], nrec+kept)
+				a := c.repair.Anomalies[len(c.repair.Anomalies)-1]
+				t.log(levelWarn, "dbf: repaired deleted flag", "record", i, "flag", rec[0])
+				if t.warnFn != nil {
+					t.warnFn(a)
+				}
+				rec[0] = ' '
+				moved = true
+			}
+			copy(buf[kept*reclen:], rec)
+			kept++
+		}
+		if moved || nrec != start {
+			if _, err = e.w.WriteAt(buf[:kept*reclen], t.recordOffset(nrec)); err != nil {
+				return err
+			}
+		}
+		nrec += kept
+	}
+
+	end := t.recordOffset(nrec)
+	if _, err := e.w.WriteAt([]byte{0x1A}, end); err != nil {
+		return err
+	}
+	var count [4]byte
+	binary.LittleEndian.PutUint32(count[:], uint32(nrec))
+	if _, err := e.w.WriteAt(count[:], 4); err != nil {
+		return err
+	}
+	if err := e.touch(); err != nil {
+		return err
+	}
+	size := t.size
+	if e.trunc != nil && size > end+1 {
+		if err := e.trunc(end + 1); err != nil {
+			return err
+		}
+		size = end + 1
+	}
+	e.resize(nrec, size)
+	return nil
+}
+
+// encodeValue is the inverse of decoding field f: it renders v as the bytes
+// stored in the table.
+func (t *Table) encodeValue(f Field, name string, v interface{}) ([]byte, error) {
+	if f.isMemo() {
+		return nil, fmt.Errorf("memo fields can't be updated")
+	}
+	if codes, ok := t.codes[name]; ok {
+		v = codes.code(v)
+	}
+	if d, ok := v.(time.Time); ok {
+		if epoch, ok := t.epochs[name]; ok {
+			v = daysSince(epoch, d, f)
+		}
+	}
+	if s, ok := v.(string); ok && t.charset != nil && f.Type == 'C' {
+		b, err := t.charset.Encode(s)
+		if err != nil {
+			return nil, err
+		}
+		v = string(b)
+	}
+	b, err := encode(t.version, f, v)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := t.crypts[name]; ok {
+		return c.Encrypt(b)
+	}
+	return b, nil
+}
+
+// seekWriterAt adapts an io.WriteSeeker to io.WriterAt, sharing the lock of
+// the seekReaderAt that reads from it.
+type seekWriterAt struct {
+	s *seekReaderAt
+	w io.WriteSeeker
+}
+
+func (sw seekWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	sw.s.Lock()
+	defer sw.s.Unlock()
+	if _, err := sw.w.Seek(off, 0); err != nil {
+		return 0, err
+	}
+	return sw.w.Write(p)
+}
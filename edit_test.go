@@ -0,0 +1,249 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEditorUpdate(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}, " 007alpha", "*  2bravo")
+	f.Write(data)
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Update(0, Record{"ID": 7, "NAME": "zulu"}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Update(1, Record{"ID": 3}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Update(0, Record{"NAME": "too long"}); err == nil {
+		t.Fatalf("expected an error for a value that doesn't fit")
+	}
+	if err = e.Update(0, Record{"ID": 9, "COLOR": "red"}); err == nil {
+		t.Fatalf("expected an error for a field the table doesn't have")
+	}
+	if _, err = e.Append(Record{"COLOR": "red"}); err == nil {
+		t.Fatalf("expected an error appending a field the table doesn't have")
+	}
+
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	headerlen := len(data) - 2*9 - 1
+	if records := string(got[headerlen : len(got)-1]); records != " 007zulu *  3bravo" {
+		t.Fatalf("wrong records after update: %q", records)
+	}
+	now := time.Now()
+	if got[1] != byte(now.Year()-1900) || got[2] != byte(now.Month()) || got[3] != byte(now.Day()) {
+		t.Fatalf("modification date not updated: % x", got[1:4])
+	}
+	if !bytes.Equal(got[4:headerlen], data[4:headerlen]) {
+		t.Fatalf("header changed beyond the modification date")
+	}
+}
+
+func TestEditorUpdateUndecodable(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}, " N/Aalpha")
+	f.Write(data)
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Update(0, Record{"NAME": "bravo"}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Update(0, Record{"ID": 4}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec, err := e.Record(0); err != nil || rec["ID"] != int64(4) || rec["NAME"] != "bravo" {
+		t.Fatalf("wrong record after update: %v, %v", rec, err)
+	}
+}
+
+func TestEditorDeleteAndPack(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.Write(scanTable)
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.MarkDeleted(0); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Undelete(1); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = e.Record(0); err != ErrDeleted {
+		t.Fatalf("expected record 0 to be deleted, got %v", err)
+	}
+	if rec, err := e.Record(1); err != nil || rec["NAME"] != "bravo" {
+		t.Fatalf("expected record 1 to be undeleted, got %v, %v", rec, err)
+	}
+	if err = e.MarkDeleted(4); err == nil {
+		t.Fatalf("expected an error for a record out of range")
+	}
+
+	if err = e.Pack(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if e.Len() != 3 {
+		t.Fatalf("expected 3 records after packing, got %d", e.Len())
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	headerlen := len(scanTable) - 4*9 - 1
+	if records := string(got[headerlen:]); records != "   2bravo N/Acharl   4delta\x1A" {
+		t.Fatalf("wrong records after packing: %q", records)
+	}
+	if n, err := e.DeletedCount(); err != nil || n != 0 {
+		t.Fatalf("DeletedCount() returned %d, %v after packing", n, err)
+	}
+}
+
+func TestEditorPackRepair(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	data := buildTable([]Field{mustField("NAME", 'C', 5, 0)}, "*alpha", "\x00bravo", " charl")
+	f.Write(data)
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var rep AnomalyReport
+	if err = e.Pack(RepairFlags(&rep)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(rep.Anomalies) != 1 || rep.Anomalies[0].Record != 1 {
+		t.Fatalf("expected a repair of record 1, got %+v", rep.Anomalies)
+	}
+	for i, name := range []string{"bravo", "charl"} {
+		if rec, err := e.Record(i); err != nil || rec["NAME"] != name {
+			t.Fatalf("Record(%d) returned %v, %v after packing", i, rec, err)
+		}
+	}
+}
+
+func TestEditorPackBlocks(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	// Enough records to span several of Pack's blocks, every third deleted.
+	var records []string
+	for i := 0; i < 3*packChunk/250; i++ {
+		flag := " "
+		if i%3 == 0 {
+			flag = "*"
+		}
+		records = append(records, fmt.Sprintf("%s%5d%244s", flag, i, ""))
+	}
+	f.Write(buildTable([]Field{mustField("ID", 'N', 5, 0), mustField("PAD", 'C', 244, 0)}, records...))
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Pack(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want := len(records) * 2 / 3; e.Len() != want {
+		t.Fatalf("expected %d records after packing, got %d", want, e.Len())
+	}
+	for i := 0; i < e.Len(); i++ {
+		if rec, err := e.Record(i); err != nil || rec["ID"] != int64(i/2*3+i%2+1) {
+			t.Fatalf("Record(%d) returned %v, %v after packing", i, rec["ID"], err)
+		}
+	}
+}
+
+func TestEditorAppend(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	empty := buildTable(fields)
+	for _, data := range [][]byte{empty, empty[:len(empty)-1], buildTable(fields, "   1alpha")} {
+		f, err := ioutil.TempFile("", "dbf")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		f.Write(data)
+
+		e, err := NewEditor(f)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		n, before := e.Len(), e.Table
+		if i, err := e.Append(Record{"ID": 7, "NAME": "zulu"}); err != nil || i != n {
+			t.Fatalf("expected record %d, got %d, %v", n, i, err)
+		}
+		if i, err := e.Append(Record{"ID": 8}); err != nil || i != n+1 {
+			t.Fatalf("expected record %d, got %d, %v", n+1, i, err)
+		}
+		if _, err := e.Append(Record{"NAME": "too long"}); err == nil {
+			t.Fatalf("expected an error for a value that doesn't fit")
+		}
+		if e.Len() != n+2 {
+			t.Fatalf("expected %d records, got %d", n+2, e.Len())
+		}
+		if before.Len() != n {
+			t.Fatalf("the Table from before the appends has %d records, expected %d", before.Len(), n)
+		}
+		now := time.Now()
+		if y, m, d := e.ModDate(); y != now.Year() || m != int(now.Month()) || d != now.Day() {
+			t.Fatalf("modification date is %d-%d-%d after an append", y, m, d)
+		}
+
+		got, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		headerlen := len(empty) - 1
+		want := string(data[headerlen:headerlen+n*9]) + "   7zulu    8     \x1A"
+		if records := string(got[headerlen:]); records != want {
+			t.Fatalf("wrong records after append: %q, expected %q", records, want)
+		}
+		r, err := NewReaderFromBytes(got)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if rep, err := r.Validate(); err != nil || len(rep.Anomalies) > 0 {
+			t.Fatalf("appended table doesn't validate: %v, %v", rep, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// encodeField renders v as the f.Len bytes stored for field f: text is
+// left-aligned and numbers right-aligned, padded with spaces.  nil and zero
+// times are stored as blanks, except that a nil logical is stored as '?'.
+// Values that don't fit are an error, and so are values of the wrong type
+// for C, N, F, D and L fields, see checkType.
+func encodeField(f Field, v interface{}) ([]byte, error) {
+	if err := checkType(f, v); err != nil {
+		return nil, err
+	}
+	var s string
+	rightAlign := false
+	switch v := v.(type) {
+	case nil:
+		if f.Type == 'L' {
+			s = "?"
+		}
+	case FieldMarshaler:
+		b, err := v.MarshalDBF(f)
+		if err != nil {
+			return nil, err
+		}
+		s = string(b)
+	case time.Time:
+		if !v.IsZero() {
+			s = v.Format("20060102")
+		}
+	case bool:
+		s = "F"
+		if v {
+			s = "T"
+		}
+	case string:
+		s, rightAlign = v, f.Type == 'N' || f.Type == 'F'
+	case int:
+		s, rightAlign = strconv.Itoa(v), true
+	case int32:
+		s, rightAlign = strconv.FormatInt(int64(v), 10), true
+	case int64:
+		s, rightAlign = strconv.FormatInt(v, 10), true
+	case float64:
+		s, rightAlign = strconv.FormatFloat(v, 'f', int(f.DecimalPlaces), 64), true
+	case *big.Int:
+		s, rightAlign = v.String(), true
+	case Currency:
+		s, rightAlign = strconv.FormatFloat(v.Float64(), 'f', int(f.DecimalPlaces), 64), true
+	default:
+		return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+	}
+	if len(s) > int(f.Len) {
+		return nil, fmt.Errorf("value %q is too long for a %d byte field", s, f.Len)
+	}
+
+	pad := bytes.Repeat([]byte{' '}, int(f.Len)-len(s))
+	if rightAlign {
+		return append(pad, s...), nil
+	}
+	return append([]byte(s), pad...), nil
+}
+
+// checkType reports values that field f can't hold in a form it reads back:
+// character fields take strings, numeric fields numbers, finite floats and
+// strings holding a number, date fields times and strings in the form
+// 20060102, and logical fields bools and the strings dBase stores.  nil, a
+// FieldMarshaler and a blank string suit them all.  Other field types
+// aren't checked.
+// isPlainNumber reports whether s is a number as numeric fields store and
+// decode it: an optional sign, then digits with at most one decimal point,
+// and no exponent.
+func isPlainNumber(s string) bool {
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:]
+	}
+	digits, point := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] >= '0' && s[i] <= '9':
+			digits++
+		case s[i] == '.' && !point:
+			point = true
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
+
+func checkType(f Field, v interface{}) error {
+	if _, ok := v.(FieldMarshaler); ok || v == nil {
+		return nil
+	}
+	s, isString := v.(string)
+	if isString && strings.TrimSpace(s) == "" {
+		return nil
+	}
+	ok := true
+	switch f.Type {
+	case 'C':
+		ok = isString
+	case 'N', 'F':
+		switch v := v.(type) {
+		case int, int32, int64, *big.Int, Currency:
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("can't store %v in field type '%c'", v, f.Type)
+			}
+		case string:
+			if !isPlainNumber(strings.TrimSpace(v)) {
+				return fmt.Errorf("can't store %q in field type '%c': not a number", v, f.Type)
+			}
+		default:
+			ok = false
+		}
+	case 'D':
+		if isString {
+			if _, err := time.Parse("20060102", strings.TrimSpace(s)); err != nil {
+				return fmt.Errorf("can't store %q in field type 'D': not a date in the form 20060102", s)
+			}
+		} else {
+			_, ok = v.(time.Time)
+		}
+	case 'L':
+		if isString {
+			if len(s) != 1 || !strings.Contains("TtFfYyNn?", s) {
+				return fmt.Errorf("can't store %q in field type 'L'", s)
+			}
+		} else {
+			_, ok = v.(bool)
+		}
+	}
+	if !ok {
+		return fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+	}
+	return nil
+}
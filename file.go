@@ -0,0 +1,128 @@
+package dbf
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Open opens the table stored in the named file.  A memo file next to it,
+// with the same base name and a .dbt, .fpt or .smt extension, is attached as if by
+// WithMemo, and a structural index with a .cdx extension as if by
+// WithIndex, though one that can't be read is skipped with a warning in the
+// log.  The Table owns the file handles, so it must be closed when no longer
+// needed.
+func Open(name string, opts ...Option) (*Table, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	opts = append([]Option{WithSourceName(name)}, opts...)
+	closers := []io.Closer{f}
+	memo := openSidecar(name, ".dbt", ".DBT", ".fpt", ".FPT", ".smt", ".SMT")
+	if memo != nil {
+		opts = append([]Option{WithMemo(memo)}, opts...)
+		closers = append(closers, memo)
+	}
+	if cdx := openSidecar(name, ".cdx", ".CDX"); cdx != nil {
+		opts = append([]Option{func(t *Table) {
+			t.indexSrcs = append(t.indexSrcs, indexSource{cdx.Name(), cdx, true})
+		}}, opts...)
+		closers = append(closers, cdx)
+	}
+	t, err := OpenTable(f, fi.Size(), opts...)
+	if err != nil {
+		for _, c := range closers {
+			c.Close()
+		}
+		return nil, err
+	}
+	t.closers = append(t.closers, closers...)
+	return t, nil
+}
+
+// openSidecar looks for a file belonging to the named table, with the same
+// base name and one of the given extensions.  It returns nil if there is
+// none.
+func openSidecar(name string, exts ...string) *os.File {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	for _, ext := range exts {
+		if f, err := os.Open(base + ext); err == nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// Close releases every file the Table opened itself.  It is a no-op for
+// Tables built on a caller-supplied reader, which remains the caller's to
+// close.
+func (t *Table) Close() error {
+	var first error
+	for _, c := range t.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	t.closers = nil
+	return first
+}
+
+var _ io.Closer = (*Table)(nil)
+
+// Create creates the named file, truncating it if it already exists, and
+// writes the header of a new table to it, as NewWriter does.  The Writer
+// owns the file, and closes it on Close.
+//
+// With Atomic, the table is built in a temporary file in the same directory
+// and only renamed to name once Close has finished it, so that programs
+// polling the directory never see a half-written table.  If any write
+// failed, a value rejected with a *FieldError included, or anything fails
+// along the way, Close removes the temporary file and leaves name as it
+// was, and so does Abort.
+func Create(name string, fields []Field, opts ...WriterOption) (*Writer, error) {
+	c := newWriterConfig(opts)
+	var f *os.File
+	var err error
+	if c.atomic {
+		f, err = ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+		if err == nil {
+			err = f.Chmod(0644)
+		}
+	} else {
+		f, err = os.Create(name)
+	}
+	if err != nil {
+		if f != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+		return nil, err
+	}
+	wr, err := newWriter(f, fields, c)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	wr.file = f
+	if c.atomic {
+		wr.rename = name
+	}
+	return wr, nil
+}
+
+// Atomic makes Create build the table under a temporary name, and rename it
+// into place only once it is complete.
+func Atomic() WriterOption {
+	return func(c *writerConfig) {
+		c.atomic = true
+	}
+}
@@ -0,0 +1,94 @@
+package dbf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(testData)
+	f.Close()
+
+	tbl, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec, err := tbl.Record(0); err != nil || rec["Name"] != "Abbotsbury" {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+	if err = tbl.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = tbl.Record(0); err == nil {
+		t.Fatalf("expected an error reading from a closed Table")
+	}
+
+	if _, err = Open(f.Name() + ".missing"); err == nil {
+		t.Fatalf("expected an error opening a missing file")
+	}
+}
+
+func TestCreateAtomic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "OUT.DBF")
+
+	w, err := Create(name, []Field{mustField("ID", 'N', 3, 0)}, Atomic())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"ID": 1}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("table visible before Close: %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	tbl, err := Open(name)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer tbl.Close()
+	if rec, err := tbl.Record(0); err != nil || rec["ID"] != int64(1) {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected only the table in %s, found %d files", dir, len(entries))
+	}
+
+	for _, abandon := range []func(w *Writer) error{
+		func(w *Writer) error { return w.Abort() },
+		func(w *Writer) error {
+			w.Write(Record{"ID": 1000}) // doesn't fit
+			return w.Close()
+		},
+	} {
+		w, err := Create(filepath.Join(dir, "ABANDONED.DBF"), []Field{mustField("ID", 'N', 3, 0)}, Atomic())
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if err = w.Write(Record{"ID": 2}); err != nil {
+			t.Fatalf("%s", err)
+		}
+		abandon(w)
+		if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+			t.Fatalf("abandoned table left %d files in %s", len(entries)-1, dir)
+		}
+	}
+
+	if _, err = NewWriter(&writeSeeker{}, []Field{mustField("ID", 'N', 3, 0)}, Atomic()); err == nil {
+		t.Fatalf("expected an error for Atomic without Create")
+	}
+}
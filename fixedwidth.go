@@ -0,0 +1,142 @@
+package dbf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// FixedWidthOptions control WriteFixedWidth.  The zero value writes just the
+// records, one per line.
+type FixedWidthOptions struct {
+	Header      string                   // line written before everything else
+	ColumnNames bool                     // write a line of field names, padded to their widths
+	Footer      func(records int) string // line written last, given the number of records
+	LineEnding  string                   // defaults to "\n"
+	SortBy      []string                 // key fields to order records by, as in ScanSorted
+	Widths      map[string]int           // output widths of fields written as read, see WriteFixedWidth
+}
+
+// WriteFixedWidth exports the records of the table that aren't deleted as a
+// flat file whose columns are exactly as wide as the table's fields, the
+// layout many mainframe-era systems expect.  Fields are written as stored,
+// decrypted but otherwise byte for byte, so numbers keep their alignment,
+// character fields their code page, memo fields their block numbers, and
+// fields read WithCodes or WithDayNumbers their stored codes and numbers.
+// A field given a width in opts.Widths is instead written as read, formatted
+// as by ToCSV and padded to that width, which suits memo text and decoded
+// values.  So are fields the table redacts, which mustn't leak their stored
+// bytes, and those it has formatters for, see WithFormatter; text is padded
+// like numbers in numeric fields and like text otherwise, and text too long
+// for its column is an error.
+func (t *Table) WriteFixedWidth(w io.Writer, opts FixedWidthOptions) error {
+	eol := opts.LineEnding
+	if eol == "" {
+		eol = "\n"
+	}
+
+	bw := bufio.NewWriter(w)
+	if opts.Header != "" {
+		bw.WriteString(opts.Header + eol)
+	}
+	if opts.ColumnNames {
+		t.writeColumnNames(bw, opts)
+		bw.WriteString(eol)
+	}
+	n, err := t.writeFixedWidthRecords(bw, opts, nil)
+	if err != nil {
+		return err
+	}
+	if opts.Footer != nil {
+		bw.WriteString(opts.Footer(n) + eol)
+	}
+	return bw.Flush()
+}
+
+// fixedWidth returns the output width of field i.
+func (t *Table) fixedWidth(opts FixedWidthOptions, i int) int {
+	if n, ok := opts.Widths[t.FieldName(i)]; ok {
+		return n
+	}
+	return int(t.fields[i].Len)
+}
+
+// writeColumnNames writes the names of the selected fields, each padded or
+// cut to its output width.
+func (t *Table) writeColumnNames(bw *bufio.Writer, opts FixedWidthOptions) {
+	for i := range t.fields {
+		if !t.isSelected(i) {
+			continue
+		}
+		name := t.FieldName(i)
+		if len(name) > t.fixedWidth(opts, i) {
+			name = name[:t.fixedWidth(opts, i)]
+		}
+		fmt.Fprintf(bw, "%-*s", t.fixedWidth(opts, i), name)
+	}
+}
+
+// writeFixedWidthRecords writes the lines of WriteFixedWidth for the
+// records, each starting with prefix, and returns how many it wrote.
+func (t *Table) writeFixedWidthRecords(bw *bufio.Writer, opts FixedWidthOptions, prefix []byte) (int, error) {
+	eol := opts.LineEnding
+	if eol == "" {
+		eol = "\n"
+	}
+	redacted := map[string]bool{}
+	for _, r := range t.redactions {
+		redacted[r.name] = true
+	}
+	scan := t.Scan
+	if len(opts.SortBy) > 0 {
+		scan = func(fn func(int, Record) error) error {
+			return t.ScanSorted(opts.SortBy, fn)
+		}
+	}
+	n := 0
+	err := scan(func(i int, rec Record) error {
+		raw, err := t.readRaw(i)
+		if err != nil {
+			return &RecordError{i, t.recordOffset(i), err}
+		}
+		bw.Write(prefix)
+		pos := 1
+		for j, f := range t.fields {
+			stored := raw[pos : pos+int(f.Len)]
+			pos += int(f.Len)
+			if !t.isSelected(j) {
+				continue
+			}
+			name := t.FieldName(j)
+			_, declared := opts.Widths[name]
+			s, formatted := t.format(name, rec[name])
+			if !formatted && (declared || redacted[name]) {
+				s, formatted = normalize(rec[name]), true
+			}
+			if formatted {
+				stored, err = padColumn(f, s, t.fixedWidth(opts, j))
+			} else if c, ok := t.crypts[name]; ok {
+				stored, err = c.Decrypt(stored)
+			}
+			if err != nil {
+				return &RecordError{i, t.recordOffset(i), fmt.Errorf("field %s: %s", name, err)}
+			}
+			bw.Write(stored)
+		}
+		n++
+		_, err = bw.WriteString(eol)
+		return err
+	})
+	return n, err
+}
+
+// padColumn pads s to width bytes, on the left in numeric fields.
+func padColumn(f Field, s string, width int) ([]byte, error) {
+	if len(s) > width {
+		return nil, fmt.Errorf("value %q is longer than the %d bytes of its column", s, width)
+	}
+	if f.Type == 'N' || f.Type == 'F' {
+		return []byte(fmt.Sprintf("%*s", width, s)), nil
+	}
+	return []byte(fmt.Sprintf("%-*s", width, s)), nil
+}
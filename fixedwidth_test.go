@@ -0,0 +1,78 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestWriteFixedWidth(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0), mustField("AMOUNT", 'N', 6, 2)},
+		"   1alpha  1.50", "*  2bravo  2.00", "  33del   -0.5 ")
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var buf bytes.Buffer
+	err = tbl.WriteFixedWidth(&buf, FixedWidthOptions{
+		Header:      "HDR",
+		ColumnNames: true,
+		Footer:      func(n int) string { return fmt.Sprintf("TRL%05d", n) },
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "HDR\n" +
+		"ID NAME AMOUNT\n" +
+		"  1alpha  1.50\n" +
+		" 33del   -0.5 \n" +
+		"TRL00002\n"
+	if buf.String() != expected {
+		t.Fatalf("wrong output:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteFixedWidthStored(t *testing.T) {
+	dbt := make([]byte, 2*512)
+	dbt[16] = 0x03
+	copy(dbt[512:], "a memo far longer than ten bytes\x1A")
+	data := buildTable([]Field{mustField("ST", 'N', 1, 0), mustField("DAY", 'N', 5, 0), mustField("NAME", 'C', 5, 0), mustField("NOTES", 'M', 10, 0)},
+		" 112345alpha         1")
+	data[0] = 0x83
+	opts := []Option{
+		WithMemoBytes(dbt),
+		WithCodes("ST", Codes{"1": "active"}),
+		WithDayNumbers("DAY", JulianEpoch),
+		WithRedaction("NAME", Hash("salt", 0)),
+	}
+	r, err := NewReaderFromBytes(data, opts...)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// Codes, day numbers and memo pointers are written as stored, but a
+	// redacted value has to be given room.
+	var buf bytes.Buffer
+	if err = r.WriteFixedWidth(&buf, FixedWidthOptions{}); err == nil {
+		t.Fatalf("expected an error for a redacted value too long for its column")
+	}
+	buf.Reset()
+	if err = r.WriteFixedWidth(&buf, FixedWidthOptions{Widths: map[string]int{"NAME": 64}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := buf.String(); len(got) != 1+5+64+10+1 || got[:6] != "112345" || got[70:] != "         1\n" {
+		t.Fatalf("wrong output %q", got)
+	}
+
+	// Declared widths write values as read.
+	r, _ = NewReaderFromBytes(data, opts[:3]...)
+	buf.Reset()
+	if err = r.WriteFixedWidth(&buf, FixedWidthOptions{ColumnNames: true, Widths: map[string]int{"ST": 6, "NOTES": 40}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := "ST    DAY  NAME NOTES                                   \n" +
+		"active12345alphaa memo far longer than ten bytes        \n"
+	if buf.String() != want {
+		t.Fatalf("wrong output:\n%q\nexpected:\n%q", buf.String(), want)
+	}
+}
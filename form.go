@@ -0,0 +1,15 @@
+package dbf
+
+import "net/url"
+
+// URLValues converts the record to form values, for forwarding rows to
+// form-based HTTP APIs.  Values are formatted as by Record.Hash: dates as
+// YYYY-MM-DD, numbers in their shortest form, logicals as true or false,
+// and blank dates and unknown values as empty strings.
+func (rec Record) URLValues() url.Values {
+	v := make(url.Values, len(rec))
+	for name, val := range rec {
+		v.Set(name, normalize(val))
+	}
+	return v
+}
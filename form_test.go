@@ -0,0 +1,21 @@
+package dbf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestURLValues(t *testing.T) {
+	rec := Record{
+		"NAME":   "Abbotsbury",
+		"ID":     42,
+		"AMOUNT": 1.5,
+		"SINCE":  time.Date(2011, 7, 26, 0, 0, 0, 0, time.UTC),
+		"ACTIVE": true,
+		"CLOSED": nil,
+	}
+	expected := "ACTIVE=true&AMOUNT=1.5&CLOSED=&ID=42&NAME=Abbotsbury&SINCE=2011-07-26"
+	if got := rec.URLValues().Encode(); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+}
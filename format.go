@@ -0,0 +1,136 @@
+package dbf
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Formatter renders a value as the text an export writes for it.
+type Formatter func(v interface{}) string
+
+// WithFormatter makes the exports of the table, ToCSV and WriteFixedWidth,
+// write the values of field name as fn renders them, nil values included.
+// It takes precedence over WithTypeFormatter.
+func WithFormatter(name string, fn Formatter) Option {
+	return func(t *Table) {
+		if t.formatters == nil {
+			t.formatters = make(map[string]Formatter)
+		}
+		t.formatters[name] = fn
+	}
+}
+
+// WithTypeFormatter makes the exports of the table write every value of the
+// same Go type as example, such as time.Time{} or float64(0), as fn renders
+// it, unless its field has a formatter of its own.  nil values aren't
+// passed to type formatters.
+func WithTypeFormatter(example interface{}, fn Formatter) Option {
+	return func(t *Table) {
+		if t.typeFormatters == nil {
+			t.typeFormatters = make(map[reflect.Type]Formatter)
+		}
+		t.typeFormatters[reflect.TypeOf(example)] = fn
+	}
+}
+
+// format renders value v of field name through the table's formatters.  ok
+// is false if none applies.
+func (t *Table) format(name string, v interface{}) (s string, ok bool) {
+	if fn, ok := t.formatters[name]; ok {
+		return fn(v), true
+	}
+	if fn, ok := t.typeFormatters[reflect.TypeOf(v)]; ok && v != nil {
+		return fn(v), true
+	}
+	return "", false
+}
+
+// FormatDate returns a Formatter that writes times in the given layout, as
+// by time.Time.Format, and zero times and other values as blanks.
+func FormatDate(layout string) Formatter {
+	return func(v interface{}) string {
+		if d, ok := v.(time.Time); ok && !d.IsZero() {
+			return d.Format(layout)
+		}
+		return ""
+	}
+}
+
+// FormatFixed returns a Formatter that writes numbers with the given number
+// of decimal places, after symbol, as in FormatFixed(2, "$") for "$-12.50".
+// Values that aren't numbers are written as by Record.URLValues.
+func FormatFixed(decimals int, symbol string) Formatter {
+	return func(v interface{}) string {
+		f, ok := toFloat(v)
+		if !ok {
+			return normalize(v)
+		}
+		return symbol + strconv.FormatFloat(f, 'f', decimals, 64)
+	}
+}
+
+// A Locale holds the conventions for writing numbers and dates that the
+// recipients of an export expect, such as Locale{",", ".", "02.01.2006"}
+// for German spreadsheets.
+type Locale struct {
+	Decimal   string // decimal separator, "." if empty
+	Thousands string // separator between groups of three digits, none if empty
+	Date      string // layout of dates, as by time.Time.Format, "2006-01-02" if empty
+}
+
+// Format renders numbers and dates by the conventions of l, and other
+// values as Record.URLValues does.  Floats are written with as many decimal
+// places as it takes to read them back exactly.  It can be passed to
+// WithTypeFormatter for exports that don't take a Locale themselves.
+func (l Locale) Format(v interface{}) string {
+	var s string
+	switch n := widen(v).(type) {
+	case int64:
+		s = strconv.FormatInt(n, 10)
+	case float64:
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	case Currency:
+		s = n.String()
+	case *big.Int:
+		s = n.String()
+	case time.Time:
+		if n.IsZero() {
+			return ""
+		} else if l.Date != "" {
+			return n.Format(l.Date)
+		}
+		return n.Format("2006-01-02")
+	default:
+		return normalize(v)
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if l.Thousands != "" {
+		var b strings.Builder
+		for i, c := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(l.Thousands)
+			}
+			b.WriteRune(c)
+		}
+		whole = b.String()
+	}
+	if frac == "" {
+		return sign + whole
+	}
+	dec := l.Decimal
+	if dec == "" {
+		dec = "."
+	}
+	return sign + whole + dec + frac
+}
@@ -0,0 +1,51 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormatters(t *testing.T) {
+	fields := []Field{mustField("NAME", 'C', 5, 0), mustField("PRICE", 'N', 8, 2), mustField("SOLD", 'D', 10, 0)}
+	data := buildTable(fields, " alpha   12.5020110726  ", " bravo   -3.00          ")
+	r, err := NewReaderFromBytes(data,
+		WithTypeFormatter(time.Time{}, FormatDate("02/01/2006")),
+		WithFormatter("PRICE", FormatFixed(2, "$")))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	var buf bytes.Buffer
+	if err = r.ToCSV(&buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want := "NAME,PRICE,SOLD\nalpha,$12.50,26/07/2011\nbravo,$-3.00,\n"; buf.String() != want {
+		t.Errorf("ToCSV wrote %q, expected %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err = r.WriteFixedWidth(&buf, FixedWidthOptions{}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want := "alpha  $12.5026/07/2011\nbravo  $-3.00          \n"; buf.String() != want {
+		t.Errorf("WriteFixedWidth wrote %q, expected %q", buf.String(), want)
+	}
+
+	// A field formatter takes precedence over a type formatter, and sees
+	// nil values too.
+	r, _ = NewReaderFromBytes(data,
+		WithTypeFormatter("", func(v interface{}) string { return "?" }),
+		WithFormatter("NAME", func(v interface{}) string { return v.(string) + "!" }),
+		WithFormatter("SOLD", func(v interface{}) string { return "way too long" }))
+	buf.Reset()
+	if err = r.ToCSV(&buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if want := "NAME,PRICE,SOLD\nalpha!,12.5,way too long\nbravo!,-3,way too long\n"; buf.String() != want {
+		t.Errorf("ToCSV wrote %q, expected %q", buf.String(), want)
+	}
+	if err = r.WriteFixedWidth(&buf, FixedWidthOptions{}); err == nil {
+		t.Errorf("expected an error for a formatted value too long for its field")
+	}
+}
@@ -0,0 +1,176 @@
+package dbf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Versions Visual FoxPro opens.
+var foxProVersions = map[byte]bool{0x03: true, 0x83: true, 0xF5: true, 0x30: true, 0x31: true, 0x32: true}
+
+// CheckFoxPro checks the table against the constraints Visual FoxPro
+// enforces when it opens a table, beyond what Validate checks: a version it
+// reads, a header whose length matches the field descriptors, field
+// displacements that match the record layout, a _NullFlags field with a bit
+// for every nullable field, and memo pointers that stay within the memo
+// file's allocated blocks without overlapping.
+func (t *Table) CheckFoxPro() (*AnomalyReport, error) {
+	rep := &AnomalyReport{Anomalies: []Anomaly{}}
+	if !foxProVersions[t.version] {
+		rep.add(SeverityError, 0, -1, "", "Visual FoxPro doesn't open file version %#02x", t.version)
+	}
+	vfp := t.version >= 0x30 && t.version <= 0x32
+	headerlen := 32 + 32*len(t.fields) + 1
+	if vfp {
+		headerlen += 263
+	}
+	if int(t.headerlen) != headerlen {
+		rep.add(SeverityError, 8, -1, "", "header is %d bytes long, but %d fields take up %d", t.headerlen, len(t.fields), headerlen)
+	}
+	if int(t.headerRecordlen) != t.datalen {
+		rep.add(SeverityError, 10, -1, "", "header gives a record length of %d bytes, but the fields take up %d", t.headerRecordlen, t.datalen)
+	}
+
+	desc := make([]byte, 32*len(t.fields))
+	if err := readFullAt(t.src, desc, 32); err != nil {
+		return nil, err
+	}
+	pos, nullable, nullFlags := 1, 0, -1
+	for j, f := range t.fields {
+		offset := int64(32 + 32*j)
+		if vfp && int(f.Offset) != pos {
+			rep.add(SeverityError, offset+12, -1, t.FieldName(j), "displacement is %d, expected %d", f.Offset, pos)
+		}
+		pos += int(f.Len)
+		if f.Type == '0' {
+			nullFlags = j
+		} else if desc[32*j+18]&0x02 != 0 {
+			nullable++
+		}
+	}
+	if nullable > 0 && nullFlags < 0 {
+		rep.add(SeverityError, 32, -1, "", "%d fields are nullable, but there's no _NullFlags field", nullable)
+	} else if nullFlags >= 0 {
+		if name := t.FieldName(nullFlags); name != "_NullFlags" {
+			rep.add(SeverityError, int64(32+32*nullFlags), -1, name, "null flags field is named %q instead of _NullFlags", name)
+		}
+		if bits := 8 * int(t.fields[nullFlags].Len); bits < nullable {
+			rep.add(SeverityError, int64(32+32*nullFlags), -1, "_NullFlags", "has %d bits for %d nullable fields", bits, nullable)
+		}
+	}
+
+	if t.memo != nil && t.hasMemo() && !t.memo.smt {
+		if err := t.checkMemoChain(rep); err != nil {
+			return nil, err
+		}
+	}
+	return rep, nil
+}
+
+// memoExtent is the span of memo blocks a record's memo occupies.
+type memoExtent struct {
+	start, end int64 // in blocks, end exclusive
+	record     int
+	field      string
+}
+
+// checkMemoChain checks that every memo pointer in the table lies between
+// the memo file's header and its next free block, and that no two memos
+// share a block.
+func (t *Table) checkMemoChain(rep *AnomalyReport) error {
+	m := t.memo
+	var h [4]byte
+	if err := readFullAt(m.r, h[:], 0); err != nil {
+		return err
+	}
+	next := int64(binary.LittleEndian.Uint32(h[:]))
+	first := int64(1)
+	if m.fpt {
+		next = int64(binary.BigEndian.Uint32(h[:]))
+		first = (512 + m.blockSize - 1) / m.blockSize
+	}
+
+	var extents []memoExtent
+	for i := 0; i < t.nrec; i++ {
+		raw, err := t.readRaw(i)
+		if err == io.ErrUnexpectedEOF {
+			break // reported by Validate
+		} else if err != nil {
+			return err
+		}
+		pos := 1
+		for j, f := range t.fields {
+			field := raw[pos : pos+int(f.Len)]
+			pos += int(f.Len)
+			if !f.isMemo() {
+				continue
+			}
+			block, err := memoBlock(field)
+			if err != nil {
+				rep.add(SeverityError, t.recordOffset(i), i, t.FieldName(j), "%s", err)
+				continue
+			} else if block == 0 {
+				continue
+			}
+			if block < first || block >= next {
+				rep.add(SeverityError, t.recordOffset(i), i, t.FieldName(j), "memo block %d is outside the allocated blocks %d to %d", block, first, next-1)
+				continue
+			}
+			n, err := m.length(block)
+			if err != nil {
+				rep.add(SeverityError, t.recordOffset(i), i, t.FieldName(j), "%s", err)
+				continue
+			}
+			end := block + (n+m.blockSize-1)/m.blockSize
+			if end > next {
+				rep.add(SeverityError, t.recordOffset(i), i, t.FieldName(j), "memo at block %d runs past the next free block %d", block, next)
+			}
+			extents = append(extents, memoExtent{block, end, i, t.FieldName(j)})
+		}
+	}
+
+	sort.Slice(extents, func(a, b int) bool { return extents[a].start < extents[b].start })
+	for k := 1; k < len(extents); k++ {
+		if prev, e := extents[k-1], extents[k]; e.start < prev.end {
+			rep.add(SeverityError, t.recordOffset(e.record), e.record, e.field, "memo at block %d overlaps the one of record %d, field %s", e.start, prev.record, prev.field)
+		}
+	}
+	return nil
+}
+
+// StrictFoxPro makes Close read back the table it wrote and check it with
+// Validate and Table.CheckFoxPro, so that a table that wouldn't open in
+// Visual FoxPro is reported as an error rather than discovered later.  The
+// writer passed to NewWriter must also implement io.ReaderAt, as an
+// *os.File does.
+func StrictFoxPro() WriterOption {
+	return func(c *writerConfig) {
+		c.verify = true
+	}
+}
+
+// verifyFoxPro reads back the table the Writer has just finished.
+func (wr *Writer) verifyFoxPro() error {
+	size, err := wr.w.Seek(0, 2)
+	if err != nil {
+		return err
+	}
+	t, err := OpenTable(wr.w.(io.ReaderAt), size)
+	if err != nil {
+		return fmt.Errorf("written table doesn't verify: %s", err)
+	}
+	for _, check := range []func() (*AnomalyReport, error){t.Validate, t.CheckFoxPro} {
+		rep, err := check()
+		if err != nil {
+			return err
+		}
+		for _, a := range rep.Anomalies {
+			if a.Severity != SeverityInfo {
+				return fmt.Errorf("written table doesn't verify: %s", a.Message)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckFoxPro(t *testing.T) {
+	check := func(data, memo []byte) []string {
+		var opts []Option
+		if memo != nil {
+			opts = append(opts, WithMemo(bytes.NewReader(memo)))
+		}
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), opts...)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		rep, err := tbl.CheckFoxPro()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		var messages []string
+		for _, a := range rep.Anomalies {
+			messages = append(messages, a.Message)
+		}
+		return messages
+	}
+
+	if messages := check(scanTable, nil); len(messages) != 0 {
+		t.Errorf("expected no anomalies, got %q", messages)
+	}
+	dbase4 := append([]byte(nil), scanTable...)
+	dbase4[0] = 0x04
+	if messages := check(dbase4, nil); len(messages) != 1 || !strings.Contains(messages[0], "version 0x04") {
+		t.Errorf("expected an anomaly about the version, got %q", messages)
+	}
+
+	// The first memo runs into the second's block, and the third lies
+	// beyond the next free block.
+	dbt := make([]byte, 4*512)
+	binary.LittleEndian.PutUint32(dbt, 3)
+	dbt[16] = 0x03
+	copy(dbt[512:], strings.Repeat("x", 600)+"\x1A")
+	copy(dbt[1024+100:], "short\x1A")
+	data := memoTable(0x83, "         1", "         2", "         3")
+	messages := check(data, dbt)
+	expected := []string{
+		"memo block 3 is outside the allocated blocks 1 to 2",
+		"memo at block 2 overlaps the one of record 0, field NOTES",
+	}
+	if strings.Join(messages, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("wrong anomalies:\n got %q\nwant %q", messages, expected)
+	}
+}
+
+func TestStrictFoxPro(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	var ws writeSeeker
+	if _, err := NewWriter(&ws, fields, StrictFoxPro()); err == nil {
+		t.Fatalf("expected an error for a writer that can't be read back")
+	}
+
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	w, err := NewWriter(f, fields, StrictFoxPro())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"ID": 1, "NAME": "alpha"}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+}
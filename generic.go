@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package dbf
+
+import "fmt"
+
+// Get returns field name of rec as a T, which must be the exact type the
+// field decodes to:
+//
+//	area, err := dbf.Get[float64](rec, "AREA")
+func Get[T any](rec Record, name string) (T, error) {
+	var zero T
+	v, ok := rec[name]
+	if !ok {
+		return zero, fmt.Errorf("record has no field %q", name)
+	}
+	tv, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("field %q holds a %T, not a %T", name, v, zero)
+	}
+	return tv, nil
+}
+
+// Column returns field name of every record in t that isn't deleted, in
+// order, as a []T.
+func Column[T any](t *Table, name string) ([]T, error) {
+	values := make([]T, 0, t.Len())
+	err := t.Scan(func(i int, rec Record) error {
+		v, err := Get[T](rec, name)
+		if err != nil {
+			return &RecordError{i, t.recordOffset(i), err}
+		}
+		values = append(values, v)
+		return nil
+	})
+	return values, err
+}
@@ -0,0 +1,42 @@
+//go:build go1.18
+// +build go1.18
+
+package dbf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	rec, err := reader.Read(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v, err := Get[float64](rec, "Shape_Leng"); err != nil || v != 0.052467 {
+		t.Errorf("Get[float64] returned %v, %v", v, err)
+	}
+	if _, err := Get[string](rec, "OBJECTID"); err == nil {
+		t.Errorf("expected an error for a mismatched type")
+	}
+	if _, err := Get[string](rec, "MISSING"); err == nil {
+		t.Errorf("expected an error for a missing field")
+	}
+}
+
+func TestColumn(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)},
+		"   1alpha", "*  2bravo", "   4delta")
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	names, err := Column[string](tbl, "NAME")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(names, []string{"alpha", "delta"}) {
+		t.Fatalf("wrong column: %v", names)
+	}
+}
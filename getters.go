@@ -0,0 +1,77 @@
+package dbf
+
+import (
+	"fmt"
+	"time"
+)
+
+// get returns field name of rec converted to typ, as by a Mapping.
+func (rec Record) get(name string, typ ColumnType) (interface{}, error) {
+	v, ok := rec[name]
+	if !ok {
+		return nil, fmt.Errorf("record has no field %q", name)
+	} else if v == nil {
+		return nil, fmt.Errorf("field %q has no value", name)
+	}
+	v, err := convertColumn(v, typ)
+	if err != nil {
+		return nil, fmt.Errorf("field %q: %s", name, err)
+	}
+	return v, nil
+}
+
+// GetString returns field name of rec as a string.  Values of other types
+// are formatted as by Record.URLValues.  Unlike Get, the typed getters
+// convert between types where that can be done without loss, and report
+// missing fields and unknown values as errors.
+func (rec Record) GetString(name string) (string, error) {
+	v, err := rec.get(name, ColumnString)
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// GetInt returns field name of rec as an int.  Whole floating-point numbers
+// and numeric strings are converted.
+func (rec Record) GetInt(name string) (int, error) {
+	v, err := rec.get(name, ColumnInt)
+	if err != nil {
+		return 0, err
+	}
+	n := v.(int64)
+	if int64(int(n)) != n {
+		return 0, fmt.Errorf("field %q: %d overflows int", name, n)
+	}
+	return int(n), nil
+}
+
+// GetFloat returns field name of rec as a float64.  Integers and numeric
+// strings are converted.
+func (rec Record) GetFloat(name string) (float64, error) {
+	v, err := rec.get(name, ColumnFloat)
+	if err != nil {
+		return 0, err
+	}
+	return v.(float64), nil
+}
+
+// GetDate returns field name of rec as a time.Time.  Strings in YYYYMMDD or
+// YYYY-MM-DD form are parsed.
+func (rec Record) GetDate(name string) (time.Time, error) {
+	v, err := rec.get(name, ColumnDate)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return v.(time.Time), nil
+}
+
+// GetBool returns field name of rec as a bool.  Strings are parsed as by
+// strconv.ParseBool.
+func (rec Record) GetBool(name string) (bool, error) {
+	v, err := rec.get(name, ColumnBool)
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
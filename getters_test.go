@@ -0,0 +1,39 @@
+package dbf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTypedGetters(t *testing.T) {
+	day := time.Date(2011, 7, 26, 0, 0, 0, 0, time.UTC)
+	rec := Record{"ID": 42, "AMOUNT": 1.5, "WHOLE": 3.0, "CODE": " 17", "DAY": day, "OK": true, "UNSET": nil}
+
+	if s, err := rec.GetString("DAY"); err != nil || s != "2011-07-26" {
+		t.Errorf("GetString(DAY) returned %q, %v", s, err)
+	}
+	if n, err := rec.GetInt("WHOLE"); err != nil || n != 3 {
+		t.Errorf("GetInt(WHOLE) returned %d, %v", n, err)
+	}
+	if n, err := rec.GetInt("CODE"); err != nil || n != 17 {
+		t.Errorf("GetInt(CODE) returned %d, %v", n, err)
+	}
+	if _, err := rec.GetInt("AMOUNT"); err == nil {
+		t.Errorf("expected an error converting 1.5 to an int")
+	}
+	if f, err := rec.GetFloat("ID"); err != nil || f != 42 {
+		t.Errorf("GetFloat(ID) returned %v, %v", f, err)
+	}
+	if d, err := rec.GetDate("DAY"); err != nil || !d.Equal(day) {
+		t.Errorf("GetDate(DAY) returned %v, %v", d, err)
+	}
+	if b, err := rec.GetBool("OK"); err != nil || !b {
+		t.Errorf("GetBool(OK) returned %v, %v", b, err)
+	}
+	if _, err := rec.GetBool("UNSET"); err == nil {
+		t.Errorf("expected an error for an unknown value")
+	}
+	if _, err := rec.GetString("MISSING"); err == nil {
+		t.Errorf("expected an error for a missing field")
+	}
+}
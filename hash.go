@@ -0,0 +1,118 @@
+package dbf
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Hash returns a SHA-256 digest of the named fields of rec, or of all its
+// fields if none are named, so that incremental pipelines can tell whether a
+// row changed without comparing it field by field.  Values are normalized
+// before hashing: the digest depends on what a record holds, not on how wide
+// its columns are or how the numbers were padded.
+func (rec Record) Hash(fields ...string) [sha256.Size]byte {
+	if len(fields) == 0 {
+		for name := range rec {
+			fields = append(fields, name)
+		}
+		sort.Strings(fields)
+	}
+	h := sha256.New()
+	writeNormalized(h, rec, fields)
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Fingerprint streams every record of the table that isn't deleted through
+// SHA-256, normalized as for Record.Hash, so an unchanged table can be
+// recognized without keeping a copy of it.  Only field names and values
+// count: a table rewritten with wider columns keeps its fingerprint.
+func (t *Table) Fingerprint() ([sha256.Size]byte, error) {
+	h := sha256.New()
+	for _, name := range t.hashedNames() {
+		fmt.Fprintf(h, "%s\x00", name)
+	}
+	return t.hashRecords(h)
+}
+
+// ContentHash is like Fingerprint, but also covers the schema: each field's
+// type, width and decimal places.  Header bytes that change on every export,
+// such as the modification date, are left out, so re-deliveries of the same
+// table can be recognized as duplicates.  The hash of a Select view covers
+// just the selected fields, and matches that of a table holding only them.
+func (t *Table) ContentHash() ([sha256.Size]byte, error) {
+	h := sha256.New()
+	for i, f := range t.fields {
+		if !t.isSelected(i) {
+			continue
+		}
+		fmt.Fprintf(h, "%s %c %d %d\x00", t.FieldName(i), f.Type, f.Len, f.DecimalPlaces)
+	}
+	for _, c := range t.computed {
+		fmt.Fprintf(h, "%s\x00", c.name)
+	}
+	return t.hashRecords(h)
+}
+
+func (t *Table) hashRecords(h hash.Hash) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	names := t.hashedNames()
+	h.Write([]byte{'\n'})
+	err := t.Scan(func(i int, rec Record) error {
+		writeNormalized(h, rec, names)
+		return nil
+	})
+	if err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// hashedNames returns the columns Fingerprint and ContentHash cover: all
+// but the provenance column, which differs between copies of a table.
+func (t *Table) hashedNames() []string {
+	var names []string
+	for _, name := range t.FieldNames() {
+		if name != t.provenance {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// writeNormalized writes the canonical form of the named fields of rec to h.
+func writeNormalized(h hash.Hash, rec Record, fields []string) {
+	for _, name := range fields {
+		fmt.Fprintf(h, "%s=%s\x00", name, normalize(rec[name]))
+	}
+	h.Write([]byte{'\n'})
+}
+
+func normalize(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,87 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRecordHash(t *testing.T) {
+	a := Record{"ID": 1, "NAME": "alpha", "AMOUNT": 1.5}
+	b := Record{"NAME": "alpha", "ID": 1, "AMOUNT": 1.5}
+	c := Record{"ID": 1, "NAME": "alpha", "AMOUNT": 2.5}
+	if a.Hash() != b.Hash() {
+		t.Errorf("equal records hashed differently")
+	}
+	if a.Hash() == c.Hash() {
+		t.Errorf("different records hashed the same")
+	}
+	if a.Hash("ID", "NAME") != c.Hash("ID", "NAME") {
+		t.Errorf("records equal in ID and NAME hashed differently on those fields")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	fingerprint := func(data []byte) [32]byte {
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		sum, err := tbl.Fingerprint()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		return sum
+	}
+	a := fingerprint(buildTable(fields, "   1alpha", "   2bravo"))
+	b := fingerprint(buildTable(fields, "  1 alpha", "   2bravo", "*  3charl"))
+	c := fingerprint(buildTable(fields, "   1alpha", "   2brava"))
+	if a != b {
+		t.Errorf("tables with equal contents have different fingerprints")
+	}
+	if a == c {
+		t.Errorf("tables with different contents have the same fingerprint")
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	hash := func(data []byte) [32]byte {
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		sum, err := tbl.ContentHash()
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		return sum
+	}
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	a := buildTable(fields, "   1alpha")
+	b := append([]byte(nil), a...)
+	b[1], b[2], b[3] = 120, 1, 1 // a different modification date
+	c := buildTable([]Field{mustField("ID", 'N', 4, 0), mustField("NAME", 'C', 5, 0)}, "    1alpha")
+
+	if hash(a) != hash(b) {
+		t.Errorf("modification date changed the content hash")
+	}
+	if hash(a) == hash(c) {
+		t.Errorf("schema change didn't change the content hash")
+	}
+
+	tbl, err := OpenTable(bytes.NewReader(a), int64(len(a)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	view, err := tbl.Select("NAME")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	sum, err := view.ContentHash()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if sum != hash(buildTable([]Field{mustField("NAME", 'C', 5, 0)}, " alpha")) {
+		t.Errorf("view's content hash doesn't match a table of the selected fields")
+	}
+}
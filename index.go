@@ -0,0 +1,402 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrKeyNotFound is returned by Index.Seek when no key matches.
+var ErrKeyNotFound = errors.New("key not found in index")
+
+// maxIndexDepth bounds the descent through an index, so that a corrupt file
+// whose nodes point in a circle fails instead of looping.
+const maxIndexDepth = 32
+
+// An Index is an index of a table, read from an .ndx file or a tag of a
+// .cdx file, through which records can be looked up by key and visited in
+// key order without reading the whole table.  Indexes are only read, so
+// records changed by an Editor or a program that doesn't maintain the index
+// aren't reflected in it; VerifyIndex finds where an index has gone stale.
+type Index struct {
+	t        *Table
+	tag      string
+	expr     string
+	keyLen   int
+	unique   bool
+	numeric  bool // keys are numbers or dates rather than characters
+	filtered bool // a FOR clause leaves some records out
+	root     int64
+	node     func(off int64) (*indexNode, error)
+
+	// encode converts a key given to Seek, and compare orders keys as
+	// stored.
+	encode  func(v interface{}) ([]byte, error)
+	compare func(a, b []byte) int
+}
+
+// An indexNode is a node of an index's B-tree, decoded from whichever
+// format it is stored in.  keys[i] is the largest key under children[i] in
+// an interior node, which may have one more child than keys for the keys
+// above the last one.  In a leaf, recnos[i] is the record number, counting
+// from 1, of keys[i].
+type indexNode struct {
+	keys     [][]byte
+	recnos   []int
+	children []int64
+}
+
+func (n *indexNode) leaf() bool {
+	return n.children == nil
+}
+
+// An indexSource is an index file to attach to a table as it is opened.
+type indexSource struct {
+	name     string
+	r        io.ReaderAt
+	optional bool // skipped, rather than an error, if it can't be read
+}
+
+// WithIndex attaches an index file to the table, read from r.  The format
+// is picked from the extension of name: an .ndx file holds a single index,
+// whose tag is the file's base name, and a .cdx file holds an index per
+// tag.  dBase IV .mdx files aren't supported.  Open attaches a table's
+// structural .cdx file by itself.
+func WithIndex(name string, r io.ReaderAt) Option {
+	return func(t *Table) {
+		t.indexSrcs = append(t.indexSrcs, indexSource{name: name, r: r})
+	}
+}
+
+// openIndexes reads the index files attached by WithIndex.
+func (t *Table) openIndexes() error {
+	for _, src := range t.indexSrcs {
+		base := filepath.Base(src.name)
+		ext := strings.ToLower(filepath.Ext(base))
+		var xs []*Index
+		var err error
+		switch ext {
+		case ".ndx":
+			var x *Index
+			x, err = t.openNDX(src.r, strings.ToUpper(strings.TrimSuffix(base, filepath.Ext(base))))
+			xs = []*Index{x}
+		case ".cdx":
+			xs, err = t.openCDX(src.r, strings.ToUpper(strings.TrimSuffix(base, filepath.Ext(base))))
+		case ".mdx":
+			err = fmt.Errorf("dBase IV .mdx indexes are not supported")
+		default:
+			err = fmt.Errorf("unknown index file type %q", ext)
+		}
+		if err != nil && src.optional {
+			t.log(levelWarn, "dbf: can't read index file", "file", src.name, "err", err)
+			continue
+		} else if err != nil {
+			return fmt.Errorf("index %s: %s", src.name, err)
+		}
+		t.indexes = append(t.indexes, xs...)
+	}
+	return nil
+}
+
+// Index returns the index of the given tag, compared case-insensitively,
+// among those attached to the table.  Records read through it come from
+// this Table, so an index found through a Select view reads only the
+// selected fields.
+func (t *Table) Index(tag string) (*Index, error) {
+	for _, x := range t.indexes {
+		if strings.EqualFold(x.tag, tag) {
+			view := *x
+			view.t = t
+			return &view, nil
+		}
+	}
+	return nil, fmt.Errorf("table has no index tagged %q", tag)
+}
+
+// IndexTags returns the tags of the indexes attached to the table, in the
+// order their files were attached.
+func (t *Table) IndexTags() []string {
+	var tags []string
+	for _, x := range t.indexes {
+		tags = append(tags, x.tag)
+	}
+	return tags
+}
+
+// Tag returns the name of the index.
+func (x *Index) Tag() string {
+	return x.tag
+}
+
+// Expr returns the index's key expression, as written by dBase or FoxPro.
+func (x *Index) Expr() string {
+	return x.expr
+}
+
+// Unique reports whether the index holds only the first record of each key.
+func (x *Index) Unique() bool {
+	return x.unique
+}
+
+// Filtered reports whether the index leaves records out because of a FoxPro
+// FOR clause.
+func (x *Index) Filtered() bool {
+	return x.filtered
+}
+
+// Field returns the name of the field the index is keyed on, if its key
+// expression is just that field, maybe qualified by an alias, and ""
+// otherwise.
+func (x *Index) Field() string {
+	if i := x.t.exprField(x.expr); i >= 0 {
+		return x.t.FieldName(i)
+	}
+	return ""
+}
+
+// Seek returns the number of the first record, in index order, whose key
+// matches key, or ErrKeyNotFound.  Character keys are given as strings and
+// match as prefixes, as dBase's SEEK does with SET EXACT OFF: "SMI" finds
+// "SMITH", and "SMITH " doesn't find "SMITHSON".  Numeric and date keys are
+// given as numbers and time.Time.  The record may be deleted.
+func (x *Index) Seek(key interface{}) (int, error) {
+	k, err := x.encode(key)
+	if err != nil {
+		return -1, err
+	}
+	it, err := x.seek(k)
+	if err != nil {
+		return -1, err
+	}
+	found, recno, err := it.advance()
+	if err == io.EOF || err == nil && !x.matches(found, k) {
+		return -1, ErrKeyNotFound
+	} else if err != nil {
+		return -1, err
+	}
+	return recno, nil
+}
+
+// matches reports whether the stored key k matches the encoded key given to
+// Seek, which is shorter than stored keys for a character prefix.
+func (x *Index) matches(k, key []byte) bool {
+	if len(key) < len(k) {
+		return bytes.HasPrefix(k, key)
+	}
+	return x.compare(k, key) == 0
+}
+
+// Iterate returns an IndexIterator that visits the table's records in key
+// order.
+func (x *Index) Iterate() (*IndexIterator, error) {
+	return x.seek(nil)
+}
+
+// IterateFrom is like Iterate, but starts at the first key that isn't less
+// than key, given as to Seek.
+func (x *Index) IterateFrom(key interface{}) (*IndexIterator, error) {
+	k, err := x.encode(key)
+	if err != nil {
+		return nil, err
+	}
+	return x.seek(k)
+}
+
+// IterateRange is like IterateFrom, but stops after the last key that
+// isn't greater than to, which like a key given to Seek takes in longer
+// character keys it is a prefix of.  A nil from or to leaves that end of
+// the range open.
+func (x *Index) IterateRange(from, to interface{}) (*IndexIterator, error) {
+	var lo, hi []byte
+	var err error
+	if from != nil {
+		if lo, err = x.encode(from); err != nil {
+			return nil, err
+		}
+	}
+	if to != nil {
+		if hi, err = x.encode(to); err != nil {
+			return nil, err
+		}
+	}
+	it, err := x.seek(lo)
+	if err != nil {
+		return nil, err
+	}
+	it.to = hi
+	return it, nil
+}
+
+// seek returns an IndexIterator positioned before the first key that isn't
+// less than key, or before the first key of all if key is nil.
+func (x *Index) seek(key []byte) (*IndexIterator, error) {
+	it := &IndexIterator{x: x, recno: -1}
+	off := x.root
+	for {
+		if len(it.stack) == maxIndexDepth {
+			return nil, fmt.Errorf("index is deeper than %d levels, it may be corrupt", maxIndexDepth)
+		}
+		n, err := x.node(off)
+		if err != nil {
+			return nil, err
+		}
+		i := 0
+		if key != nil {
+			i = sort.Search(len(n.keys), func(i int) bool {
+				return x.compare(n.keys[i], key) >= 0
+			})
+		}
+		it.stack = append(it.stack, indexFrame{n, i})
+		if n.leaf() || i == len(n.children) {
+			return it, nil
+		}
+		off = n.children[i]
+	}
+}
+
+// An IndexIterator walks through the records of a table in the order of an
+// Index.  It must not be shared between goroutines.
+type IndexIterator struct {
+	x     *Index
+	stack []indexFrame // path from the root to the current leaf
+	recno int
+	to    []byte // the last key to visit, nil for all of them
+}
+
+type indexFrame struct {
+	n   *indexNode
+	pos int // of the next key in a leaf, of the child being visited otherwise
+}
+
+// Next returns the next record in key order that isn't deleted, or io.EOF
+// once the index has been read to the end.  A record that can't be read is
+// reported as a *RecordError and iteration can carry on past it, but a
+// corrupt index is reported as a plain error.
+func (it *IndexIterator) Next() (Record, error) {
+	t := it.x.t
+	for {
+		k, recno, err := it.advance()
+		if err != nil {
+			return nil, err
+		}
+		if it.to != nil && it.x.compare(k, it.to) > 0 && !it.x.matches(k, it.to) {
+			it.stack = nil
+			return nil, io.EOF
+		}
+		it.recno = recno
+		rec, err := t.Record(recno)
+		if err == ErrDeleted {
+			t.count(MetricDeletedSkipped, 1)
+			continue
+		} else if err != nil {
+			return nil, &RecordError{recno, t.recordOffset(recno), err}
+		}
+		return rec, nil
+	}
+}
+
+// RecNo returns the number of the record most recently returned by Next, or
+// -1 if Next hasn't been called.
+func (it *IndexIterator) RecNo() int {
+	return it.recno
+}
+
+// advance moves to the next key, returning it and its record number,
+// counting from 0.
+func (it *IndexIterator) advance() ([]byte, int, error) {
+	k, recno, err := it.entry()
+	if err != nil {
+		return nil, -1, err
+	} else if recno < 1 || recno > it.x.t.nrec {
+		return nil, -1, fmt.Errorf("index points to record %d, table has %d records", recno, it.x.t.nrec)
+	}
+	return k, recno - 1, nil
+}
+
+// entry moves to the next key, returning it and the record number stored
+// with it.
+func (it *IndexIterator) entry() ([]byte, int, error) {
+	x := it.x
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if top.n.leaf() && top.pos < len(top.n.keys) {
+			top.pos++
+			return top.n.keys[top.pos-1], top.n.recnos[top.pos-1], nil
+		}
+		it.stack = it.stack[:len(it.stack)-1]
+		if len(it.stack) == 0 {
+			break
+		}
+		// Move on to the parent's next child, and down to its first leaf.
+		parent := &it.stack[len(it.stack)-1]
+		parent.pos++
+		for !parent.n.leaf() && parent.pos < len(parent.n.children) {
+			if len(it.stack) == maxIndexDepth {
+				return nil, -1, fmt.Errorf("index is deeper than %d levels, it may be corrupt", maxIndexDepth)
+			}
+			n, err := x.node(parent.n.children[parent.pos])
+			if err != nil {
+				return nil, -1, err
+			}
+			it.stack = append(it.stack, indexFrame{n, 0})
+			parent = &it.stack[len(it.stack)-1]
+		}
+	}
+	return nil, -1, io.EOF
+}
+
+// charKey encodes a character key, without padding it, so that it matches
+// stored keys as a prefix.
+func (x *Index) charKey(v interface{}) ([]byte, error) {
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("index %s has character keys, not %T", x.tag, v)
+	}
+	k := []byte(s)
+	if x.t.charset != nil {
+		var err error
+		if k, err = x.t.charset.Encode(s); err != nil {
+			return nil, err
+		}
+	}
+	if len(k) > x.keyLen {
+		return nil, fmt.Errorf("key %q is longer than the %d bytes of index %s", s, x.keyLen, x.tag)
+	}
+	return k, nil
+}
+
+// numericKey converts a numeric or date key to the number dBase indexes it
+// by: dates become Julian day numbers.
+func (x *Index) numericKey(v interface{}) (float64, error) {
+	if d, ok := v.(time.Time); ok {
+		return float64(d.Unix()-JulianEpoch.Unix()) / (24 * 60 * 60), nil
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return 0, fmt.Errorf("index %s has numeric keys, not %T", x.tag, v)
+	}
+	return f, nil
+}
+
+// compareFloatLE orders little-endian floats numerically.
+func compareFloatLE(a, b []byte) int {
+	if len(a) < 8 || len(b) < 8 {
+		return bytes.Compare(a, b)
+	}
+	x := math.Float64frombits(binary.LittleEndian.Uint64(a))
+	y := math.Float64frombits(binary.LittleEndian.Uint64(b))
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
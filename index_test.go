@@ -0,0 +1,370 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type ndxEntry struct {
+	child, recno uint32
+	key          string // empty for the extra child of an interior page
+}
+
+// buildNDX assembles an .ndx file whose page i+1 holds pages[i].
+func buildNDX(keyLen int, numeric bool, expr string, root uint32, pages ...[]ndxEntry) []byte {
+	entry := (keyLen+3)/4*4 + 8
+	data := make([]byte, ndxPage*(len(pages)+1))
+	binary.LittleEndian.PutUint32(data[0:], root)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(pages)+1))
+	binary.LittleEndian.PutUint16(data[12:], uint16(keyLen))
+	if numeric {
+		data[16] = 1
+	}
+	binary.LittleEndian.PutUint16(data[18:], uint16(entry))
+	copy(data[24:], expr)
+	for i, page := range pages {
+		p := data[ndxPage*(i+1):]
+		nkeys := 0
+		for j, e := range page {
+			binary.LittleEndian.PutUint32(p[4+j*entry:], e.child)
+			binary.LittleEndian.PutUint32(p[8+j*entry:], e.recno)
+			copy(p[12+j*entry:], e.key)
+			if e.key != "" {
+				nkeys++
+			}
+		}
+		binary.LittleEndian.PutUint32(p, uint32(nkeys))
+	}
+	return data
+}
+
+func float64LE(f float64) string {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+	return string(b[:])
+}
+
+// indexOrder reads the records of an index in order, returning the values
+// of one field and the record numbers.
+func indexOrder(t *testing.T, it *IndexIterator, field string) (values []interface{}, recnos []int) {
+	for {
+		rec, err := it.Next()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("%s", err)
+		}
+		values = append(values, rec[field])
+		recnos = append(recnos, it.RecNo())
+	}
+}
+
+func TestNDX(t *testing.T) {
+	fields := []Field{mustField("NAME", 'C', 7, 0), mustField("QTY", 'N', 4, 1)}
+	data := buildTable(fields, " BRAVO   3.0", "*CHARLIE-1.5", " ALPHA  10.0", " DELTA   3.0")
+	names := buildNDX(7, false, "NAME", 3,
+		[]ndxEntry{{0, 3, "ALPHA  "}, {0, 1, "BRAVO  "}},
+		[]ndxEntry{{0, 2, "CHARLIE"}, {0, 4, "DELTA  "}},
+		[]ndxEntry{{1, 0, "BRAVO  "}, {2, 0, ""}})
+	qty := buildNDX(8, true, "QTY", 1,
+		[]ndxEntry{{0, 2, float64LE(-1.5)}, {0, 1, float64LE(3)}, {0, 4, float64LE(3)}, {0, 3, float64LE(10)}})
+
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)),
+		WithIndex("names.ndx", bytes.NewReader(names)), WithIndex("/data/QTY.NDX", bytes.NewReader(qty)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if tags := tbl.IndexTags(); !reflect.DeepEqual(tags, []string{"NAMES", "QTY"}) {
+		t.Fatalf("IndexTags() returned %v", tags)
+	}
+	x, err := tbl.Index("names")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if x.Expr() != "NAME" {
+		t.Errorf("Expr() returned %q", x.Expr())
+	}
+	for key, want := range map[string]int{"BRAVO": 0, "B": 0, "CHARLIE": 1, "CH": 1, "DELTA": 3, "ALPHA  ": 2, "AB": -1, "ECHO": -1, "BRAVO ": 0, "BRAVOS": -1} {
+		recno, err := x.Seek(key)
+		if want == -1 && err != ErrKeyNotFound || want >= 0 && (err != nil || recno != want) {
+			t.Errorf("Seek(%q) returned %d, %v, expected %d", key, recno, err, want)
+		}
+	}
+	it, err := x.Iterate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if values, recnos := indexOrder(t, it, "NAME"); !reflect.DeepEqual(values, []interface{}{"ALPHA", "BRAVO", "DELTA"}) || !reflect.DeepEqual(recnos, []int{2, 0, 3}) {
+		t.Errorf("index order is %v, records %v", values, recnos)
+	}
+	if it, err = x.IterateFrom("C"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if values, _ := indexOrder(t, it, "NAME"); !reflect.DeepEqual(values, []interface{}{"DELTA"}) {
+		t.Errorf("index order from C is %v", values)
+	}
+	if _, err = x.Seek(3); err == nil {
+		t.Errorf("expected an error seeking a number in a character index")
+	}
+
+	if x, err = tbl.Index("QTY"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for key, want := range map[float64]int{3: 0, 10: 2, -1.5: 1, 4: -1} {
+		recno, err := x.Seek(key)
+		if want == -1 && err != ErrKeyNotFound || want >= 0 && (err != nil || recno != want) {
+			t.Errorf("Seek(%v) returned %d, %v, expected %d", key, recno, err, want)
+		}
+	}
+	if it, err = x.IterateFrom(0); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, recnos := indexOrder(t, it, "QTY"); !reflect.DeepEqual(recnos, []int{0, 3, 2}) {
+		t.Errorf("index order from 0 is %v", recnos)
+	}
+
+	// An index found through a view reads through the view.
+	view, err := tbl.Select("QTY")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if x, err = view.Index("names"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	it, _ = x.Iterate()
+	if rec, err := it.Next(); err != nil || !reflect.DeepEqual(rec, Record{"QTY": 10.0}) {
+		t.Errorf("Next() through a view returned %v, %v", rec, err)
+	}
+
+	if _, err = tbl.Index("missing"); err == nil {
+		t.Errorf("expected an error for a missing tag")
+	}
+	if _, err = OpenTable(bytes.NewReader(data), int64(len(data)), WithIndex("t.mdx", bytes.NewReader(qty))); err == nil {
+		t.Errorf("expected an error for an .mdx index")
+	}
+	bad := buildNDX(8, true, "QTY", 1, []ndxEntry{{0, 9, float64LE(3)}})
+	tbl, _ = OpenTable(bytes.NewReader(data), int64(len(data)), WithIndex("qty.ndx", bytes.NewReader(bad)))
+	x, _ = tbl.Index("qty")
+	if _, err = x.Seek(3); err == nil || err == ErrKeyNotFound {
+		t.Errorf("expected an error for an index pointing past the last record, got %v", err)
+	}
+}
+
+// cdxLeaf compresses keys into a leaf node, with 16 bits for record
+// numbers and 4 each for the duplicate and trailing counts.
+func cdxLeaf(keyLen int, fill byte, keys []string, recnos []int) []byte {
+	node := make([]byte, cdxNode)
+	node[0] = 0x03
+	binary.LittleEndian.PutUint16(node[2:], uint16(len(keys)))
+	binary.LittleEndian.PutUint32(node[4:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(node[8:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(node[14:], 0xFFFF)
+	node[18], node[19], node[20], node[21], node[22], node[23] = 0x0F, 0x0F, 16, 4, 4, 3
+	end, prev := cdxNode, ""
+	for i, k := range keys {
+		trail := 0
+		for trail < keyLen && trail < 15 && k[keyLen-1-trail] == fill {
+			trail++
+		}
+		dup := 0
+		for dup < len(prev) && dup < 15 && dup < keyLen-trail && k[dup] == prev[dup] {
+			dup++
+		}
+		stored := k[dup : keyLen-trail]
+		end -= len(stored)
+		copy(node[end:], stored)
+		v := uint32(recnos[i]) | uint32(dup)<<16 | uint32(trail)<<20
+		node[24+3*i], node[25+3*i], node[26+3*i] = byte(v), byte(v>>8), byte(v>>16)
+		prev = k
+	}
+	return node
+}
+
+func cdxInterior(keyLen int, keys []string, children []int) []byte {
+	node := make([]byte, cdxNode)
+	node[0] = 0x01
+	binary.LittleEndian.PutUint16(node[2:], uint16(len(keys)))
+	for i, k := range keys {
+		e := node[12+i*(keyLen+8):]
+		copy(e, k)
+		binary.BigEndian.PutUint32(e[keyLen+4:], uint32(children[i]))
+	}
+	return node
+}
+
+func cdxTagHeader(root, keyLen int, options byte, expr string) []byte {
+	h := make([]byte, cdxHeader)
+	binary.LittleEndian.PutUint32(h[0:], uint32(root))
+	binary.LittleEndian.PutUint16(h[12:], uint16(keyLen))
+	h[14] = options
+	copy(h[512:], expr)
+	return h
+}
+
+func cdxNumber(f float64) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], orderedFloat(f))
+	return string(b[:])
+}
+
+func TestCDX(t *testing.T) {
+	fields := []Field{mustField("NAME", 'C', 7, 0), mustField("QTY", 'N', 4, 1)}
+	data := buildTable(fields, " BRAVO   3.0", "*CHARLIE-1.5", " ALPHA  10.0", " DELTA   3.0")
+
+	var cdx []byte
+	cdx = append(cdx, cdxTagHeader(1024, 10, 0x60, "")...)                                          // 0: tag directory
+	cdx = append(cdx, cdxLeaf(10, ' ', []string{"NAME      ", "QTY       "}, []int{1536, 4096})...) // 1024
+	cdx = append(cdx, cdxTagHeader(2560, 7, 0x60, "UPPER(NAME)")...)                                // 1536
+	cdx = append(cdx, cdxInterior(7, []string{"BRAVO  ", "DELTA  "}, []int{3072, 3584})...)         // 2560
+	cdx = append(cdx, cdxLeaf(7, ' ', []string{"ALPHA  ", "BRAVO  "}, []int{3, 1})...)              // 3072
+	cdx = append(cdx, cdxLeaf(7, ' ', []string{"CHARLIE", "DELTA  "}, []int{2, 4})...)              // 3584
+	cdx = append(cdx, cdxTagHeader(5120, 8, 0x60, "T.QTY")...)                                      // 4096
+	cdx = append(cdx, cdxLeaf(8, 0, []string{cdxNumber(-1.5), cdxNumber(3), cdxNumber(3), cdxNumber(10)}, []int{2, 1, 4, 3})...)
+
+	dir, err := ioutil.TempDir("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "T.DBF")
+	if err = ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "T.CDX"), cdx, 0644); err != nil {
+		t.Fatalf("%s", err)
+	}
+	tbl, err := Open(name)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer tbl.Close()
+	if tags := tbl.IndexTags(); !reflect.DeepEqual(tags, []string{"NAME", "QTY"}) {
+		t.Fatalf("IndexTags() returned %v", tags)
+	}
+
+	x, err := tbl.Index("name")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for key, want := range map[string]int{"BRAVO": 0, "CH": 1, "DELTA": 3, "A": 2, "ECHO": -1, "BRAVOS": -1} {
+		recno, err := x.Seek(key)
+		if want == -1 && err != ErrKeyNotFound || want >= 0 && (err != nil || recno != want) {
+			t.Errorf("Seek(%q) returned %d, %v, expected %d", key, recno, err, want)
+		}
+	}
+	it, err := x.Iterate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if values, _ := indexOrder(t, it, "NAME"); !reflect.DeepEqual(values, []interface{}{"ALPHA", "BRAVO", "DELTA"}) {
+		t.Errorf("index order is %v", values)
+	}
+
+	if x, err = tbl.Index("QTY"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for key, want := range map[float64]int{3: 0, 10: 2, -1.5: 1, 4: -1} {
+		recno, err := x.Seek(key)
+		if want == -1 && err != ErrKeyNotFound || want >= 0 && (err != nil || recno != want) {
+			t.Errorf("Seek(%v) returned %d, %v, expected %d", key, recno, err, want)
+		}
+	}
+	if it, err = x.IterateFrom(5); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, recnos := indexOrder(t, it, "QTY"); !reflect.DeepEqual(recnos, []int{2}) {
+		t.Errorf("index order from 5 is %v", recnos)
+	}
+	if it, err = x.IterateRange(0, 3); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, recnos := indexOrder(t, it, "QTY"); !reflect.DeepEqual(recnos, []int{0, 3}) {
+		t.Errorf("index order from 0 to 3 is %v", recnos)
+	}
+	if field := x.Field(); field != "QTY" {
+		t.Errorf("Field() of T.QTY returned %q", field)
+	}
+	if x, err = tbl.Index("NAME"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if it, err = x.IterateRange(nil, "C"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if values, _ := indexOrder(t, it, "NAME"); !reflect.DeepEqual(values, []interface{}{"ALPHA", "BRAVO"}) {
+		t.Errorf("index order up to C is %v", values)
+	}
+	if field := x.Field(); field != "" {
+		t.Errorf("Field() of UPPER(NAME) returned %q", field)
+	}
+
+	// A structural index that can't be read doesn't keep the table from
+	// opening.
+	if err = ioutil.WriteFile(filepath.Join(dir, "T.CDX"), cdx[:100], 0644); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if tbl, err = Open(name); err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer tbl.Close()
+	if tags := tbl.IndexTags(); len(tags) != 0 {
+		t.Errorf("IndexTags() returned %v for an unreadable index", tags)
+	}
+}
+
+func TestVerifyIndex(t *testing.T) {
+	fields := []Field{mustField("NAME", 'C', 7, 0), mustField("QTY", 'N', 4, 1)}
+	data := buildTable(fields, " BRAVO   3.0", "*CHARLIE-1.5", " ALPHA  10.0", " DELTA   3.0")
+	good := buildNDX(7, false, "NAME", 1,
+		[]ndxEntry{{0, 3, "ALPHA  "}, {0, 1, "BRAVO  "}, {0, 2, "CHARLIE"}, {0, 4, "DELTA  "}})
+	// DELTA was added without updating this one.
+	stale := buildNDX(7, false, "NAME", 1,
+		[]ndxEntry{{0, 3, "ALPHA  "}, {0, 1, "BRAVO  "}, {0, 2, "CHARLIE"}})
+	qty := buildNDX(8, true, "QTY", 1,
+		[]ndxEntry{{0, 2, float64LE(-1.5)}, {0, 1, float64LE(3)}, {0, 4, float64LE(3)}, {0, 3, float64LE(10)}})
+	exprs := buildNDX(12, false, "upper(name)+STR(QTY,5,1)", 1,
+		[]ndxEntry{{0, 3, "ALPHA   10.0"}, {0, 1, "BRAVO    3.0"}, {0, 2, "CHARLIE -1.5"}, {0, 4, "DELTA    3.0"}})
+	data[len(data)-1-2*12+1] = 'E' // ALPHA becomes ELPHA, behind the indexes' backs
+
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)),
+		WithIndex("good.ndx", bytes.NewReader(good)), WithIndex("stale.ndx", bytes.NewReader(stale)),
+		WithIndex("qty.ndx", bytes.NewReader(qty)), WithIndex("exprs.ndx", bytes.NewReader(exprs)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, tc := range []struct {
+		tag     string
+		records []int
+	}{
+		{"qty", nil},
+		{"good", []int{2}},
+		{"stale", []int{2, 3}},
+		{"exprs", []int{2}},
+	} {
+		rep, err := tbl.VerifyIndex(tc.tag)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.tag, err)
+		}
+		var records []int
+		for _, a := range rep.Anomalies {
+			records = append(records, a.Record)
+		}
+		if !reflect.DeepEqual(records, tc.records) {
+			t.Errorf("%s: expected anomalies for records %v, got %+v", tc.tag, tc.records, rep.Anomalies)
+		}
+	}
+
+	if err = tbl.Reindex("good"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected Reindex to be unsupported, got %v", err)
+	}
+	if _, err = tbl.compileKey("SUBSTR(NAME,1,3)"); err == nil {
+		t.Errorf("expected an error for an unsupported key expression")
+	}
+}
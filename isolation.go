@@ -0,0 +1,80 @@
+package dbf
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// A latch keeps the readers of an Editor's table from seeing records torn by
+// its writes.  Each write holds the latch exclusively and bumps its
+// generation; record reads hold it shared, and buffered reads, which can't
+// hold it across a record that spans two buffer fills, check that the
+// generation hasn't moved since the buffer was started, and read the record
+// afresh if it has.
+type latch struct {
+	sync.RWMutex
+	gen uint64
+}
+
+// generation returns the number of writes through the latch so far.
+func (l *latch) generation() uint64 {
+	return atomic.LoadUint64(&l.gen)
+}
+
+// withLatch is the Option by which an Editor shares its latch with the
+// Tables it opens.
+func withLatch(l *latch) Option {
+	return func(t *Table) {
+		t.latch = l
+	}
+}
+
+// latchedWriterAt writes to w while holding l exclusively.
+type latchedWriterAt struct {
+	w io.WriterAt
+	l *latch
+}
+
+func (lw latchedWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	lw.l.Lock()
+	defer lw.l.Unlock()
+	defer atomic.AddUint64(&lw.l.gen, 1)
+	return lw.w.WriteAt(p, off)
+}
+
+// latchedReaderAt reads from r while holding l shared, so that each read
+// sees either all or none of a write.
+type latchedReaderAt struct {
+	r io.ReaderAt
+	l *latch
+}
+
+func (lr latchedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	lr.l.RLock()
+	defer lr.l.RUnlock()
+	return lr.r.ReadAt(p, off)
+}
+
+// A span is a run of bytes to be written at an offset.
+type span struct {
+	off int64
+	b   []byte
+}
+
+// writeSpans writes spans to w, all under a single hold of the latch if w
+// is latched.
+func writeSpans(w io.WriterAt, spans []span) error {
+	if lw, ok := w.(latchedWriterAt); ok {
+		lw.l.Lock()
+		defer lw.l.Unlock()
+		defer atomic.AddUint64(&lw.l.gen, 1)
+		w = lw.w
+	}
+	for _, s := range spans {
+		if _, err := w.WriteAt(s.b, s.off); err != nil {
+			return err
+		}
+	}
+	return nil
+}
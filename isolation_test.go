@@ -0,0 +1,66 @@
+package dbf
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEditorIsolation(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	// Records long enough that many straddle the Iterator's buffer fills.
+	var records []string
+	for i := 0; i < 2000; i++ {
+		records = append(records, " "+strings.Repeat("a", 200))
+	}
+	f.Write(buildTable([]Field{mustField("A", 'C', 100, 0), mustField("B", 'C', 100, 0)}, records...))
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	done := make(chan error)
+	go func() {
+		for n := 0; n < 5*len(records); n++ {
+			v := strings.Repeat(string('b'+byte(n%2)), 100)
+			if err := e.Update(n%len(records), Record{"A": v, "B": v}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for pass := 0; ; pass++ {
+		it := e.Iterate()
+		for {
+			rec, err := it.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if rec["A"] != rec["B"] {
+				t.Fatalf("pass %d: record %d torn by a write: %.5s... and %.5s...", pass, it.RecNo(), rec["A"], rec["B"])
+			}
+		}
+		if rec, err := e.Record(pass % len(records)); err != nil || rec["A"] != rec["B"] {
+			t.Fatalf("pass %d: Record returned %v, %v", pass, rec, err)
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package dbf
+
+import (
+	"bufio"
+	"io"
+)
+
+// An Iterator streams through the records of a Table in a single buffered
+// pass, which is much faster than reading records one at a time when the
+// whole table is wanted.  Like a Cursor, it must not be shared between
+// goroutines.
+type Iterator struct {
+	t    *Table
+	r    *bufio.Reader
+	buf  []byte
+	next int    // number of the record to be read by the next call to Next
+	gen  uint64 // generation of the table's latch when r was started
+}
+
+// Iterate returns an Iterator positioned before the first record.
+func (t *Table) Iterate() *Iterator {
+	return t.iterateFrom(0)
+}
+
+// iterateFrom returns an Iterator positioned before record i.
+func (t *Table) iterateFrom(i int) *Iterator {
+	it := &Iterator{t: t, buf: make([]byte, t.recordlen), next: i}
+	it.seek(i)
+	return it
+}
+
+// seek starts the Iterator's buffered reads at record i.
+func (it *Iterator) seek(i int) {
+	t := it.t
+	off := t.recordOffset(i)
+	if off > t.size {
+		off = t.size
+	}
+	src := t.src
+	if t.latch != nil {
+		it.gen = t.latch.generation()
+		src = latchedReaderAt{src, t.latch}
+	}
+	r := io.NewSectionReader(src, off, t.size-off)
+	if it.r == nil {
+		it.r = bufio.NewReaderSize(r, 64*1024)
+	} else {
+		it.r.Reset(r)
+	}
+}
+
+// Next returns the next record that isn't deleted, or io.EOF once all records
+// have been read.  A record that can't be decoded is reported as a
+// *RecordError and iteration can carry on past it, but a table that ends
+// before all its records have been read reports a single *RecordError and
+// then io.EOF.
+func (it *Iterator) Next() (Record, error) {
+	t := it.t
+	for it.next < t.nrec {
+		i := it.next
+		it.next++
+		// The last record may be cut short of its padding.
+		if n, err := io.ReadFull(it.r, it.buf); err != nil && (err != io.ErrUnexpectedEOF || n < t.datalen) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			it.next = t.nrec
+			t.log(levelWarn, "dbf: can't read record", "record", i, "err", err)
+			return nil, &RecordError{i, t.recordOffset(i), err}
+		}
+		if t.latch != nil && t.latch.generation() != it.gen {
+			// An Editor wrote to the table since the buffer was started, maybe
+			// between the reads that make up this record.
+			raw, err := t.readRaw(i)
+			if err != nil {
+				it.next = t.nrec
+				return nil, &RecordError{i, t.recordOffset(i), err}
+			}
+			copy(it.buf, raw)
+			it.seek(i + 1)
+		}
+		rec, err := t.parse(i, it.buf[:t.datalen])
+		if err == ErrDeleted {
+			t.count(MetricDeletedSkipped, 1)
+			continue
+		} else if err != nil {
+			t.log(levelWarn, "dbf: can't read record", "record", i, "err", err)
+			return nil, &RecordError{i, t.recordOffset(i), err}
+		}
+		return rec, nil
+	}
+	return nil, io.EOF
+}
+
+// RecNo returns the number of the record most recently returned by Next, or
+// -1 if Next hasn't been called.
+func (it *Iterator) RecNo() int {
+	return it.next - 1
+}
+
+// ReadAll reads every record that isn't deleted in a single sequential pass.
+// It stops at the first record that can't be decoded, returning the records
+// read so far and a *RecordError, unless the table was opened WithLenient, in
+// which case such records are skipped and reported together as RecordErrors
+// once the whole table has been read.
+func (t *Table) ReadAll() ([]Record, error) {
+	n := t.nrec
+	if t.recordlen > 0 {
+		// Don't trust a corrupt header to size the slice.
+		if fit := (t.size - int64(t.headerlen)) / int64(t.recordlen); fit < int64(n) {
+			n = int(fit)
+		}
+	}
+	if n < 0 {
+		n = 0
+	}
+	recs := make([]Record, 0, n)
+	var failures RecordErrors
+	it := t.Iterate()
+	for {
+		rec, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if e, ok := err.(*RecordError); ok && t.lenient {
+			failures = append(failures, e)
+			continue
+		} else if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+	if failures != nil {
+		return recs, failures
+	}
+	return recs, nil
+}
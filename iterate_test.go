@@ -0,0 +1,111 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestIterate(t *testing.T) {
+	tbl, err := OpenTable(bytes.NewReader(scanTable), int64(len(scanTable)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	it := tbl.Iterate()
+	if rec, err := it.Next(); err != nil || rec["NAME"] != "alpha" || it.RecNo() != 0 {
+		t.Fatalf("expected record 0, got %v, %v at %d", rec, err, it.RecNo())
+	}
+	if _, err := it.Next(); err == nil || it.RecNo() != 2 {
+		t.Fatalf("expected an error for record 2, got %v at %d", err, it.RecNo())
+	}
+	if rec, err := it.Next(); err != nil || rec["NAME"] != "delta" {
+		t.Fatalf("expected record 3, got %v, %v", rec, err)
+	}
+	if _, err := it.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestIterateTruncated(t *testing.T) {
+	data := scanTable[:len(scanTable)-10] // the last record and the EOF marker
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	it := tbl.Iterate()
+	var errs int
+	for {
+		_, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if e, ok := err.(*RecordError); ok && e.Err == io.ErrUnexpectedEOF {
+			if e.Record != 3 {
+				t.Fatalf("expected record 3 to be cut short, got %v", e)
+			}
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Fatalf("expected one truncation error, got %d", errs)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	r, err := NewReaderFromBytes(scanTable)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	recs, err := r.ReadAll()
+	if e, ok := err.(*RecordError); !ok || e.Record != 2 {
+		t.Fatalf("expected an error for record 2, got %v", err)
+	}
+	if len(recs) != 1 || recs[0]["NAME"] != "alpha" {
+		t.Fatalf("expected the records before the error, got %v", recs)
+	}
+
+	limits := Limits{Fields: map[string]int{"NAME": 4}}
+	if r, err = NewReaderFromBytes(scanTable, WithLenient(), WithLimits(limits)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	recs, err = r.ReadAll()
+	if e, ok := err.(RecordErrors); !ok || len(e) != 3 {
+		t.Fatalf("expected 3 errors, got %v", err)
+	}
+	if len(recs) != 0 {
+		t.Fatalf("expected no records, got %v", recs)
+	}
+
+	if r, err = NewReaderFromBytes(scanTable, WithLenient()); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if recs, err = r.ReadAll(); err != nil || len(recs) != 3 || recs[1]["ID"] != nil {
+		t.Fatalf("ReadAll returned %v, %v", recs, err)
+	}
+}
+
+func TestIterateShortRecordLength(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	for _, recordlen := range []uint16{0, 5} {
+		data := buildTable(fields, "   1alpha", "   2bravo")
+		binary.LittleEndian.PutUint16(data[10:], recordlen)
+		if _, err := OpenTable(bytes.NewReader(data), int64(len(data))); err == nil {
+			t.Fatalf("record length %d: expected an error for records shorter than their fields", recordlen)
+		}
+
+		var warnings []Anomaly
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), WithLenient(), WithWarnings(func(a Anomaly) {
+			warnings = append(warnings, a)
+		}))
+		if err != nil {
+			t.Fatalf("record length %d: %s", recordlen, err)
+		}
+		if len(warnings) != 1 || warnings[0].Offset != 10 {
+			t.Fatalf("record length %d: expected a warning about the record length, got %v", recordlen, warnings)
+		}
+		recs, err := tbl.ReadAll()
+		if err != nil || len(recs) != 2 || recs[1]["NAME"] != "bravo" {
+			t.Fatalf("record length %d: expected the fields to be trusted, got %v, %v", recordlen, recs, err)
+		}
+	}
+}
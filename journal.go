@@ -0,0 +1,65 @@
+package dbf
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// A JournalEntry records one change an Editor made to a table.
+type JournalEntry struct {
+	Op     string    `json:"op"`               // "update", "delete", "undelete", "append" or "pack"
+	Record int       `json:"record"`           // record number, counting from 0, before the change
+	Before Record    `json:"before,omitempty"` // the record's values before the change, if it had any
+	After  Record    `json:"after,omitempty"`  // the record's values after the change, if it has any
+	Time   time.Time `json:"time"`
+}
+
+// journal writes JournalEntries to w as JSON lines.
+type journal struct {
+	sync.Mutex
+	enc *json.Encoder
+}
+
+// WithJournal makes an Editor write a JournalEntry to w, as a line of JSON,
+// for every change it makes to a record, so that there is an audit trail of
+// modifications to the table: Update, Apply and Session commits as
+// "update", MarkDeleted and Undelete as "delete" and "undelete", Append as
+// "append", and Pack as "pack", once for every deleted record it removes.
+// Records after a removed one move up a place, which Pack doesn't journal
+// separately.  Values are decoded as by a table opened WithLenient, so that
+// changes to undecodable values are journaled too.  Entries are written
+// after the change they record, and an Editor method that can't write its
+// entry returns the error although the change has been made.
+func WithJournal(w io.Writer) Option {
+	j := &journal{enc: json.NewEncoder(w)}
+	return func(t *Table) {
+		t.journal = j
+	}
+}
+
+// record writes an entry to the table's journal, if it has one.
+func (t *Table) record(op string, i int, before, after Record) error {
+	if t.journal == nil {
+		return nil
+	}
+	e := JournalEntry{Op: op, Record: i, Before: before, After: after, Time: time.Now()}
+	t.journal.Lock()
+	defer t.journal.Unlock()
+	return t.journal.enc.Encode(e)
+}
+
+// decodeLeniently decodes raw record i as a table opened WithLenient does,
+// without logging or warnings.  A nil raw decodes to a nil Record.
+func (t *Table) decodeLeniently(i int, raw []byte) Record {
+	if raw == nil {
+		return nil
+	}
+	lenient := *t
+	lenient.lenient = true
+	lenient.logger = nil
+	lenient.warnFn = nil
+	rec, _ := lenient.decodeRecord(i, raw)
+	return rec
+}
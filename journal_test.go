@@ -0,0 +1,69 @@
+package dbf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestJournal(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.Write(buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}, "   1alpha", "   2bravo"))
+
+	var out bytes.Buffer
+	e, err := NewEditor(f, WithJournal(&out))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Update(0, Record{"NAME": "zulu"}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.MarkDeleted(1); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = e.Append(Record{"ID": 3, "NAME": "charl"}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = e.Pack(); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	var entries []JournalEntry
+	sc := bufio.NewScanner(&out)
+	for sc.Scan() {
+		var entry JournalEntry
+		if err := json.Unmarshal(sc.Bytes(), &entry); err != nil {
+			t.Fatalf("%s: %q", err, sc.Text())
+		}
+		entries = append(entries, entry)
+	}
+	expected := []struct {
+		op            string
+		record        int
+		before, after string
+	}{
+		{"update", 0, "alpha", "zulu"},
+		{"delete", 1, "bravo", ""},
+		{"append", 2, "", "charl"},
+		{"pack", 1, "bravo", ""},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d journal entries, got %+v", len(expected), entries)
+	}
+	for k, x := range expected {
+		e := entries[k]
+		before, _ := e.Before["NAME"].(string)
+		after, _ := e.After["NAME"].(string)
+		if e.Op != x.op || e.Record != x.record || before != x.before || after != x.after || e.Time.IsZero() {
+			t.Errorf("entry %d: expected %+v, got %+v", k, x, e)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package dbf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A keyFunc evaluates an index key expression for a record, given its raw
+// bytes and its decoded values.  Character results are []byte, numeric ones
+// float64 and dates time.Time.
+type keyFunc func(raw []byte, rec Record) (interface{}, error)
+
+// compileKey parses the commonest index key expressions: a field, maybe
+// qualified by an alias, UPPER, DTOS and STR of them, and concatenations of
+// character values with +.
+func (t *Table) compileKey(expr string) (keyFunc, error) {
+	p := &keyParser{t: t, s: expr}
+	f, err := p.sum()
+	p.skip()
+	if err == nil && p.pos < len(p.s) {
+		err = fmt.Errorf("unexpected %q", p.s[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't evaluate key expression %q: %s", expr, err)
+	}
+	return f, nil
+}
+
+type keyParser struct {
+	t   *Table
+	s   string
+	pos int
+}
+
+// skip moves past spaces.
+func (p *keyParser) skip() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// peek reports whether the next token starts with tok, and consumes it if
+// so.
+func (p *keyParser) peek(tok string) bool {
+	p.skip()
+	if strings.HasPrefix(p.s[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// sum parses terms joined by +.
+func (p *keyParser) sum() (keyFunc, error) {
+	f, err := p.term()
+	for err == nil && p.peek("+") {
+		var g keyFunc
+		if g, err = p.term(); err != nil {
+			break
+		}
+		left := f
+		f = func(raw []byte, rec Record) (interface{}, error) {
+			a, err := left(raw, rec)
+			if err != nil {
+				return nil, err
+			}
+			b, err := g(raw, rec)
+			if err != nil {
+				return nil, err
+			}
+			x, ok1 := a.([]byte)
+			y, ok2 := b.([]byte)
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("+ only joins character values")
+			}
+			return append(append([]byte(nil), x...), y...), nil
+		}
+	}
+	return f, err
+}
+
+// term parses a number, a field or a function call.
+func (p *keyParser) term() (keyFunc, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+		p.pos++
+	}
+	if p.pos > start {
+		n, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+		return func([]byte, Record) (interface{}, error) { return n, nil }, err
+	}
+	name := p.ident()
+	if name == "" {
+		return nil, fmt.Errorf("expected a field or function at %q", p.s[p.pos:])
+	}
+	if p.peek("->") || p.peek(".") {
+		if name = p.ident(); name == "" {
+			return nil, fmt.Errorf("expected a field after the alias")
+		}
+	}
+	if !p.peek("(") {
+		return p.field(name)
+	}
+	var args []keyFunc
+	for !p.peek(")") {
+		if len(args) > 0 && !p.peek(",") {
+			return nil, fmt.Errorf("expected , or ) in the arguments of %s", name)
+		}
+		arg, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return keyFunction(strings.ToUpper(name), args)
+}
+
+// ident reads a name.
+func (p *keyParser) ident() string {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || p.pos > start && c >= '0' && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.s[start:p.pos]
+}
+
+// field returns the value of the named field: the bytes stored in a
+// character field, blanks included, and the decoded value otherwise.
+func (p *keyParser) field(name string) (keyFunc, error) {
+	t := p.t
+	pos := 1
+	for i, f := range t.fields {
+		fname := t.FieldName(i)
+		if !strings.EqualFold(fname, name) {
+			pos += int(f.Len)
+			continue
+		}
+		start, end := pos, pos+int(f.Len)
+		switch f.Type {
+		case 'C':
+			return func(raw []byte, rec Record) (interface{}, error) {
+				b := raw[start:end]
+				if c, ok := t.crypts[fname]; ok {
+					return c.Decrypt(b)
+				}
+				return append([]byte(nil), b...), nil
+			}, nil
+		case 'N', 'F', 'I', 'B', 'Y':
+			return func(raw []byte, rec Record) (interface{}, error) {
+				if rec[fname] == nil {
+					return 0.0, nil
+				}
+				n, ok := toFloat(rec[fname])
+				if !ok {
+					return nil, fmt.Errorf("field %s holds %v, not a number", fname, rec[fname])
+				}
+				return n, nil
+			}, nil
+		case 'D', 'T':
+			return func(raw []byte, rec Record) (interface{}, error) {
+				d, _ := rec[fname].(time.Time)
+				return d, nil
+			}, nil
+		}
+		return nil, fmt.Errorf("field %s is of type '%c', which keys can't be made of", fname, f.Type)
+	}
+	return nil, fmt.Errorf("table has no field %s", name)
+}
+
+// keyFunction returns the function name applied to args.
+func keyFunction(name string, args []keyFunc) (keyFunc, error) {
+	switch {
+	case name == "UPPER" && len(args) == 1:
+		return func(raw []byte, rec Record) (interface{}, error) {
+			v, err := args[0](raw, rec)
+			if b, ok := v.([]byte); ok {
+				return bytes.ToUpper(b), nil
+			} else if err == nil {
+				err = fmt.Errorf("UPPER of a %T", v)
+			}
+			return nil, err
+		}, nil
+	case name == "DTOS" && len(args) == 1:
+		return func(raw []byte, rec Record) (interface{}, error) {
+			v, err := args[0](raw, rec)
+			if d, ok := v.(time.Time); ok && d.IsZero() {
+				return []byte("        "), nil
+			} else if ok {
+				return []byte(d.Format("20060102")), nil
+			} else if err == nil {
+				err = fmt.Errorf("DTOS of a %T", v)
+			}
+			return nil, err
+		}, nil
+	case name == "STR" && len(args) >= 1 && len(args) <= 3:
+		return func(raw []byte, rec Record) (interface{}, error) {
+			var n [3]float64
+			n[1] = 10
+			for i, arg := range args {
+				v, err := arg(raw, rec)
+				if err != nil {
+					return nil, err
+				}
+				f, ok := v.(float64)
+				if !ok {
+					return nil, fmt.Errorf("STR of a %T", v)
+				}
+				n[i] = f
+			}
+			width := int(n[1])
+			s := strconv.FormatFloat(n[0], 'f', int(n[2]), 64)
+			if len(s) > width {
+				s = strings.Repeat("*", width)
+			}
+			return []byte(fmt.Sprintf("%*s", width, s)), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported function %s with %d arguments", name, len(args))
+}
+
+// VerifyIndex checks the index tagged tag against the table: that every key
+// it holds is what its key expression evaluates to for the record it points
+// to, that its keys are in order, and that every record is indexed exactly
+// once, except for records whose key a unique index already holds and, for
+// FoxPro tags with a FOR clause, which isn't evaluated, records left out by
+// it.  Records missing from an index are the usual reason a legacy
+// application can't find records that are in the table.  Only common key
+// expressions can be evaluated: fields, maybe qualified by an alias, UPPER,
+// DTOS and STR of them, and character values joined with +; others are an
+// error.  As with Validate, the returned error is for failures to read the
+// table or walk the index; what doesn't match is described by the report.
+func (t *Table) VerifyIndex(tag string) (*AnomalyReport, error) {
+	x, err := t.Index(tag)
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.compileKey(x.expr)
+	if err != nil {
+		return nil, err
+	}
+	rep := &AnomalyReport{Anomalies: []Anomaly{}}
+	seen := make([]bool, t.nrec)
+	keys := map[string]bool{}
+	it, err := x.seek(nil)
+	if err != nil {
+		return nil, err
+	}
+	var prev []byte
+	for {
+		k, recno, err := it.entry()
+		if err != io.EOF && err != nil {
+			return nil, err
+		} else if err == io.EOF {
+			break
+		}
+		if prev != nil && x.compare(prev, k) > 0 {
+			rep.add(SeverityError, -1, recno-1, "", "index key %q comes after the greater key %q", k, prev)
+		}
+		prev = k
+		i := recno - 1
+		if i < 0 || i >= t.nrec {
+			rep.add(SeverityError, -1, -1, "", "index key %q points to record %d, table has %d records", k, recno, t.nrec)
+			continue
+		}
+		if seen[i] {
+			rep.add(SeverityError, t.recordOffset(i), i, "", "record %d is indexed more than once", i)
+		}
+		seen[i] = true
+		keys[string(x.normalizeKey(k))] = true
+		want, err := x.evaluate(key, i)
+		if err != nil {
+			if _, ok := err.(*RecordError); ok {
+				return nil, err
+			}
+			rep.add(SeverityError, t.recordOffset(i), i, "", "can't evaluate the key of record %d: %s", i, err)
+		} else if !bytes.Equal(x.normalizeKey(k), x.normalizeKey(want)) {
+			rep.add(SeverityError, t.recordOffset(i), i, "", "index has key %q for record %d, whose key is %q", k, i, want)
+		}
+	}
+	if x.filtered {
+		return rep, nil
+	}
+	for i, ok := range seen {
+		if ok {
+			continue
+		}
+		want, err := x.evaluate(key, i)
+		if _, ok := err.(*RecordError); ok {
+			return nil, err
+		}
+		if err == nil && x.unique && keys[string(x.normalizeKey(want))] {
+			continue
+		}
+		rep.add(SeverityError, t.recordOffset(i), i, "", "record %d is missing from the index", i)
+	}
+	return rep, nil
+}
+
+// evaluate returns the key of record i as the index stores it.  A record
+// that can't be read is reported as a *RecordError.
+func (x *Index) evaluate(key keyFunc, i int) ([]byte, error) {
+	t := x.t
+	raw, err := t.readRaw(i)
+	if err != nil {
+		return nil, &RecordError{i, t.recordOffset(i), err}
+	}
+	v, err := key(raw, t.decodeLeniently(i, raw))
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := v.([]byte); ok {
+		if len(b) > x.keyLen {
+			b = b[:x.keyLen]
+		}
+		return b, nil
+	}
+	return x.encode(v)
+}
+
+// normalizeKey drops the padding of a character key.
+func (x *Index) normalizeKey(k []byte) []byte {
+	if x.numeric {
+		return k
+	}
+	return bytes.TrimRight(k, " \x00")
+}
+
+// Reindex would rebuild the index tagged tag from the table, but indexes
+// are read-only: it always fails with an error wrapping
+// errors.ErrUnsupported.  Rebuild the index with the program that
+// maintains it, and check it with VerifyIndex.
+func (t *Table) Reindex(tag string) error {
+	if _, err := t.Index(tag); err != nil {
+		return err
+	}
+	return fmt.Errorf("can't rebuild index %s: %w", tag, errors.ErrUnsupported)
+}
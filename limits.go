@@ -0,0 +1,57 @@
+package dbf
+
+import "fmt"
+
+// Limits bound the size of decoded values, protecting services that read
+// untrusted tables from memory abuse, chiefly through memo fields whose
+// stored lengths are arbitrary.  A limit of 0 means no limit.
+type Limits struct {
+	Value  int            // bytes in any one decoded value
+	Memo   int            // bytes in a memo, checked before it is read
+	Record int            // bytes in all the values of a record together
+	Fields map[string]int // per-field limits, taking precedence over Value and Memo
+}
+
+// WithLimits caps the size of decoded values.  A value or record over its
+// limit fails to decode with a *LimitError.
+func WithLimits(l Limits) Option {
+	return func(t *Table) {
+		t.limits = l
+	}
+}
+
+// A LimitError reports a value or record larger than its limit.
+type LimitError struct {
+	Field string // the offending field, or "" for a whole record
+	Size  int    // size of the value in bytes, or a lower bound
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("record is at least %d bytes long, over the limit of %d", e.Size, e.Limit)
+	}
+	return fmt.Sprintf("field %s is at least %d bytes long, over the limit of %d", e.Field, e.Size, e.Limit)
+}
+
+// limit returns the size limit for values of field f.
+func (l Limits) limit(name string, f Field) int {
+	if n, ok := l.Fields[name]; ok {
+		return n
+	} else if f.isMemo() && l.Memo > 0 {
+		return l.Memo
+	}
+	return l.Value
+}
+
+// valueSize measures a decoded value for the purpose of Limits.  Only text
+// and binary values can grow beyond their field's width.
+func valueSize(v interface{}, f Field) int {
+	switch v := v.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	}
+	return int(f.Len)
+}
@@ -0,0 +1,121 @@
+package dbf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field types each file version can store, keyed by version byte.
+var versionTypes = map[byte]string{
+	0x03: "CNLDF",           // dBase III / dBase IV without memo
+	0x83: "CNLDMF",          // dBase III with memo
+	0x8B: "CNLDMF",          // dBase IV with memo
+	0x04: "CNLDMFBGOI@+",    // dBase 7
+	0x8C: "CNLDMFBGOI@+",    // dBase 7 with memo
+	0xF5: "CNLDMFGP",        // FoxPro 2.x with memo
+	0xE5: "CNLDMF",          // HiPer-SIx with SMT memo
+	0x30: "CYNFDTBILMGPVQW", // Visual FoxPro
+	0x31: "CYNFDTBILMGPVQW", // Visual FoxPro with autoincrement
+	0x32: "CYNFDTBILMGPVQW", // Visual FoxPro with varchar/varbinary
+}
+
+// A LintIssue is a problem Lint found with a schema.
+type LintIssue struct {
+	Field   string // name of the offending field, empty for the whole schema
+	Message string
+}
+
+func (i LintIssue) String() string {
+	if i.Field == "" {
+		return i.Message
+	}
+	return fmt.Sprintf("field %s: %s", i.Field, i.Message)
+}
+
+// Lint checks a schema for problems before a table is written with it as the
+// given file version: missing, malformed or duplicate names, zero-length
+// fields, widths that don't suit the field type or its decimal places, types
+// the version can't store, and records or headers too long for the header to
+// describe.
+func Lint(fields []Field, version byte) []LintIssue {
+	var issues []LintIssue
+	add := func(field, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{field, fmt.Sprintf(format, args...)})
+	}
+
+	types, known := versionTypes[version]
+	if !known {
+		add("", "unknown file version %#02x", version)
+	}
+	if len(fields) == 0 {
+		add("", "schema has no fields")
+	}
+
+	seen := make(map[string]bool)
+	recordlen := 1
+	for _, f := range fields {
+		name := strings.TrimRight(string(f.Name[:]), "\x00")
+		recordlen += int(f.Len)
+
+		switch {
+		case name == "":
+			add(name, "name is empty")
+		case f.Name[len(f.Name)-1] != 0:
+			add(name, "name is longer than %d bytes", len(f.Name)-1)
+		case !validFieldName(name):
+			add(name, "name must start with a letter and contain only letters, digits and underscores")
+		}
+		if upper := strings.ToUpper(name); seen[upper] {
+			add(name, "duplicate name")
+		} else {
+			seen[upper] = true
+		}
+
+		if _, registered := registeredType(version, f.Type); known && !registered && strings.IndexByte(types, f.Type) < 0 {
+			add(name, "type '%c' isn't supported by file version %#02x", f.Type, version)
+		}
+		if f.Len == 0 {
+			add(name, "length is zero")
+			continue
+		}
+		switch f.Type {
+		case 'N', 'F':
+			if f.Len > 20 {
+				add(name, "numeric width %d exceeds the maximum of 20", f.Len)
+			}
+			if f.DecimalPlaces > 0 && int(f.Len) < int(f.DecimalPlaces)+2 {
+				add(name, "width %d is too small for %d decimal places", f.Len, f.DecimalPlaces)
+			}
+		case 'D':
+			if f.Len != 8 {
+				add(name, "date fields must be 8 bytes wide, not %d", f.Len)
+			}
+		case 'L':
+			if f.Len != 1 {
+				add(name, "logical fields must be 1 byte wide, not %d", f.Len)
+			}
+		}
+		if f.Type != 'N' && f.Type != 'F' && f.Type != 'Y' && f.Type != 'B' && f.DecimalPlaces > 0 {
+			add(name, "type '%c' can't have decimal places", f.Type)
+		}
+	}
+	if recordlen > 0xFFFF {
+		add("", "records are %d bytes long, more than the maximum of %d", recordlen, 0xFFFF)
+	}
+	if headerlen := 32 + 32*len(fields) + 1; headerlen > 0xFFFF {
+		add("", "header is %d bytes long, more than the maximum of %d", headerlen, 0xFFFF)
+	}
+	return issues
+}
+
+func validFieldName(name string) bool {
+	for i, c := range name {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package dbf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	if issues := Lint(reader.fields, 0x03); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+
+	tooLong := Field{Type: 'C', Len: 1}
+	copy(tooLong.Name[:], "ELEVENCHARS")
+	fields := []Field{
+		mustField("ID", 'N', 4, 3),
+		mustField("id", 'C', 0, 0),
+		mustField("2ND", 'D', 10, 0),
+		mustField("PRICE", 'Y', 8, 4),
+		tooLong,
+	}
+	var actual []string
+	for _, issue := range Lint(fields, 0x03) {
+		actual = append(actual, issue.String())
+	}
+	expected := []string{
+		"field ID: width 4 is too small for 3 decimal places",
+		"field id: duplicate name",
+		"field id: length is zero",
+		"field 2ND: name must start with a letter and contain only letters, digits and underscores",
+		"field 2ND: date fields must be 8 bytes wide, not 10",
+		"field PRICE: type 'Y' isn't supported by file version 0x03",
+		"field ELEVENCHARS: name is longer than 10 bytes",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("wrong issues:\n got %q\nwant %q", actual, expected)
+	}
+}
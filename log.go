@@ -0,0 +1,51 @@
+package dbf
+
+import "time"
+
+// Log levels, numerically identical to log/slog's.
+const (
+	levelDebug = -4
+	levelInfo  = 0
+	levelWarn  = 4
+	levelError = 8
+)
+
+// logger receives a Table's diagnostic events.  It is satisfied by the
+// log/slog adapter installed with WithLogger, and kept free of the slog
+// types themselves so the package still builds on older Go releases.
+type logger interface {
+	log(level int, msg string, args ...interface{})
+}
+
+func (t *Table) log(level int, msg string, args ...interface{}) {
+	if t.logger != nil {
+		t.logger.log(level, msg, args...)
+	}
+}
+
+// defaultSlowScan is how long a scan may take before it is logged as slow,
+// unless WithSlowScanThreshold says otherwise.
+const defaultSlowScan = 10 * time.Second
+
+// WithSlowScanThreshold sets how long Scan and ScanSkippingErrors may take
+// before the table logs them as slow, at Warn; 10 seconds by default.
+func WithSlowScanThreshold(d time.Duration) Option {
+	return func(t *Table) {
+		t.slowScan = d
+	}
+}
+
+// logScan logs a scan that started at start and visited n records if it
+// was slow.
+func (t *Table) logScan(start time.Time, n int) {
+	if t.logger == nil {
+		return
+	}
+	limit := t.slowScan
+	if limit <= 0 {
+		limit = defaultSlowScan
+	}
+	if d := time.Since(start); d >= limit {
+		t.log(levelWarn, "dbf: slow scan", "records", n, "duration", d)
+	}
+}
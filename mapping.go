@@ -0,0 +1,159 @@
+package dbf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A ColumnType is the type of a column in an export, independent of the
+// output format.
+type ColumnType string
+
+const (
+	ColumnString ColumnType = "string"
+	ColumnInt    ColumnType = "int"
+	ColumnFloat  ColumnType = "float"
+	ColumnBool   ColumnType = "bool"
+	ColumnDate   ColumnType = "date"
+)
+
+// A ColumnMap sends one field of a table to one column of an export.
+type ColumnMap struct {
+	Field  string     `json:"field"`            // source field or computed column
+	Column string     `json:"column,omitempty"` // target column name, defaults to Field
+	Type   ColumnType `json:"type,omitempty"`   // target type, defaults to the decoded type
+}
+
+// A Mapping declares the columns of an export, in order.  It is meant to be
+// written once, in Go or as JSON, and shared between exporters so that every
+// output format renames and converts fields the same way.
+type Mapping []ColumnMap
+
+// MappingFor returns the identity mapping of a table: every field, computed
+// columns included, under its own name and decoded type.
+func MappingFor(t *Table) Mapping {
+	var m Mapping
+	for _, name := range t.FieldNames() {
+		m = append(m, ColumnMap{Field: name})
+	}
+	return m
+}
+
+// Check verifies that every source field exists in the table, that column
+// names are unique and that target types are known.
+func (m Mapping) Check(t *Table) error {
+	fields := make(map[string]bool)
+	for _, name := range t.FieldNames() {
+		fields[name] = true
+	}
+	columns := make(map[string]bool)
+	for _, c := range m {
+		if !fields[c.Field] {
+			return fmt.Errorf("mapping refers to unknown field %s", c.Field)
+		}
+		col := c.column()
+		if columns[col] {
+			return fmt.Errorf("mapping has more than one column named %s", col)
+		}
+		columns[col] = true
+		switch c.Type {
+		case "", ColumnString, ColumnInt, ColumnFloat, ColumnBool, ColumnDate:
+		default:
+			return fmt.Errorf("column %s has unknown type %q", col, c.Type)
+		}
+	}
+	return nil
+}
+
+func (c ColumnMap) column() string {
+	if c.Column == "" {
+		return c.Field
+	}
+	return c.Column
+}
+
+// Columns returns the names of the target columns.
+func (m Mapping) Columns() []string {
+	names := make([]string, len(m))
+	for i, c := range m {
+		names[i] = c.column()
+	}
+	return names
+}
+
+// Row converts rec to the values of the target columns, in order.  Missing
+// and unknown values stay nil whatever the target type.
+func (m Mapping) Row(rec Record) ([]interface{}, error) {
+	row := make([]interface{}, len(m))
+	for i, c := range m {
+		v, err := convertColumn(rec[c.Field], c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %s", c.column(), err)
+		}
+		row[i] = v
+	}
+	return row, nil
+}
+
+// convertColumn converts a decoded field value to typ.
+func convertColumn(v interface{}, typ ColumnType) (interface{}, error) {
+	if v == nil || typ == "" {
+		return v, nil
+	}
+	switch typ {
+	case ColumnString:
+		return normalize(v), nil
+	case ColumnInt:
+		switch v := v.(type) {
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				return int64(v), nil
+			}
+		case string:
+			return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+	case ColumnFloat:
+		switch v := v.(type) {
+		case int:
+			return float64(v), nil
+		case int32:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case Currency:
+			return v.Float64(), nil
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+	case ColumnBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(v))
+		}
+	case ColumnDate:
+		switch v := v.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			s := strings.TrimSpace(v)
+			if len(s) == 8 {
+				return time.Parse("20060102", s)
+			}
+			return time.Parse("2006-01-02", s)
+		}
+	}
+	return nil, fmt.Errorf("can't convert %v (%T) to %s", v, v, typ)
+}
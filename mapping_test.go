@@ -0,0 +1,60 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMapping(t *testing.T) {
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0), mustField("AMOUNT", 'N', 6, 2)},
+		"   1alpha  1.50",
+	)
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var m Mapping
+	err = json.Unmarshal([]byte(`[
+		{"field": "NAME", "column": "customer"},
+		{"field": "ID", "column": "id", "type": "string"},
+		{"field": "AMOUNT", "type": "float"}
+	]`), &m)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = m.Check(tbl); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if cols := m.Columns(); !reflect.DeepEqual(cols, []string{"customer", "id", "AMOUNT"}) {
+		t.Fatalf("wrong columns: %v", cols)
+	}
+	rec, err := tbl.Record(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	row, err := m.Row(rec)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := []interface{}{"alpha", "1", 1.5}; !reflect.DeepEqual(row, expected) {
+		t.Fatalf("expected %v, got %v", expected, row)
+	}
+
+	if _, err = (Mapping{{Field: "NAME", Type: ColumnInt}}).Row(rec); err == nil {
+		t.Fatalf("expected an error converting a name to an int")
+	}
+	for _, bad := range []Mapping{
+		{{Field: "NOPE"}},
+		{{Field: "ID"}, {Field: "NAME", Column: "ID"}},
+		{{Field: "ID", Type: "decimal"}},
+	} {
+		if err = bad.Check(tbl); err == nil {
+			t.Fatalf("expected %v to fail its check", bad)
+		}
+	}
+	if m = MappingFor(tbl); len(m) != 3 || m.Check(tbl) != nil {
+		t.Fatalf("bad identity mapping %v", m)
+	}
+}
@@ -0,0 +1,37 @@
+package dbf
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Marshal writes a table to dst holding one record per element of slice,
+// which must be a slice of structs or of pointers to structs.  The schema is
+// derived from the element type by SchemaFromStruct, so `dbf` struct tags
+// set column names, widths and decimal places, and each element is converted
+// by RecordFromStruct.  opts configure the Writer, as for NewWriter.
+func Marshal(dst io.WriteSeeker, slice interface{}, opts ...WriterOption) error {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("can't marshal a %T, expected a slice of structs", slice)
+	}
+	fields, err := SchemaFromStruct(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	w, err := NewWriter(dst, fields, opts...)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < v.Len(); i++ {
+		rec, err := RecordFromStruct(v.Index(i).Interface())
+		if err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
+		}
+		if err = w.Write(rec); err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+	return w.Close()
+}
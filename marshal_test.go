@@ -0,0 +1,62 @@
+package dbf
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	type sale struct {
+		Item   string    `dbf:",len=8"`
+		Amount float64   `dbf:",len=8,dec=2"`
+		Sold   time.Time `dbf:"DATE"`
+		Note   string    `dbf:"-"`
+	}
+	var ws writeSeeker
+	sales := []*sale{
+		{"apple", 1.5, time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), "ignored"},
+		{"banana", -0.25, time.Time{}, ""},
+	}
+	if err := Marshal(&ws, sales); err != nil {
+		t.Fatalf("%s", err)
+	}
+	tbl, err := OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if names := tbl.FieldNames(); len(names) != 3 || names[2] != "DATE" {
+		t.Fatalf("wrong fields %v", names)
+	}
+	var got sale
+	if err = tbl.ReadInto(1, &got); err != nil || got.Item != "banana" || got.Amount != -0.25 || !got.Sold.IsZero() {
+		t.Fatalf("ReadInto returned %+v, %v", got, err)
+	}
+
+	// Blank dates come back as NULL.
+	type visit struct {
+		Name  string `dbf:",len=8"`
+		First *time.Time
+		Last  sql.NullTime
+	}
+	ws = writeSeeker{}
+	if err = Marshal(&ws, []visit{{Name: "never"}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if tbl, err = OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf))); err != nil {
+		t.Fatalf("%s", err)
+	}
+	now := time.Now()
+	v := visit{First: &now, Last: sql.NullTime{Time: now, Valid: true}}
+	if err = tbl.ReadInto(0, &v); err != nil || v.Name != "never" || v.First != nil || v.Last.Valid {
+		t.Fatalf("ReadInto returned %+v, %v", v, err)
+	}
+
+	if err = Marshal(&writeSeeker{}, sale{}); err == nil {
+		t.Fatalf("expected an error for a non-slice")
+	}
+	if err = Marshal(&writeSeeker{}, []sale{{Item: "much too long"}}); err == nil {
+		t.Fatalf("expected an error for a value that doesn't fit")
+	}
+}
@@ -0,0 +1,297 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Memo ('M') fields store only a block number; the text itself lives in a
+// companion file.  FoxPro general ('G') and picture ('P') fields are stored
+// the same way.  dBase uses .dbt files and FoxPro .fpt files:
+//
+//	dBase III  .dbt  512-byte blocks, text runs until a 0x1A marker
+//	dBase IV   .dbt  block size at byte 20, each memo has an 8-byte header
+//	                 (0xFF 0xFF 0x08 0x00, then its little-endian length)
+//	FoxPro     .fpt  block size at byte 6, each memo has an 8-byte header
+//	                 (big-endian type, then big-endian length)
+//	HiPer-SIx  .smt  block size at byte 4, memos have no header: the
+//	                 10-byte memo field holds a little-endian type, length
+//	                 and block number
+//
+// Tables with SMT memos, used by Clipper and Harbour applications with the
+// SIx driver, have version byte 0xE5.  Their memos can be read but not
+// written.  FlexFile's .dbv memos aren't supported.
+
+// WithMemo supplies the memo file (.dbt or .fpt) holding the contents of the
+// table's memo fields.  The format is picked from the table's version byte.
+// Without a memo file, reading a record whose memo field isn't blank fails.
+func WithMemo(r io.ReaderAt) Option {
+	return func(t *Table) {
+		t.memo = &memoFile{r: r}
+	}
+}
+
+// WithMemoBytes is like WithMemo, for a memo file held in memory.  The
+// slice is read in place and must not be modified while the table is in
+// use.
+func WithMemoBytes(memo []byte) Option {
+	return WithMemo(memSource(memo))
+}
+
+type memoFile struct {
+	r         io.ReaderAt
+	fpt       bool
+	smt       bool // the memo field holds the length, the block no header
+	dbase3    bool // memos end with 0x1A rather than starting with a header
+	blockSize int64
+}
+
+// open reads the memo file's header, which fixes its block size.
+func (m *memoFile) open(version byte) error {
+	var h [22]byte
+	if err := readFullAt(m.r, h[:], 0); err != nil {
+		return fmt.Errorf("can't read memo file header: %s", err)
+	}
+	switch version {
+	case 0xF5, 0x30, 0x31, 0x32:
+		m.fpt = true
+		m.blockSize = int64(binary.BigEndian.Uint16(h[6:8]))
+	case 0xE5:
+		m.smt = true
+		m.blockSize = int64(binary.LittleEndian.Uint16(h[4:6]))
+	default:
+		m.blockSize = int64(binary.LittleEndian.Uint16(h[20:22]))
+		if h[16] == 0x03 || m.blockSize == 0 {
+			m.blockSize, m.dbase3 = 512, true
+		}
+	}
+	if m.blockSize == 0 {
+		return fmt.Errorf("memo file has a block size of 0")
+	}
+	return nil
+}
+
+// read returns the memo starting at the given block.  Text memos are
+// returned as strings; FoxPro pictures and OLE objects as []byte.  Memos
+// longer than max bytes, unless it is 0, are reported as a *LimitError
+// without being read in full.
+func (m *memoFile) read(block int64, max int) (interface{}, error) {
+	off := block * m.blockSize
+	var h [8]byte
+	if err := readFullAt(m.r, h[:], off); err != nil {
+		return nil, fmt.Errorf("can't read memo block %d: %s", block, err)
+	}
+
+	if m.fpt {
+		typ, n := binary.BigEndian.Uint32(h[:4]), binary.BigEndian.Uint32(h[4:])
+		if max > 0 && int64(n) > int64(max) {
+			return nil, &LimitError{Size: int(n), Limit: max}
+		}
+		buf := make([]byte, n)
+		if err := readFullAt(m.r, buf, off+8); err != nil {
+			return nil, fmt.Errorf("can't read memo block %d: %s", block, err)
+		}
+		if typ == 1 {
+			return string(buf), nil
+		}
+		return buf, nil
+	}
+
+	if bytes.Equal(h[:4], []byte{0xFF, 0xFF, 0x08, 0x00}) {
+		n := binary.LittleEndian.Uint32(h[4:])
+		if n < 8 {
+			return nil, fmt.Errorf("memo block %d has an invalid length %d", block, n)
+		} else if max > 0 && int64(n-8) > int64(max) {
+			return nil, &LimitError{Size: int(n - 8), Limit: max}
+		}
+		buf := make([]byte, n-8)
+		if err := readFullAt(m.r, buf, off+8); err != nil {
+			return nil, fmt.Errorf("can't read memo block %d: %s", block, err)
+		}
+		return string(buf), nil
+	}
+
+	// dBase III: no length is stored, so read block by block until the
+	// terminator turns up.
+	var text []byte
+	buf := make([]byte, m.blockSize)
+	for {
+		n, err := m.r.ReadAt(buf, off)
+		end := bytes.IndexByte(buf[:n], 0x1A)
+		if end < 0 {
+			end = n
+		}
+		text = append(text, buf[:end]...)
+		if max > 0 && len(text) > max {
+			return nil, &LimitError{Size: len(text), Limit: max}
+		}
+		if end < n || err == io.EOF {
+			return string(text), nil
+		} else if err != nil {
+			return nil, fmt.Errorf("can't read memo block %d: %s", block, err)
+		}
+		off += int64(n)
+	}
+}
+
+// length returns the number of bytes the memo at block takes up, its header
+// or terminator included.
+func (m *memoFile) length(block int64) (int64, error) {
+	var h [8]byte
+	if err := readFullAt(m.r, h[:], block*m.blockSize); err != nil {
+		return 0, fmt.Errorf("can't read memo block %d: %s", block, err)
+	}
+	if m.fpt {
+		return 8 + int64(binary.BigEndian.Uint32(h[4:])), nil
+	} else if bytes.Equal(h[:4], []byte{0xFF, 0xFF, 0x08, 0x00}) {
+		return int64(binary.LittleEndian.Uint32(h[4:])), nil
+	}
+	text, err := m.read(block, 0)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(text.(string))) + 1, nil
+}
+
+// nextBlock returns the number of the memo file's first free block, kept in
+// its header.
+func (m *memoFile) nextBlock() (int64, error) {
+	var h [4]byte
+	if err := readFullAt(m.r, h[:], 0); err != nil {
+		return 0, fmt.Errorf("can't read memo file header: %s", err)
+	}
+	if m.fpt {
+		return int64(binary.BigEndian.Uint32(h[:])), nil
+	}
+	return int64(binary.LittleEndian.Uint32(h[:])), nil
+}
+
+// putNextBlock encodes the number of the first free block as stored in the
+// header.
+func (m *memoFile) putNextBlock(block int64) []byte {
+	h := make([]byte, 4)
+	if m.fpt {
+		binary.BigEndian.PutUint32(h, uint32(block))
+	} else {
+		binary.LittleEndian.PutUint32(h, uint32(block))
+	}
+	return h
+}
+
+// encode renders memo v as stored, padded to a whole number of blocks.
+func (m *memoFile) encode(v interface{}) ([]byte, error) {
+	var data []byte
+	typ := uint32(1) // text
+	switch v := v.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		if m.smt {
+			return nil, fmt.Errorf("SMT memos can't be written")
+		}
+		if !m.fpt {
+			return nil, fmt.Errorf("only FoxPro memo files hold binary memos")
+		}
+		data, typ = v, 2 // object
+	default:
+		return nil, fmt.Errorf("can't store a %T in a memo field", v)
+	}
+	var buf []byte
+	switch {
+	case m.fpt:
+		buf = make([]byte, 8, 8+len(data))
+		binary.BigEndian.PutUint32(buf, typ)
+		binary.BigEndian.PutUint32(buf[4:], uint32(len(data)))
+		buf = append(buf, data...)
+	case m.dbase3:
+		if bytes.IndexByte(data, 0x1A) >= 0 {
+			return nil, fmt.Errorf("dBase III memos can't contain 0x1A")
+		}
+		buf = append(append(buf, data...), 0x1A, 0x1A)
+	default:
+		buf = []byte{0xFF, 0xFF, 0x08, 0x00, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(buf[4:], uint32(8+len(data)))
+		buf = append(buf, data...)
+	}
+	if n := int64(len(buf)) % m.blockSize; n > 0 {
+		buf = append(buf, make([]byte, m.blockSize-n)...)
+	}
+	return buf, nil
+}
+
+// memoBlock decodes the raw contents of a memo field: a block number stored
+// as ASCII digits, or as a little-endian uint32 in 4-byte Visual FoxPro
+// fields.  A blank pointer is block 0, which means the memo is empty.
+func memoBlock(raw []byte) (int64, error) {
+	if len(raw) == 4 {
+		return int64(binary.LittleEndian.Uint32(raw)), nil
+	}
+	s := string(bytes.TrimSpace(raw))
+	if s == "" {
+		return 0, nil
+	}
+	block, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad memo block number %q", s)
+	}
+	return block, nil
+}
+
+// smtMemo reads the memo an SMT memo field points to.  Character memos,
+// of type 1, are returned as strings, and other Clipper values as the bytes
+// they are stored as.
+func (m *memoFile) smtMemo(raw []byte, max int) (interface{}, error) {
+	if len(raw) != 10 {
+		return nil, fmt.Errorf("SMT memo fields must be 10 bytes wide, not %d", len(raw))
+	}
+	typ := binary.LittleEndian.Uint16(raw)
+	n := binary.LittleEndian.Uint32(raw[2:])
+	block := int64(binary.LittleEndian.Uint32(raw[6:]))
+	if block == 0 || n == 0 || bytes.Count(raw, []byte{' '}) == len(raw) {
+		return "", nil
+	} else if max > 0 && int64(n) > int64(max) {
+		return nil, &LimitError{Size: int(n), Limit: max}
+	}
+	buf := make([]byte, n)
+	if err := readFullAt(m.r, buf, block*m.blockSize); err != nil {
+		return nil, fmt.Errorf("can't read memo block %d: %s", block, err)
+	}
+	if typ == 1 {
+		return string(buf), nil
+	}
+	return buf, nil
+}
+
+// readMemo resolves the raw contents of a memo field.
+func (t *Table) readMemo(raw []byte, max int) (interface{}, error) {
+	if t.memo != nil && t.memo.smt {
+		return t.memo.smtMemo(raw, max)
+	}
+	block, err := memoBlock(raw)
+	if err != nil || block == 0 {
+		return "", err
+	}
+	if t.memo == nil {
+		return nil, fmt.Errorf("table has memo fields but no memo file was supplied, see WithMemo")
+	}
+	return t.memo.read(block, max)
+}
+
+// isMemo reports whether the field's contents live in the memo file: memo
+// fields, and FoxPro general (OLE object) and picture fields.
+func (f Field) isMemo() bool {
+	return f.Type == 'M' || f.Type == 'G' || f.Type == 'P'
+}
+
+// hasMemo reports whether any of the table's fields are memo fields.
+func (t *Table) hasMemo() bool {
+	for _, f := range t.fields {
+		if f.isMemo() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memoTable builds a table of the given version with an ID and a memo field.
+func memoTable(version byte, pointers ...string) []byte {
+	var recs []string
+	for i, p := range pointers {
+		recs = append(recs, " "+string('1'+byte(i))+p)
+	}
+	data := buildTable([]Field{mustField("ID", 'N', 1, 0), mustField("NOTES", 'M', 10, 0)}, recs...)
+	data[0] = version
+	return data
+}
+
+func TestMemoFormats(t *testing.T) {
+	long := strings.Repeat("x", 600)
+
+	dbt3 := make([]byte, 4*512)
+	dbt3[16] = 0x03
+	copy(dbt3[512:], "hello world\x1A\x1A")
+	copy(dbt3[1024:], long+"\x1A")
+
+	dbt4 := make([]byte, 2*512)
+	binary.LittleEndian.PutUint16(dbt4[20:], 512)
+	copy(dbt4[512:], []byte{0xFF, 0xFF, 0x08, 0x00, 8 + 5, 0, 0, 0})
+	copy(dbt4[520:], "hello")
+
+	fpt := make([]byte, 512+64)
+	binary.BigEndian.PutUint16(fpt[6:], 64)
+	copy(fpt[512:], []byte{0, 0, 0, 1, 0, 0, 0, 5})
+	copy(fpt[520:], "hello")
+
+	smt := make([]byte, 2*32)
+	binary.LittleEndian.PutUint16(smt[4:], 32)
+	copy(smt[32:], "hello")
+
+	for _, tc := range []struct {
+		name     string
+		version  byte
+		memo     []byte
+		pointers []string
+		expected []string
+	}{
+		{"dBase III", 0x83, dbt3, []string{"         1", "         2", "          "}, []string{"hello world", long, ""}},
+		{"dBase IV", 0x8B, dbt4, []string{"         1"}, []string{"hello"}},
+		{"FoxPro", 0xF5, fpt, []string{"         8"}, []string{"hello"}},
+		{"SMT", 0xE5, smt, []string{"\x01\x00\x05\x00\x00\x00\x01\x00\x00\x00", "          "}, []string{"hello", ""}},
+	} {
+		data := memoTable(tc.version, tc.pointers...)
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), WithMemo(bytes.NewReader(tc.memo)))
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		for i, expected := range tc.expected {
+			rec, err := tbl.Record(i)
+			if err != nil {
+				t.Fatalf("%s: Record(%d) returned %s", tc.name, i, err)
+			}
+			if rec["NOTES"] != expected {
+				t.Fatalf("%s: expected memo %d to be %q, got %q", tc.name, i, expected, rec["NOTES"])
+			}
+		}
+	}
+}
+
+func TestMemoMissing(t *testing.T) {
+	data := memoTable(0x83, "         1", "          ")
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = tbl.Record(0); err == nil {
+		t.Fatalf("expected an error reading a memo without a memo file")
+	}
+	if rec, err := tbl.Record(1); err != nil || rec["NOTES"] != "" {
+		t.Fatalf("expected a blank memo to read as empty, got %v, %v", rec, err)
+	}
+}
+
+func TestOpenMemo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	memo := make([]byte, 2*512)
+	memo[16] = 0x03
+	copy(memo[512:], "hello\x1A")
+	ioutil.WriteFile(filepath.Join(dir, "NOTES.DBF"), memoTable(0x83, "         1"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "NOTES.DBT"), memo, 0644)
+
+	tbl, err := Open(filepath.Join(dir, "NOTES.DBF"))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer tbl.Close()
+	if rec, err := tbl.Record(0); err != nil || rec["NOTES"] != "hello" {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+}
+
+func TestMemoLimits(t *testing.T) {
+	memo := make([]byte, 3*512)
+	memo[16] = 0x03
+	copy(memo[512:], strings.Repeat("x", 600)+"\x1A")
+	data := memoTable(0x83, "         1")
+	for _, tc := range []struct {
+		limits Limits
+		field  string
+	}{
+		{Limits{Memo: 599}, "NOTES"},
+		{Limits{Value: 599}, "NOTES"},
+		{Limits{Value: 599, Fields: map[string]int{"NOTES": 600}, Record: 600}, ""},
+		{Limits{Memo: 600}, "-"},
+	} {
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), WithMemo(bytes.NewReader(memo)), WithLimits(tc.limits))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		_, err = tbl.Record(0)
+		if tc.field == "-" {
+			if err != nil {
+				t.Errorf("%+v: unexpected error %s", tc.limits, err)
+			}
+			continue
+		}
+		if e, ok := err.(*LimitError); !ok || e.Field != tc.field {
+			t.Errorf("%+v: expected a LimitError for %q, got %v", tc.limits, tc.field, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package dbf
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Names of the counters a Table reports to its Metrics.  They follow the
+// Prometheus naming conventions so they can be exported as-is.  Deleted
+// records are counted as skipped when Scan or an iterator passes over them,
+// not when Record reports ErrDeleted.  Records written are counted by a
+// Writer given WriterMetrics, and by an Editor's Update and Append.
+const (
+	MetricRecordsRead    = "dbf_records_read_total"
+	MetricBytesRead      = "dbf_bytes_read_total"
+	MetricDecodeErrors   = "dbf_decode_errors_total"
+	MetricDeletedSkipped = "dbf_deleted_records_skipped_total"
+	MetricRecordsWritten = "dbf_records_written_total"
+)
+
+// Metrics receives running counts from a Table, for services that want to
+// expose throughput and error rates.  Add must be safe for concurrent use.
+type Metrics interface {
+	Add(name string, delta int64)
+}
+
+// WithMetrics makes the Table report its counters to m.
+func WithMetrics(m Metrics) Option {
+	return func(t *Table) {
+		t.metrics = m
+	}
+}
+
+// WriterMetrics makes the Writer report the records it writes to m.
+func WriterMetrics(m Metrics) WriterOption {
+	return func(c *writerConfig) {
+		c.metrics = m
+	}
+}
+
+func (t *Table) count(name string, delta int64) {
+	if t.metrics != nil {
+		t.metrics.Add(name, delta)
+	}
+}
+
+// Counters is an in-memory Metrics which renders its totals in the
+// Prometheus text exposition format, so it can be mounted directly as a
+// /metrics handler.  The zero value is ready to use.
+type Counters struct {
+	sync.Mutex
+	m map[string]int64
+}
+
+func (c *Counters) Add(name string, delta int64) {
+	c.Lock()
+	defer c.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]int64)
+	}
+	c.m[name] += delta
+}
+
+// Get returns the current total for name.
+func (c *Counters) Get(name string) int64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.m[name]
+}
+
+// WriteTo writes every counter to w in the Prometheus text format.
+func (c *Counters) WriteTo(w io.Writer) (int64, error) {
+	c.Lock()
+	names := make([]string, 0, len(c.m))
+	for name := range c.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	values := make([]int64, len(names))
+	for i, name := range names {
+		values[i] = c.m[name]
+	}
+	c.Unlock()
+
+	var total int64
+	for i, name := range names {
+		n, err := fmt.Fprintf(w, "# TYPE %s counter\n%s %d\n", name, name, values[i])
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (c *Counters) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.WriteTo(w)
+}
@@ -0,0 +1,58 @@
+package dbf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	var c Counters
+	r, err := NewReader(bytes.NewReader(testData), WithMetrics(&c))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r.Read(0)
+	r.Read(0)
+	if n := c.Get(MetricRecordsRead); n != 2 {
+		t.Errorf("wrong %s: got %d, expected 2", MetricRecordsRead, n)
+	}
+	if n := c.Get(MetricBytesRead); n != 2*85 {
+		t.Errorf("wrong %s: got %d, expected %d", MetricBytesRead, n, 2*85)
+	}
+
+	var buf bytes.Buffer
+	c.WriteTo(&buf)
+	if !strings.Contains(buf.String(), "# TYPE dbf_records_read_total counter\ndbf_records_read_total 2\n") {
+		t.Errorf("unexpected exposition output:\n%s", buf.String())
+	}
+}
+
+func TestMetricsDeletedAndWritten(t *testing.T) {
+	var c Counters
+	tbl, err := OpenTable(bytes.NewReader(scanTable), int64(len(scanTable)), WithMetrics(&c))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = tbl.Record(1); err != ErrDeleted {
+		t.Fatalf("expected record 1 to be deleted, got %v", err)
+	}
+	if n := c.Get(MetricDeletedSkipped); n != 0 {
+		t.Errorf("a random access read was counted as skipped: %d", n)
+	}
+	tbl.Scan(func(int, Record) error { return nil })
+	if n := c.Get(MetricDeletedSkipped); n != 1 {
+		t.Errorf("wrong %s after a scan: got %d, expected 1", MetricDeletedSkipped, n)
+	}
+
+	var ws writeSeeker
+	w, err := NewWriter(&ws, []Field{mustField("ID", 'N', 3, 0)}, WriterMetrics(&c))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	w.Write(Record{"ID": 1})
+	w.Write(Record{"ID": 2})
+	if n := c.Get(MetricRecordsWritten); n != 2 {
+		t.Errorf("wrong %s: got %d, expected 2", MetricRecordsWritten, n)
+	}
+}
@@ -0,0 +1,109 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A dBase III .ndx file is a B-tree of 512-byte pages, page 0 being the
+// header:
+//
+//	0   root page (uint32)
+//	12  key length (uint16)
+//	16  key type: 0 for character keys, 1 for numeric and date keys
+//	18  length of each entry in a page, the key padded to 4 bytes plus 8
+//	23  unique flag
+//	24  key expression, NUL-terminated
+//
+// A page holds its number of keys (uint32), then entries of a child page
+// (0 in a leaf), a record number and the key.  An interior page has one
+// more child than keys, in an entry of its own.  Numeric keys are
+// little-endian float64s, dates as Julian day numbers.  Integers are
+// little-endian.
+const ndxPage = 512
+
+func (t *Table) openNDX(r io.ReaderAt, tag string) (*Index, error) {
+	var h [ndxPage]byte
+	if err := readFullAt(r, h[:], 0); err != nil {
+		return nil, fmt.Errorf("can't read header: %s", err)
+	}
+	keyLen := int(binary.LittleEndian.Uint16(h[12:]))
+	entry := int(binary.LittleEndian.Uint16(h[18:]))
+	if keyLen == 0 || entry < keyLen+8 || 4+entry > ndxPage {
+		return nil, fmt.Errorf("key length %d and entry length %d don't fit a page", keyLen, entry)
+	}
+	x := &Index{
+		t:       t,
+		tag:     tag,
+		expr:    cString(h[24:]),
+		keyLen:  keyLen,
+		unique:  h[23] != 0,
+		root:    int64(binary.LittleEndian.Uint32(h[0:])),
+		compare: bytes.Compare,
+	}
+	x.encode = x.charKey
+	if binary.LittleEndian.Uint16(h[16:]) != 0 {
+		if keyLen != 8 {
+			return nil, fmt.Errorf("numeric keys are %d bytes long instead of 8", keyLen)
+		}
+		x.numeric = true
+		x.compare = compareFloatLE
+		x.encode = func(v interface{}) ([]byte, error) {
+			f, err := x.numericKey(v)
+			if err != nil {
+				return nil, err
+			}
+			k := make([]byte, 8)
+			binary.LittleEndian.PutUint64(k, math.Float64bits(f))
+			return k, nil
+		}
+	}
+	x.node = func(page int64) (*indexNode, error) {
+		return readNDXPage(r, page, keyLen, entry)
+	}
+	return x, nil
+}
+
+func readNDXPage(r io.ReaderAt, page int64, keyLen, entry int) (*indexNode, error) {
+	if page <= 0 {
+		return nil, fmt.Errorf("index points to page %d", page)
+	}
+	var buf [ndxPage]byte
+	if err := readFullAt(r, buf[:], page*ndxPage); err != nil {
+		return nil, fmt.Errorf("can't read page %d: %s", page, err)
+	}
+	nkeys := int(binary.LittleEndian.Uint32(buf[0:]))
+	if 4+nkeys*entry > ndxPage {
+		return nil, fmt.Errorf("page %d claims %d keys, which don't fit", page, nkeys)
+	}
+	n := &indexNode{}
+	for i := 0; i < nkeys; i++ {
+		e := buf[4+i*entry:]
+		n.keys = append(n.keys, append([]byte(nil), e[8:8+keyLen]...))
+		if child := binary.LittleEndian.Uint32(e[0:]); child != 0 {
+			n.children = append(n.children, int64(child))
+		} else {
+			n.recnos = append(n.recnos, int(binary.LittleEndian.Uint32(e[4:])))
+		}
+	}
+	if last := 4 + nkeys*entry; last+4 <= ndxPage {
+		if child := binary.LittleEndian.Uint32(buf[last:]); child != 0 && (n.children != nil || nkeys == 0) {
+			n.children = append(n.children, int64(child))
+		}
+	}
+	if n.children != nil && n.recnos != nil {
+		return nil, fmt.Errorf("page %d mixes child pages and records", page)
+	}
+	return n, nil
+}
+
+// cString returns the NUL-terminated string at the start of b.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(bytes.TrimSpace(b))
+}
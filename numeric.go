@@ -0,0 +1,45 @@
+package dbf
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// WithNumericStrings makes the table return the values of numeric fields as
+// the text stored in them, stripped of padding, instead of as an int64 or a
+// float64, for callers that need exact decimals such as amounts of money.
+// Values are still checked to be numbers, and blank fields come back as "".
+func WithNumericStrings() Option {
+	return func(t *Table) {
+		t.numericStrings = true
+	}
+}
+
+// WithBigNumbers makes the table return whole numbers too large for an int64,
+// which a 20-digit N field can hold, as a *big.Int instead of failing to
+// decode them.  Writers and Editor.Update accept a *big.Int too.
+func WithBigNumbers() Option {
+	return func(t *Table) {
+		t.bigNumbers = true
+	}
+}
+
+// number applies the table's options for numeric fields to v, decoded from
+// raw with the error err.
+func (t *Table) number(name string, raw []byte, v interface{}, err error) (interface{}, error) {
+	if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrRange && t.bigNumbers {
+		if n, ok := new(big.Int).SetString(strings.TrimSpace(string(raw)), 10); ok {
+			v, err = n, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if epoch, ok := t.epochs[name]; ok {
+		return dayNumber(epoch, raw, v), nil
+	} else if t.numericStrings {
+		return strings.TrimSpace(string(raw)), nil
+	}
+	return v, nil
+}
@@ -0,0 +1,65 @@
+package dbf
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestNumericDecoding(t *testing.T) {
+	fields := []Field{mustField("QTY", 'N', 6, 0), mustField("PRICE", 'N', 8, 2), mustField("RATE", 'F', 6, 0)}
+	data := buildTable(fields, "     12  123.45   0.5", "   12.5    0.10      ")
+
+	r, err := NewReaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := []Record{
+		{"QTY": int64(12), "PRICE": 123.45, "RATE": 0.5},
+		{"QTY": 12.5, "PRICE": 0.1, "RATE": float64(0)},
+	}
+	for i, want := range expected {
+		if rec, err := r.Read(uint16(i)); err != nil || !reflect.DeepEqual(rec, want) {
+			t.Errorf("Read(%d) returned %#v, %v, expected %#v", i, rec, err, want)
+		}
+	}
+
+	if r, err = NewReaderFromBytes(data, WithNumericStrings()); err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := Record{"QTY": "12.5", "PRICE": "0.10", "RATE": ""}
+	if rec, err := r.Read(1); err != nil || !reflect.DeepEqual(rec, want) {
+		t.Errorf("Read(1) returned %#v, %v, expected %#v", rec, err, want)
+	}
+}
+
+func TestBigNumbers(t *testing.T) {
+	fields := []Field{mustField("SERIAL", 'N', 20, 0)}
+	data := buildTable(fields, " 12345678901234567890", " -9223372036854775808")
+
+	r, err := NewReaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = r.Read(0); err == nil {
+		t.Errorf("expected an error for a number that overflows an int64")
+	}
+	if rec, err := r.Read(1); err != nil || rec["SERIAL"] != int64(math.MinInt64) {
+		t.Errorf("Read(1) returned %v, %v", rec, err)
+	}
+
+	if r, err = NewReaderFromBytes(data, WithBigNumbers()); err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec, err := r.Read(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if n, ok := rec["SERIAL"].(*big.Int); !ok || n.String() != "12345678901234567890" {
+		t.Errorf("Read(0) returned %#v", rec["SERIAL"])
+	}
+	if b, err := encodeField(fields[0], rec["SERIAL"]); err != nil || string(b) != "12345678901234567890" {
+		t.Errorf("encoding a *big.Int returned %q, %v", b, err)
+	}
+}
@@ -0,0 +1,48 @@
+package dbf
+
+import (
+	"fmt"
+	"time"
+)
+
+// Provenance identifies where a record came from, so that a row at the end
+// of a pipeline can be traced back to the bytes it was read from.
+type Provenance struct {
+	Source  string    `json:"source"`   // path of the table, or the name given by WithSourceName
+	Record  int       `json:"record"`   // record number, counting from 0
+	Offset  int64     `json:"offset"`   // position of the record in the file, in bytes
+	ModDate time.Time `json:"mod_date"` // the table's modification date, from its header
+}
+
+func (p Provenance) String() string {
+	return fmt.Sprintf("%s record %d at offset %d, modified %s", p.Source, p.Record, p.Offset, p.ModDate.Format("2006-01-02"))
+}
+
+// WithProvenance makes every record read from the table carry its
+// Provenance under the given column, which FieldNames lists after the
+// stored fields, so that it flows through Unions and into exports such as
+// ToCSV.  Computed columns can use it.  Fingerprint and ContentHash leave
+// it out, so a copy of a table hashes the same as the original.
+func WithProvenance(column string) Option {
+	return func(t *Table) {
+		t.provenance = column
+	}
+}
+
+// WithSourceName names the table in the Provenance of its records.  Open
+// names tables after their path; others have no name unless given one.
+func WithSourceName(name string) Option {
+	return func(t *Table) {
+		t.source = name
+	}
+}
+
+// Provenance returns the provenance of record i.
+func (t *Table) Provenance(i int) Provenance {
+	return Provenance{
+		Source:  t.source,
+		Record:  i,
+		Offset:  t.recordOffset(i),
+		ModDate: time.Date(t.year, time.Month(t.month), t.day, 0, 0, 0, 0, time.UTC),
+	}
+}
@@ -0,0 +1,85 @@
+package dbf
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvenance(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	data := buildTable(fields, "   1alpha", "*  2bravo", "   3charl")
+	headerlen := int64(32 + 32*len(fields) + 1)
+
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(data)
+	f.Close()
+	tbl, err := Open(f.Name(), WithProvenance("_ORIGIN"))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer tbl.Close()
+
+	rec, err := tbl.Record(2)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	want := Provenance{f.Name(), 2, headerlen + 2*9, time.Date(2011, 7, 26, 0, 0, 0, 0, time.UTC)}
+	if rec["_ORIGIN"] != want {
+		t.Errorf("record 2 has provenance %v, expected %v", rec["_ORIGIN"], want)
+	}
+	if names := tbl.FieldNames(); len(names) != 3 || names[2] != "_ORIGIN" {
+		t.Errorf("FieldNames() returned %v", names)
+	}
+	var buf bytes.Buffer
+	if err = tbl.ToCSV(&buf); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !strings.Contains(buf.String(), "3,charl,\""+want.String()+"\"\n") {
+		t.Errorf("ToCSV wrote %q", buf.String())
+	}
+
+	// Provenance doesn't change what the table hashes to.
+	plain, _ := NewReaderFromBytes(data)
+	a, _ := plain.Fingerprint()
+	b, _ := tbl.Fingerprint()
+	if a != b {
+		t.Errorf("provenance changed the fingerprint")
+	}
+
+	var u Union
+	u.Add("january", tbl)
+	u.Add("february", plain.Table)
+	it := u.Iterate()
+	var got []Provenance
+	for {
+		if _, err := it.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("%s", err)
+		}
+		got = append(got, it.Provenance())
+	}
+	if p := it.Provenance(); p != (Provenance{}) {
+		t.Errorf("provenance after io.EOF is %v", p)
+	}
+	if p := new(Union).Iterate().Provenance(); p != (Provenance{}) {
+		t.Errorf("provenance of an empty union is %v", p)
+	}
+	if len(got) != 4 || got[1].Source != "january" || got[1].Record != 2 || got[2].Source != "february" || got[2].Offset != headerlen {
+		t.Errorf("union provenance is %v", got)
+	}
+
+	named, _ := NewReaderFromBytes(data, WithProvenance("_ORIGIN"), WithSourceName("upload.dbf"))
+	if rec, _ = named.Record(0); rec["_ORIGIN"].(Provenance).Source != "upload.dbf" {
+		t.Errorf("record 0 has provenance %v", rec["_ORIGIN"])
+	}
+}
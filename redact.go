@@ -0,0 +1,96 @@
+package dbf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A Redactor replaces the value of a field, to hide personal data.
+type Redactor func(v interface{}) interface{}
+
+type redaction struct {
+	name   string
+	redact Redactor
+}
+
+// WithRedaction makes the table pass field name of every record through
+// redact as it's decoded, so that nothing read from the table, including
+// exports, sees the original value.  Redactions run before computed columns,
+// which therefore only ever see redacted data.  A nil redact, as returned by
+// Substitute with no choices, makes OpenTable fail.
+func WithRedaction(name string, redact Redactor) Option {
+	return func(t *Table) {
+		if redact == nil {
+			if t.optErr == nil {
+				t.optErr = fmt.Errorf("no redactor for field %s", name)
+			}
+			return
+		}
+		t.redactions = append(t.redactions, redaction{name, redact})
+	}
+}
+
+func (t *Table) redact(rec Record) {
+	for _, r := range t.redactions {
+		if v, ok := rec[r.name]; ok {
+			rec[r.name] = r.redact(v)
+		}
+	}
+}
+
+// Mask replaces all but the last keep characters of a value with '*'.
+func Mask(keep int) Redactor {
+	return func(v interface{}) interface{} {
+		s := normalize(v)
+		n := utf8.RuneCountInString(s) - keep
+		if n <= 0 {
+			return s
+		}
+		i := 0
+		for j := 0; j < n; j++ {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+		return strings.Repeat("*", n) + s[i:]
+	}
+}
+
+// Hash replaces a value with the hex SHA-256 digest of salt followed by the
+// value, cut to length characters (0 keeps all 64).  Equal values still hash
+// equally, so the field can be joined on without being readable.
+func Hash(salt string, length int) Redactor {
+	return func(v interface{}) interface{} {
+		sum := sha256.Sum256([]byte(salt + normalize(v)))
+		s := hex.EncodeToString(sum[:])
+		if length > 0 && length < len(s) {
+			s = s[:length]
+		}
+		return s
+	}
+}
+
+// Constant replaces every value with c.
+func Constant(c interface{}) Redactor {
+	return func(interface{}) interface{} {
+		return c
+	}
+}
+
+// Substitute replaces a value with one of choices, such as a list of fake
+// names.  The choice is derived from the original value, so the same value
+// is always replaced the same way.  With no choices there is nothing to
+// substitute, and Substitute returns nil, which WithRedaction rejects.
+func Substitute(choices ...string) Redactor {
+	if len(choices) == 0 {
+		return nil
+	}
+	return func(v interface{}) interface{} {
+		h := fnv.New32a()
+		h.Write([]byte(normalize(v)))
+		return choices[h.Sum32()%uint32(len(choices))]
+	}
+}
@@ -0,0 +1,45 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedactors(t *testing.T) {
+	if v := Mask(4)("4111111111111111"); v != "************1111" {
+		t.Errorf("Mask(4) returned %v", v)
+	}
+	if v := Mask(4)(42); v != "42" {
+		t.Errorf("Mask(4) of a short value returned %v", v)
+	}
+	if v := Hash("salt", 8)("alice"); v != Hash("salt", 8)("alice") || len(v.(string)) != 8 {
+		t.Errorf("Hash(salt, 8) returned %v", v)
+	}
+	if Hash("salt", 0)("alice") == Hash("pepper", 0)("alice") {
+		t.Errorf("Hash ignored its salt")
+	}
+	if v := Constant("X")("anything"); v != "X" {
+		t.Errorf("Constant returned %v", v)
+	}
+	sub := Substitute("Smith", "Jones", "Brown")
+	if sub("alice") != sub("alice") {
+		t.Errorf("Substitute isn't deterministic")
+	}
+	if _, err := NewReader(bytes.NewReader(testData), WithRedaction("Name", Substitute())); err == nil {
+		t.Errorf("expected an error for Substitute with no choices")
+	}
+}
+
+func TestWithRedaction(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(testData), WithRedaction("Name", Constant("REDACTED")))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec, err := r.Read(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec["Name"] != "REDACTED" || rec["OBJECTID"] != int64(1) {
+		t.Fatalf("wrong redacted record: %v", rec)
+	}
+}
@@ -0,0 +1,85 @@
+package dbf
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A FieldType teaches the package a field type it doesn't know natively,
+// such as a vendor-specific extension.
+type FieldType struct {
+	// Decode converts the raw bytes of a field to its Go value.
+	Decode func(f Field, raw []byte) (interface{}, error)
+
+	// Encode renders a value as exactly f.Len bytes.  It may be nil, in
+	// which case fields of the type can be read but not written.
+	Encode func(f Field, v interface{}) ([]byte, error)
+}
+
+type fieldTypeKey struct {
+	version, typ byte
+}
+
+var registry struct {
+	sync.RWMutex
+	types map[fieldTypeKey]FieldType
+}
+
+// RegisterFieldType makes type code typ acceptable in tables of the given
+// file version, or of any version if version is 0, decoded and encoded by
+// ft.  A registration for a specific version takes precedence over one for
+// any version, and both take precedence over the built-in types.  It is
+// meant to be called from an init function.
+func RegisterFieldType(version, typ byte, ft FieldType) {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.types == nil {
+		registry.types = make(map[fieldTypeKey]FieldType)
+	}
+	registry.types[fieldTypeKey{version, typ}] = ft
+}
+
+// registeredType looks up the registered handler for typ in tables of the
+// given version.
+func registeredType(version, typ byte) (FieldType, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if ft, ok := registry.types[fieldTypeKey{version, typ}]; ok {
+		return ft, true
+	}
+	ft, ok := registry.types[fieldTypeKey{0, typ}]
+	return ft, ok
+}
+
+// decode converts the raw bytes of field f of a table of the given version,
+// preferring a registered type.
+func decode(version byte, f Field, raw []byte) (interface{}, error) {
+	if ft, ok := registeredType(version, f.Type); ok {
+		return ft.Decode(f, raw)
+	} else if _, ok := level7Len[f.Type]; ok && isLevel7(version) {
+		return decodeLevel7(f, raw)
+	} else if _, ok := vfpLen[f.Type]; ok && isVFP(version) {
+		return decodeVFP(f, raw)
+	}
+	return decodeField(f, raw)
+}
+
+// encode renders v for field f of a table of the given version, preferring
+// a registered type.
+func encode(version byte, f Field, v interface{}) ([]byte, error) {
+	ft, ok := registeredType(version, f.Type)
+	if _, level7 := level7Len[f.Type]; !ok && level7 && isLevel7(version) {
+		return encodeLevel7(f, v)
+	} else if _, vfp := vfpLen[f.Type]; !ok && vfp && isVFP(version) {
+		return encodeVFP(f, v)
+	} else if !ok {
+		return encodeField(f, v)
+	} else if ft.Encode == nil {
+		return nil, fmt.Errorf("field type '%c' can't be written", f.Type)
+	}
+	b, err := ft.Encode(f, v)
+	if err == nil && len(b) != int(f.Len) {
+		err = fmt.Errorf("encoded value is %d bytes long, expected %d", len(b), f.Len)
+	}
+	return b, err
+}
@@ -0,0 +1,52 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestRegisterFieldType(t *testing.T) {
+	// 'X': an integer stored in hexadecimal, as some vendor extension might.
+	RegisterFieldType(0x03, 'X', FieldType{
+		Decode: func(f Field, raw []byte) (interface{}, error) {
+			return strconv.ParseInt(string(bytes.TrimSpace(raw)), 16, 64)
+		},
+		Encode: func(f Field, v interface{}) ([]byte, error) {
+			return []byte(fmt.Sprintf("%*x", f.Len, v)), nil
+		},
+	})
+	defer func() {
+		registry.Lock()
+		delete(registry.types, fieldTypeKey{0x03, 'X'})
+		registry.Unlock()
+	}()
+
+	fields := []Field{mustField("ID", 'X', 4, 0)}
+	if issues := Lint(fields, 0x03); len(issues) > 0 {
+		t.Fatalf("unexpected lint issues: %v", issues)
+	}
+	var ws writeSeeker
+	w, err := NewWriter(&ws, fields)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"ID": int64(255)}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	w.Close()
+
+	tbl, err := OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec, err := tbl.Record(0); err != nil || rec["ID"] != int64(255) {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+
+	ws.buf[0] = 0x83 // the type is only registered for version 0x03
+	if _, err = OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf))); err == nil {
+		t.Fatalf("expected an error for an unregistered version")
+	}
+}
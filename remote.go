@@ -0,0 +1,194 @@
+package dbf
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HTTPReaderAt implements io.ReaderAt on top of HTTP Range requests, so a
+// table hosted on a web server or in object storage can be opened and
+// sampled without downloading the whole file:
+//
+//	h, err := dbf.NewHTTPReaderAt("https://example.com/roads.dbf")
+//	dbr, err := dbf.NewReaderAt(h, h.Size())
+//
+// Data is fetched in fixed-size blocks, and the most recently used blocks are
+// kept in memory so that neighbouring reads don't hit the network again.
+type HTTPReaderAt struct {
+	url       string
+	client    *http.Client
+	size      int64
+	blockSize int64
+	maxBlocks int
+
+	sync.Mutex
+	blocks map[int64][]byte
+	order  []int64 // cached block numbers, least recently used first
+}
+
+const (
+	defaultBlockSize = 64 * 1024
+	defaultMaxBlocks = 64
+)
+
+// NewHTTPReaderAt prepares url for ranged reads using http.DefaultClient.
+// The size of the remote file is learned with a one-byte Range request, which
+// also verifies that the server honours ranges at all.  A server that answers
+// 416 Range Not Satisfiable is taken to hold an empty file.
+func NewHTTPReaderAt(url string) (*HTTPReaderAt, error) {
+	return NewHTTPReaderAtClient(http.DefaultClient, url, defaultBlockSize, defaultMaxBlocks)
+}
+
+// NewHTTPReaderAtClient is like NewHTTPReaderAt, but lets the caller supply
+// the http.Client (for auth, timeouts, ...), the block size in bytes, and the
+// number of blocks to cache.
+func NewHTTPReaderAtClient(client *http.Client, url string, blockSize int64, maxBlocks int) (*HTTPReaderAt, error) {
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+	if maxBlocks <= 0 {
+		maxBlocks = defaultMaxBlocks
+	}
+	h := &HTTPReaderAt{
+		url:       url,
+		client:    client,
+		blockSize: blockSize,
+		maxBlocks: maxBlocks,
+		blocks:    make(map[int64][]byte),
+	}
+	resp, err := h.do(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return h, nil
+	}
+	if err = h.check(resp); err != nil {
+		return nil, err
+	}
+	if h.size, err = contentRangeSize(resp.Header.Get("Content-Range")); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Size returns the length of the remote file in bytes.
+func (h *HTTPReaderAt) Size() int64 {
+	return h.size
+}
+
+func (h *HTTPReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	for n < len(p) {
+		if off >= h.size {
+			return n, io.EOF
+		}
+		block, err := h.block(off / h.blockSize)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], block[off%h.blockSize:])
+		n += c
+		off += int64(c)
+	}
+	return n, nil
+}
+
+func (h *HTTPReaderAt) block(i int64) ([]byte, error) {
+	h.Lock()
+	b, ok := h.blocks[i]
+	if ok {
+		h.touch(i)
+	}
+	h.Unlock()
+	if ok {
+		return b, nil
+	}
+
+	start := i * h.blockSize
+	end := start + h.blockSize - 1
+	if end >= h.size {
+		end = h.size - 1
+	}
+	resp, err := h.get(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b = make([]byte, end-start+1)
+	if _, err = io.ReadFull(resp.Body, b); err != nil {
+		return nil, err
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	if _, ok := h.blocks[i]; !ok {
+		if len(h.order) >= h.maxBlocks {
+			delete(h.blocks, h.order[0])
+			h.order = h.order[1:]
+		}
+		h.blocks[i] = b
+		h.order = append(h.order, i)
+	}
+	return b, nil
+}
+
+// touch moves block i to the back of h.order, as the most recently used.
+// h must be locked.
+func (h *HTTPReaderAt) touch(i int64) {
+	for j, k := range h.order {
+		if k == i {
+			copy(h.order[j:], h.order[j+1:])
+			h.order[len(h.order)-1] = i
+			return
+		}
+	}
+}
+
+func (h *HTTPReaderAt) get(start, end int64) (*http.Response, error) {
+	resp, err := h.do(start, end)
+	if err != nil {
+		return nil, err
+	}
+	if err = h.check(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (h *HTTPReaderAt) do(start, end int64) (*http.Response, error) {
+	req, err := http.NewRequest("GET", h.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	return h.client.Do(req)
+}
+
+// check closes resp and returns an error unless it is a 206 Partial Content.
+func (h *HTTPReaderAt) check(resp *http.Response) error {
+	if resp.StatusCode != http.StatusPartialContent {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		return fmt.Errorf("range request for %s returned %s, expected 206 Partial Content", h.url, resp.Status)
+	}
+	return nil
+}
+
+// contentRangeSize extracts the complete length from a header such as
+// "bytes 0-0/12345".
+func contentRangeSize(header string) (int64, error) {
+	i := strings.LastIndex(header, "/")
+	if i < 0 || header[i+1:] == "*" {
+		return 0, fmt.Errorf("can't determine file size from Content-Range %q", header)
+	}
+	return strconv.ParseInt(header[i+1:], 10, 64)
+}
@@ -0,0 +1,89 @@
+package dbf
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPReaderAt(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		http.ServeContent(w, req, "test.dbf", time.Time{}, bytes.NewReader(testData))
+	}))
+	defer ts.Close()
+
+	h, err := NewHTTPReaderAtClient(ts.Client(), ts.URL, 16, 4)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if h.Size() != int64(len(testData)) {
+		t.Fatalf("wrong Size(): got %d, expected %d", h.Size(), len(testData))
+	}
+	r, err := NewReaderAt(h, h.Size())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec, err := r.Read(0)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec["Name"] != "Abbotsbury" {
+		t.Fatalf("wrong Name: got %v, expected Abbotsbury", rec["Name"])
+	}
+
+	before := requests
+	buf := make([]byte, 4)
+	h.ReadAt(buf, int64(len(testData))-4)
+	if requests != before {
+		t.Fatalf("expected cached read, but made %d more requests", requests-before)
+	}
+}
+
+func TestHTTPReaderAtEviction(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		http.ServeContent(w, req, "test.dbf", time.Time{}, bytes.NewReader(testData))
+	}))
+	defer ts.Close()
+
+	h, err := NewHTTPReaderAtClient(ts.Client(), ts.URL, 16, 4)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	buf := make([]byte, 1)
+	for _, block := range []int64{0, 1, 2, 3, 0, 4} {
+		if _, err = h.ReadAt(buf, block*16); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	before := requests
+	h.ReadAt(buf, 0)
+	if requests != before {
+		t.Fatalf("expected the recently used block 0 to stay cached")
+	}
+	h.ReadAt(buf, 16)
+	if requests != before+1 {
+		t.Fatalf("expected the least recently used block 1 to be evicted")
+	}
+}
+
+func TestHTTPReaderAtEmpty(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Range", "bytes */0")
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	}))
+	defer ts.Close()
+
+	h, err := NewHTTPReaderAtClient(ts.Client(), ts.URL, 16, 4)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if h.Size() != 0 {
+		t.Fatalf("wrong Size(): got %d, expected 0", h.Size())
+	}
+}
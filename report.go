@@ -0,0 +1,38 @@
+package dbf
+
+import "io"
+
+// A Template renders reports.  Both *text/template.Template and
+// *html/template.Template implement it.
+type Template interface {
+	ExecuteTemplate(w io.Writer, name string, data interface{}) error
+}
+
+// Render executes template name of tmpl once for every record that isn't
+// deleted, in order, with the Record as data, so that fields are written
+// {{.NAME}}.  Each record is written out as soon as it's read, so reports of
+// any size can be produced; headers and footers are written by the caller
+// around the call:
+//
+//	tmpl := template.Must(template.New("").Parse(
+//		`{{define "head"}}<table>{{end}}` +
+//		`{{define "row"}}<tr><td>{{.NAME}}</td></tr>{{end}}`))
+//	tmpl.ExecuteTemplate(w, "head", nil)
+//	err := t.Render(w, tmpl, "row")
+//
+// Render stops at the first record that can't be decoded or rendered, and
+// reports it as a *RecordError.
+func (t *Table) Render(w io.Writer, tmpl Template, name string) error {
+	it := t.Iterate()
+	for {
+		rec, err := it.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = tmpl.ExecuteTemplate(w, name, rec); err != nil {
+			return &RecordError{it.RecNo(), t.recordOffset(it.RecNo()), err}
+		}
+	}
+}
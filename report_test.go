@@ -0,0 +1,39 @@
+package dbf
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"testing"
+	"text/template"
+)
+
+func TestRender(t *testing.T) {
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}, "   1alpha", "*  2bravo", "   3<b>&c")
+	r, err := NewReaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	var buf bytes.Buffer
+	text := template.Must(template.New("").Parse(`{{define "row"}}{{.ID}}: {{.NAME}}
+{{end}}`))
+	if err = r.Render(&buf, text, "row"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := "1: alpha\n3: <b>&c\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	html := htmltemplate.Must(htmltemplate.New("").Parse(`{{define "row"}}<li>{{.NAME}}</li>{{end}}`))
+	if err = r.Render(&buf, html, "row"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := "<li>alpha</li><li>&lt;b&gt;&amp;c</li>"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	if err = r.Render(&buf, text, "missing"); err == nil {
+		t.Errorf("expected an error for an undefined template")
+	}
+}
@@ -0,0 +1,128 @@
+package dbf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// A RecordError reports a record that couldn't be decoded.
+type RecordError struct {
+	Record int   // record number, counting from 0
+	Offset int64 // position of the record in the file, in bytes
+	Err    error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d at offset %d: %s", e.Record, e.Offset, e.Err)
+}
+
+// RecordErrors collects the records ReadAll couldn't decode in lenient mode.
+type RecordErrors []*RecordError
+
+func (e RecordErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%d records couldn't be decoded, the first %s", len(e), e[0])
+}
+
+// Scan calls fn for every record in the table, in order, skipping deleted
+// records.  It stops at the first record that can't be decoded, or as soon as
+// fn returns an error, and returns that error.
+func (t *Table) Scan(fn func(i int, rec Record) error) error {
+	start, n := time.Now(), 0
+	defer func() { t.logScan(start, n) }()
+	c := t.NewCursor()
+	for {
+		rec, err := c.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		n++
+		if err = fn(c.RecNo(), rec); err != nil {
+			return err
+		}
+	}
+}
+
+// ScanSkippingErrors is like Scan, except that records which can't be
+// decoded are passed over rather than ending the scan.  Each one is
+// collected and returned once the whole table has been read, so a single bad
+// record doesn't throw away an export that has been running for an hour.  An
+// error from fn still stops the scan immediately.
+func (t *Table) ScanSkippingErrors(fn func(i int, rec Record) error) ([]*RecordError, error) {
+	var failures []*RecordError
+	start, n := time.Now(), 0
+	defer func() { t.logScan(start, n) }()
+	c := t.NewCursor()
+	for {
+		rec, err := c.Next()
+		if err == io.EOF {
+			return failures, nil
+		} else if e, ok := err.(*RecordError); ok {
+			failures = append(failures, e)
+			continue
+		} else if err != nil {
+			return failures, err
+		}
+		n++
+		if err = fn(c.RecNo(), rec); err != nil {
+			return failures, err
+		}
+	}
+}
+
+// ScanDeleted calls fn, in order, for each record that is flagged as deleted
+// but hasn't been packed out of the file yet, so that removals can be
+// reviewed.  i is the record's number, as in Scan.
+func (t *Table) ScanDeleted(fn func(i int, rec Record) error) error {
+	for i := 0; i < t.nrec; i++ {
+		buf, err := t.readRaw(i)
+		var rec Record
+		if err == nil && buf[0] == '*' {
+			rec, err = t.decodeRecord(i, buf)
+		}
+		if err != nil {
+			return &RecordError{i, t.recordOffset(i), err}
+		}
+		if rec == nil {
+			continue
+		}
+		if err = fn(i, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeletedCount returns the number of records flagged as deleted.  Only the
+// flags are examined, in a single buffered pass, so it is cheap even for
+// large tables.
+func (t *Table) DeletedCount() (int, error) {
+	off := int64(t.headerlen)
+	br := bufio.NewReader(io.NewSectionReader(t.src, off, t.size-off))
+	n := 0
+	for i := 0; i < t.nrec; i++ {
+		flag, err := br.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return n, &RecordError{i, t.recordOffset(i), err}
+		}
+		if flag == '*' {
+			n++
+		}
+		if i+1 < t.nrec {
+			if _, err = io.CopyN(ioutil.Discard, br, int64(t.recordlen)-1); err != nil {
+				return n, &RecordError{i, t.recordOffset(i), err}
+			}
+		}
+	}
+	return n, nil
+}
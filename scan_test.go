@@ -0,0 +1,86 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+var scanTable = buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)},
+	"   1alpha",
+	"*  2bravo",
+	" N/Acharl",
+	"   4delta",
+)
+
+func TestScan(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(scanTable))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var seen []int
+	err = r.Scan(func(i int, rec Record) error {
+		seen = append(seen, i)
+		return nil
+	})
+	if e, ok := err.(*RecordError); !ok || e.Record != 2 || e.Offset != int64(r.headerlen)+2*9 {
+		t.Fatalf("expected a RecordError for record 2, got %v", err)
+	}
+	if len(seen) != 1 || seen[0] != 0 {
+		t.Fatalf("wrong records scanned: %v", seen)
+	}
+}
+
+func TestScanSkippingErrors(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(scanTable))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var names []string
+	failures, err := r.ScanSkippingErrors(func(i int, rec Record) error {
+		names = append(names, rec["NAME"].(string))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(names) != 2 || names[0] != "alpha" || names[1] != "delta" {
+		t.Fatalf("wrong records scanned: %v", names)
+	}
+	if len(failures) != 1 || failures[0].Record != 2 {
+		t.Fatalf("expected one failure for record 2, got %v", failures)
+	}
+}
+
+func TestScanDeleted(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(scanTable))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var deleted []Record
+	err = r.ScanDeleted(func(i int, rec Record) error {
+		if i != 1 {
+			t.Errorf("record %d isn't deleted", i)
+		}
+		deleted = append(deleted, rec)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(deleted) != 1 || deleted[0]["ID"] != int64(2) || deleted[0]["NAME"] != "bravo" {
+		t.Fatalf("wrong deleted records: %v", deleted)
+	}
+}
+
+func TestDeleted(t *testing.T) {
+	tbl, err := OpenTable(bytes.NewReader(scanTable), int64(len(scanTable)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = tbl.Record(1); err != ErrDeleted {
+		t.Fatalf("expected ErrDeleted, got %v", err)
+	}
+	if n, err := tbl.DeletedCount(); err != nil || n != 1 {
+		t.Fatalf("DeletedCount() returned %d, %v", n, err)
+	}
+}
@@ -0,0 +1,407 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewField builds a field descriptor.  Names are limited to 10 bytes, the
+// 11th being reserved for the 0x0 terminator.
+func NewField(name string, typ byte, length, decimals uint8) (Field, error) {
+	f := Field{Type: typ, Len: length, DecimalPlaces: decimals}
+	if len(name) == 0 || len(name) > len(f.Name)-1 {
+		return f, fmt.Errorf("field name %q must be between 1 and %d bytes long", name, len(f.Name)-1)
+	}
+	copy(f.Name[:], name)
+	return f, nil
+}
+
+// FieldMarshaler is implemented by types that encode themselves as the raw
+// bytes of a field, much like encoding.TextMarshaler.  Domain types such as
+// money amounts or enums can use it to control their own representation.
+type FieldMarshaler interface {
+	MarshalDBF(f Field) ([]byte, error)
+}
+
+// FieldUnmarshaler is the counterpart of FieldMarshaler: it is handed the
+// field's bytes as stored, padding included.
+type FieldUnmarshaler interface {
+	UnmarshalDBF(f Field, raw []byte) error
+}
+
+// NumericType names the narrowest Go type that can hold every value of a
+// numeric field, judging by its width and decimal places: "int16", "int32" or
+// "int64" for whole numbers, "float64" when the digits fit a float64 exactly,
+// and "decimal" when only an exact decimal type will do.  It returns "" for
+// fields that aren't numeric.  F fields are always "float64", the type they
+// decode to: they hold floating-point numbers, even where they are wider
+// than the 15 digits a float64 keeps exactly.
+func (f Field) NumericType() string {
+	switch f.Type {
+	case 'F':
+		return "float64"
+	case 'N':
+	default:
+		return ""
+	}
+	digits := int(f.Len) // worst case, a sign takes the place of one digit
+	switch {
+	case f.DecimalPlaces > 0 && digits-1 <= 15:
+		return "float64"
+	case f.DecimalPlaces > 0:
+		return "decimal"
+	case digits <= 4:
+		return "int16"
+	case digits <= 9:
+		return "int32"
+	case digits <= 18:
+		return "int64"
+	}
+	return "decimal"
+}
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	fieldMarshalerType = reflect.TypeOf((*FieldMarshaler)(nil)).Elem()
+)
+
+// SchemaFromStruct derives a table schema from the exported fields of a
+// struct.  v may be a struct value, a pointer to one, or its reflect.Type.
+// Go types map onto DBF fields as follows:
+//
+//	string           C(254)
+//	bool             L(1)
+//	int, int8, ...   N, wide enough for the type's range
+//	float32, float64 N(20,6)
+//	time.Time        D(8)
+//	decimal types    N(20,6)
+//	FieldMarshaler   C, width taken from the len tag option
+//
+// Pointers and database/sql's Null types (sql.NullString, sql.NullTime, ...)
+// map like the type they wrap.  A decimal type is any named Decimal, such as
+// github.com/shopspring/decimal's.
+//
+// Columns are named after the upper-cased Go field name.  A `dbf` struct tag
+// overrides the name and, after a comma, the inferred layout:
+//
+//	Amount float64 `dbf:"AMOUNT,len=12,dec=2"`
+//	Code   int     `dbf:",type=C,len=4"`
+//	Notes  string  `dbf:"-"` // not stored
+//	Name   string  `dbf:",encoding=CP866"`
+//
+// The encoding option names one of the package's code pages, such as CP437
+// or CP1251, for a character field whose text is stored in a different code
+// page than the rest of the table: RecordFromStruct encodes the field's text
+// in it, and ReadInto decodes it from it, whatever charset the table or
+// writer was given.
+func SchemaFromStruct(v interface{}) ([]Field, error) {
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't derive a schema from %v, expected a struct", t)
+	}
+
+	var fields []Field
+	offset := uint32(1) // skip the deleted flag
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		if columnName(sf) == "" {
+			continue
+		}
+		f, err := structField(sf, sf.Tag.Get("dbf"))
+		if err != nil {
+			return nil, fmt.Errorf("struct field %s: %s", sf.Name, err)
+		}
+		f.Offset = offset
+		offset += uint32(f.Len)
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// RecordFromStruct is the counterpart of SchemaFromStruct: it returns the
+// exported fields of the struct v, or of the struct v points to, as a Record
+// keyed by the column names SchemaFromStruct gives them.  Integers become an
+// int64, floats a float64, nil pointers and database/sql Null values that
+// aren't Valid become nil, and decimal types their String form.
+func RecordFromStruct(v interface{}) (Record, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't make a record from %T, expected a struct", v)
+	}
+	if !rv.CanAddr() { // so that pointer methods, such as MarshalDBF, can be called
+		p := reflect.New(rv.Type())
+		p.Elem().Set(rv)
+		rv = p.Elem()
+	}
+
+	rec := make(Record)
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := columnName(sf)
+		if sf.PkgPath != "" || name == "" {
+			continue
+		}
+		val, err := recordValue(rv.Field(i))
+		if err != nil {
+			return nil, fmt.Errorf("struct field %s: %s", sf.Name, err)
+		}
+		if s, ok := val.(string); ok && tagCharset(sf) != nil {
+			val = encodedText{s, tagCharset(sf)}
+		}
+		rec[name] = val
+	}
+	return rec, nil
+}
+
+// recordValue converts a struct field to the value a Writer stores for it.
+func recordValue(fv reflect.Value) (interface{}, error) {
+	t := fv.Type()
+	switch {
+	case t.Implements(fieldMarshalerType):
+		return fv.Interface(), nil
+	case reflect.PtrTo(t).Implements(fieldMarshalerType):
+		return fv.Addr().Interface(), nil
+	case t == timeType:
+		return fv.Interface(), nil
+	case t.Kind() == reflect.Ptr:
+		if fv.IsNil() {
+			return nil, nil
+		}
+		return recordValue(fv.Elem())
+	case isSQLNull(t):
+		if !fv.Field(1).Bool() {
+			return nil, nil
+		}
+		return recordValue(fv.Field(0))
+	case t.Name() == "Decimal":
+		if s, ok := fv.Interface().(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return fv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n := fv.Uint(); n > math.MaxInt64 {
+			return new(big.Int).SetUint64(n), nil
+		}
+		return int64(fv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), nil
+	}
+	return nil, fmt.Errorf("can't store a %s", t)
+}
+
+// encodedText is the text of a struct field with an encoding option, which
+// stores itself in its own code page.
+type encodedText struct {
+	s  string
+	cs *Charset
+}
+
+func (e encodedText) MarshalDBF(f Field) ([]byte, error) {
+	return e.cs.Encode(e.s)
+}
+
+// tagCharset returns the code page named by the encoding option of a struct
+// field's tag, or nil.
+func tagCharset(sf reflect.StructField) *Charset {
+	for _, opt := range strings.Split(sf.Tag.Get("dbf"), ",")[1:] {
+		if strings.HasPrefix(opt, "encoding=") {
+			return charsetNamed(opt[len("encoding="):])
+		}
+	}
+	return nil
+}
+
+// charsetNamed returns the code page with the given name, or nil.
+func charsetNamed(name string) *Charset {
+	for _, c := range []*Charset{CP437, CP850, CP852, CP865, CP866, Mazovia, KOI8R, CP1250, CP1251, CP1252, CP1257} {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// columnName returns the name of the column an exported struct field maps
+// to, or "" if its tag says it isn't stored.
+func columnName(sf reflect.StructField) string {
+	tag := sf.Tag.Get("dbf")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.SplitN(tag, ",", 2)[0]; name != "" {
+		return name
+	}
+	return strings.ToUpper(sf.Name)
+}
+
+// structField lays out the column for a struct field according to its Go
+// type and the options in its tag.
+func structField(sf reflect.StructField, tag string) (Field, error) {
+	opts := strings.Split(tag, ",")
+	name := columnName(sf)
+	typ, length, decimals, inferErr := fieldTypeOf(sf.Type)
+	encoding := false
+	for _, opt := range opts[1:] {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return Field{}, fmt.Errorf("malformed tag option %q", opt)
+		}
+		switch kv[0] {
+		case "type":
+			if len(kv[1]) != 1 {
+				return Field{}, fmt.Errorf("field type must be a single character, got %q", kv[1])
+			}
+			typ, inferErr = kv[1][0], nil
+		case "len", "dec":
+			n, err := strconv.ParseUint(kv[1], 10, 8)
+			if err != nil {
+				return Field{}, fmt.Errorf("bad %s option %q: %s", kv[0], kv[1], err)
+			}
+			if kv[0] == "len" {
+				length = uint8(n)
+			} else {
+				decimals = uint8(n)
+			}
+		case "encoding":
+			if charsetNamed(kv[1]) == nil {
+				return Field{}, fmt.Errorf("unknown encoding %q", kv[1])
+			}
+			encoding = true
+		default:
+			return Field{}, fmt.Errorf("unknown tag option %q", kv[0])
+		}
+	}
+	if inferErr != nil {
+		return Field{}, inferErr
+	}
+	if encoding && typ != 'C' {
+		return Field{}, fmt.Errorf("the encoding option only applies to character fields")
+	}
+	if length == 0 {
+		return Field{}, fmt.Errorf("field type '%c' needs an explicit len option", typ)
+	}
+	return NewField(name, typ, length, decimals)
+}
+
+// fieldTypeOf picks the DBF type, width and decimal places for a Go type.
+func fieldTypeOf(t reflect.Type) (typ byte, length, decimals uint8, err error) {
+	switch {
+	case t.Implements(fieldMarshalerType) || reflect.PtrTo(t).Implements(fieldMarshalerType):
+		return 'C', 0, 0, nil
+	case t == timeType:
+		return 'D', 8, 0, nil
+	case t.Kind() == reflect.Ptr:
+		return fieldTypeOf(t.Elem())
+	case isSQLNull(t):
+		return fieldTypeOf(t.Field(0).Type)
+	case t.Name() == "Decimal":
+		return 'N', 20, 6, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return 'C', 254, 0, nil
+	case reflect.Bool:
+		return 'L', 1, 0, nil
+	case reflect.Int8:
+		return 'N', 4, 0, nil
+	case reflect.Int16:
+		return 'N', 6, 0, nil
+	case reflect.Int32:
+		return 'N', 11, 0, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return 'N', 20, 0, nil
+	case reflect.Uint8:
+		return 'N', 3, 0, nil
+	case reflect.Uint16:
+		return 'N', 5, 0, nil
+	case reflect.Uint32:
+		return 'N', 10, 0, nil
+	case reflect.Float32, reflect.Float64:
+		return 'N', 20, 6, nil
+	}
+	return 0, 0, 0, fmt.Errorf("no DBF field type for Go type %s", t)
+}
+
+// isSQLNull reports whether t is one of database/sql's nullable wrappers,
+// which all pair a value with a Valid flag.
+func isSQLNull(t reflect.Type) bool {
+	return t.PkgPath() == "database/sql" && t.Kind() == reflect.Struct &&
+		t.NumField() == 2 && t.Field(1).Name == "Valid"
+}
+
+// TightSchema measures the longest value actually stored in each character
+// and numeric field of the table's records, and returns a copy of the schema
+// with those fields narrowed to fit, as GIS tools prefer.  Deleted records
+// count too, so that they still fit if they are recalled.  Only trailing
+// padding is discounted from character values, since leading spaces are
+// part of the value, while numbers lose the spaces that right-align them.
+// Fields of other types keep their width, and numeric fields stay wide
+// enough for their decimal places.
+func (t *Table) TightSchema() ([]Field, error) {
+	widths := make([]int, len(t.fields))
+	for i := 0; i < t.nrec; i++ {
+		buf, err := t.readRaw(i)
+		if err != nil {
+			return nil, &RecordError{i, t.recordOffset(i), err}
+		}
+		pos := 1
+		for j, f := range t.fields {
+			stored := buf[pos : pos+int(f.Len)]
+			if f.Type == 'C' {
+				stored = bytes.TrimRight(stored, " \x00")
+			} else {
+				stored = bytes.TrimSpace(stored)
+			}
+			if w := len(stored); w > widths[j] {
+				widths[j] = w
+			}
+			pos += int(f.Len)
+		}
+	}
+
+	fields := make([]Field, len(t.fields))
+	offset := uint32(1)
+	for j, f := range t.fields {
+		switch f.Type {
+		case 'C', 'N', 'F':
+			min := 1
+			if f.DecimalPlaces > 0 {
+				min = int(f.DecimalPlaces) + 2
+			}
+			if widths[j] < min {
+				widths[j] = min
+			}
+			f.Len = uint8(widths[j])
+		}
+		f.Offset = offset
+		offset += uint32(f.Len)
+		fields[j] = f
+	}
+	return fields, nil
+}
@@ -0,0 +1,258 @@
+package dbf
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchemaFromStruct(t *testing.T) {
+	type customer struct {
+		ID      int32
+		Name    string `dbf:"CUSTNAME"`
+		Balance float64
+		Active  bool
+		Since   time.Time
+		private int
+	}
+	fields, err := SchemaFromStruct(&customer{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	expected := []struct {
+		name     string
+		typ      byte
+		length   uint8
+		decimals uint8
+		offset   uint32
+	}{
+		{"ID", 'N', 11, 0, 1},
+		{"CUSTNAME", 'C', 254, 0, 12},
+		{"BALANCE", 'N', 20, 6, 266},
+		{"ACTIVE", 'L', 1, 0, 286},
+		{"SINCE", 'D', 8, 0, 287},
+	}
+	if len(fields) != len(expected) {
+		t.Fatalf("expected %d fields, got %d", len(expected), len(fields))
+	}
+	for i, e := range expected {
+		f := fields[i]
+		name := string(f.Name[:len(e.name)])
+		if name != e.name || f.Type != e.typ || f.Len != e.length || f.DecimalPlaces != e.decimals || f.Offset != e.offset {
+			t.Errorf("field %d: got %s %c(%d,%d)@%d, expected %s %c(%d,%d)@%d", i,
+				name, f.Type, f.Len, f.DecimalPlaces, f.Offset, e.name, e.typ, e.length, e.decimals, e.offset)
+		}
+	}
+}
+
+func TestSchemaFromStructErrors(t *testing.T) {
+	if _, err := SchemaFromStruct(42); err == nil {
+		t.Errorf("expected an error for a non-struct")
+	}
+	if _, err := SchemaFromStruct(struct{ C chan int }{}); err == nil {
+		t.Errorf("expected an error for an unmappable field type")
+	}
+	if _, err := SchemaFromStruct(struct {
+		X int `dbf:"MUCH_TOO_LONG"`
+	}{}); err == nil {
+		t.Errorf("expected an error for an over-long field name")
+	}
+}
+
+func TestSchemaTagOptions(t *testing.T) {
+	fields, err := SchemaFromStruct(struct {
+		Amount float64 `dbf:"AMOUNT,len=12,dec=2"`
+		Code   int     `dbf:",type=C,len=4"`
+		Notes  string  `dbf:"-"`
+	}{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("expected 2 fields, got %d", len(fields))
+	}
+	if f := fields[0]; f.Type != 'N' || f.Len != 12 || f.DecimalPlaces != 2 {
+		t.Errorf("AMOUNT: got %c(%d,%d), expected N(12,2)", f.Type, f.Len, f.DecimalPlaces)
+	}
+	if f := fields[1]; string(f.Name[:4]) != "CODE" || f.Type != 'C' || f.Len != 4 || f.Offset != 13 {
+		t.Errorf("CODE: got %s %c(%d)@%d, expected CODE C(4)@13", f.Name[:4], f.Type, f.Len, f.Offset)
+	}
+
+	if _, err := SchemaFromStruct(struct {
+		X int `dbf:"X,width=3"`
+	}{}); err == nil {
+		t.Errorf("expected an error for an unknown tag option")
+	}
+}
+
+func TestSchemaTagEncoding(t *testing.T) {
+	type person struct {
+		Name  string `dbf:",len=6,encoding=CP866"`
+		Place string `dbf:",len=6"`
+	}
+	fields, err := SchemaFromStruct(person{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var ws writeSeeker
+	w, err := NewWriter(&ws, fields)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec, err := RecordFromStruct(person{"Иван", "Koln"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(rec); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if name := ws.buf[len(ws.buf)-13 : len(ws.buf)-7]; !bytes.Equal(name, []byte{0x88, 0xA2, 0xA0, 0xAD, ' ', ' '}) {
+		t.Fatalf("name not stored in CP866: % x", name)
+	}
+
+	tbl, err := OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf)), WithCharset(CP1252))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var p person
+	if err = tbl.ReadInto(0, &p); err != nil || p.Name != "Иван" || p.Place != "Koln" {
+		t.Fatalf("ReadInto returned %+v, %v", p, err)
+	}
+
+	for _, v := range []interface{}{
+		struct {
+			X string `dbf:",encoding=EBCDIC"`
+		}{},
+		struct {
+			X int `dbf:",encoding=CP866"`
+		}{},
+	} {
+		if _, err := SchemaFromStruct(v); err == nil {
+			t.Errorf("expected an error for %T", v)
+		}
+	}
+}
+
+func TestSchemaWrappedTypes(t *testing.T) {
+	type Decimal struct{ unscaled int64 }
+	fields, err := SchemaFromStruct(struct {
+		Born    *time.Time
+		Name    sql.NullString `dbf:",len=40"`
+		Score   sql.NullFloat64
+		Flag    sql.NullBool
+		Visited sql.NullTime
+		Price   Decimal `dbf:",dec=2"`
+	}{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := "D(8,0) C(40,0) N(20,6) L(1,0) D(8,0) N(20,2)"
+	actual := ""
+	for i, f := range fields {
+		if i > 0 {
+			actual += " "
+		}
+		actual += fmt.Sprintf("%c(%d,%d)", f.Type, f.Len, f.DecimalPlaces)
+	}
+	if actual != expected {
+		t.Fatalf("wrong schema: got %s, expected %s", actual, expected)
+	}
+}
+
+type cents int64
+
+func (c cents) MarshalDBF(f Field) ([]byte, error) {
+	return []byte(fmt.Sprintf("%*.2f", f.Len, float64(c)/100)), nil
+}
+
+func TestSchemaFieldMarshaler(t *testing.T) {
+	fields, err := SchemaFromStruct(struct {
+		Price cents `dbf:",type=N,len=10,dec=2"`
+		Cost  cents `dbf:",len=8"`
+	}{})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if f := fields[0]; f.Type != 'N' || f.Len != 10 || f.DecimalPlaces != 2 {
+		t.Errorf("PRICE: got %c(%d,%d), expected N(10,2)", f.Type, f.Len, f.DecimalPlaces)
+	}
+	if f := fields[1]; f.Type != 'C' || f.Len != 8 {
+		t.Errorf("COST: got %c(%d), expected C(8)", f.Type, f.Len)
+	}
+	if _, err := SchemaFromStruct(struct{ Price cents }{}); err == nil {
+		t.Errorf("expected an error for a FieldMarshaler without a len option")
+	}
+}
+
+func TestTightSchema(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 10, 0), mustField("NAME", 'C', 20, 0), mustField("AMOUNT", 'N', 12, 2), mustField("EMPTY", 'C', 20, 0)},
+		fmt.Sprintf(" %10s%-20s%12s%20s", "1", "alpha", "1.50", ""),
+		fmt.Sprintf("*%10s%-20s%12s%20s", "12345", "deleted", "2.00", ""),
+		fmt.Sprintf(" %10s%-20s%12s%20s", "123", "   bravo-charlie", "-10.00", ""))
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	fields, err := tbl.TightSchema()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	actual := ""
+	for _, f := range fields {
+		actual += fmt.Sprintf("%c(%d,%d)@%d ", f.Type, f.Len, f.DecimalPlaces, f.Offset)
+	}
+	if expected := "N(5,0)@1 C(16,0)@6 N(6,2)@22 C(1,0)@28 "; actual != expected {
+		t.Fatalf("wrong schema: got %s, expected %s", actual, expected)
+	}
+}
+
+func TestNumericType(t *testing.T) {
+	for _, c := range []struct {
+		f        Field
+		expected string
+	}{
+		{mustField("A", 'N', 4, 0), "int16"},
+		{mustField("A", 'N', 5, 0), "int32"},
+		{mustField("A", 'N', 10, 0), "int64"},
+		{mustField("A", 'N', 19, 0), "decimal"},
+		{mustField("A", 'N', 12, 2), "float64"},
+		{mustField("A", 'N', 20, 4), "decimal"},
+		{mustField("A", 'F', 20, 4), "float64"},
+		{mustField("A", 'C', 10, 0), ""},
+	} {
+		if actual := c.f.NumericType(); actual != c.expected {
+			t.Errorf("%c(%d,%d): got %q, expected %q", c.f.Type, c.f.Len, c.f.DecimalPlaces, actual, c.expected)
+		}
+	}
+}
+
+func TestRecordFromStruct(t *testing.T) {
+	type row struct {
+		ID      uint16
+		Name    string `dbf:"NAME"`
+		Score   *float64
+		Born    sql.NullTime
+		Active  bool
+		Skipped string `dbf:"-"`
+		private int
+	}
+	day := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	rec, err := RecordFromStruct(&row{ID: 7, Name: "alpha", Born: sql.NullTime{Time: day, Valid: true}, Active: true})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	expected := Record{"ID": int64(7), "NAME": "alpha", "SCORE": nil, "BORN": day, "ACTIVE": true}
+	if !reflect.DeepEqual(rec, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, rec)
+	}
+	if _, err = RecordFromStruct(42); err == nil {
+		t.Fatalf("expected an error for a non-struct")
+	}
+}
@@ -0,0 +1,42 @@
+package dbf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0), mustField("QTY", 'N', 3, 0)}
+	r, err := NewReaderFromBytes(buildTable(fields, "   1alphaN/A", "   2bravo  7"))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err = r.Record(0); err == nil {
+		t.Fatalf("expected an error for a number that can't be decoded")
+	}
+
+	view, err := r.Select("NAME", "ID")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	// QTY isn't decoded, so its bad value goes unnoticed.
+	if rec, err := view.Record(0); err != nil || !reflect.DeepEqual(rec, Record{"ID": int64(1), "NAME": "alpha"}) {
+		t.Errorf("Record(0) returned %v, %v", rec, err)
+	}
+	if names := view.FieldNames(); !reflect.DeepEqual(names, []string{"ID", "NAME"}) {
+		t.Errorf("FieldNames() returned %v, expected [ID NAME]", names)
+	}
+	if schema := view.Schema(); len(schema) != 2 || schema[1].Name != fields[1].Name {
+		t.Errorf("Schema() returned %v", schema)
+	}
+	if got := len(r.FieldNames()); got != 3 {
+		t.Errorf("the original table lists %d fields, expected 3", got)
+	}
+
+	if _, err = r.Select("NAME", "PRICE"); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+	if _, err = view.Select("QTY"); err == nil {
+		t.Errorf("expected an error for a field outside the view")
+	}
+}
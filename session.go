@@ -0,0 +1,319 @@
+package dbf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A Session collects changes to an Editor's table and its memo file, and
+// writes them together on Commit in an order that a crash can't turn into
+// dangling memo references: the table's transaction flag is set first, then
+// the memo blocks are written and synced, then the records pointing to them,
+// and finally the header, which clears the flag.  A table found with the
+// flag still set, which dBase IV reports as an incomplete transaction, may
+// hold some of the changes but no record points to a memo that wasn't
+// written.
+//
+// Unlike Update, a Session can set memo fields, to strings or, in FoxPro
+// memo files, []byte.  Index files attached to the table aren't updated.
+// Changes aren't visible through the Editor until they are committed, and a
+// Session must not be shared between goroutines.
+type Session struct {
+	e       *Editor
+	memo    io.WriterAt
+	changes []change
+	appends int
+	done    bool
+}
+
+// A change is a record to be written by Commit.
+type change struct {
+	recno int
+	buf   []byte
+	memos []memoChange
+}
+
+// A memoChange is a memo to be written by Commit, and pointed to by the
+// field at pos.
+type memoChange struct {
+	pos  int
+	f    Field
+	memo []byte // as stored, empty for a blank pointer
+}
+
+// Begin starts a Session.  memo is where the table's memo file, the one
+// passed to WithMemo, is written; it may be nil if no memo fields will be
+// changed.
+func (e *Editor) Begin(memo io.WriterAt) *Session {
+	return &Session{e: e, memo: memo}
+}
+
+// Update changes the fields of record i as Editor.Update does, once the
+// Session is committed.  Values are checked right away.  Updates to a record
+// already changed or appended in the Session add to the earlier changes.
+func (s *Session) Update(i int, rec Record) error {
+	t := s.e.Table
+	fields, memos, err := s.splitMemos(rec)
+	if err != nil {
+		return err
+	}
+	if c := s.pending(i); c != nil {
+		buf := append([]byte(nil), c.buf...)
+		pos := 1
+		for j, f := range t.fields {
+			name := t.FieldName(j)
+			if v, ok := fields[name]; ok {
+				b, err := t.encodeValue(f, name, v)
+				if err != nil {
+					return fmt.Errorf("field %s: %s", name, err)
+				}
+				copy(buf[pos:], b)
+			}
+			pos += int(f.Len)
+		}
+		c.buf = buf
+		for _, m := range memos {
+			replaced := false
+			for k := range c.memos {
+				if c.memos[k].pos == m.pos {
+					c.memos[k], replaced = m, true
+				}
+			}
+			if !replaced {
+				c.memos = append(c.memos, m)
+			}
+		}
+		return nil
+	}
+	buf, err := t.updated(i, fields)
+	if err != nil {
+		return err
+	}
+	s.changes = append(s.changes, change{i, buf, memos})
+	return nil
+}
+
+// Append adds rec to the end of the table as Editor.Append does, once the
+// Session is committed, and returns the number the record will have.
+func (s *Session) Append(rec Record) (int, error) {
+	t := s.e.Table
+	fields, memos, err := s.splitMemos(rec)
+	if err != nil {
+		return -1, err
+	}
+	buf, err := t.newRecord(fields)
+	if err != nil {
+		return -1, err
+	}
+	i := t.nrec + s.appends
+	s.appends++
+	s.changes = append(s.changes, change{i, buf, memos})
+	return i, nil
+}
+
+// pending returns the change already made to record i in the Session, if
+// any.
+func (s *Session) pending(i int) *change {
+	for j := range s.changes {
+		if s.changes[j].recno == i {
+			return &s.changes[j]
+		}
+	}
+	return nil
+}
+
+// splitMemos separates the memo fields of rec, encoding their memos, from
+// the others.
+func (s *Session) splitMemos(rec Record) (Record, []memoChange, error) {
+	t := s.e.Table
+	if s.done {
+		return nil, nil, fmt.Errorf("session is already committed or rolled back")
+	}
+	fields := make(Record, len(rec))
+	for name, v := range rec {
+		fields[name] = v
+	}
+	var memos []memoChange
+	pos := 1
+	for j, f := range t.fields {
+		name := t.FieldName(j)
+		v, ok := rec[name]
+		if f.isMemo() && ok {
+			delete(fields, name)
+			c := memoChange{pos: pos, f: f}
+			if str, isString := v.(string); isString && t.charset != nil {
+				b, err := t.charset.Encode(str)
+				if err != nil {
+					return nil, nil, fmt.Errorf("field %s: %s", name, err)
+				}
+				v = string(b)
+			}
+			if v != nil && v != "" {
+				if t.memo == nil || s.memo == nil {
+					return nil, nil, fmt.Errorf("field %s: writing memos needs the memo file, see WithMemo and Begin", name)
+				}
+				b, err := t.memo.encode(v)
+				if err != nil {
+					return nil, nil, fmt.Errorf("field %s: %s", name, err)
+				}
+				c.memo = b
+			}
+			memos = append(memos, c)
+		}
+		pos += int(f.Len)
+	}
+	return fields, memos, nil
+}
+
+// Commit writes the Session's changes.  If it fails part way, the table's
+// transaction flag stays set.
+func (s *Session) Commit() error {
+	if s.done {
+		return fmt.Errorf("session is already committed or rolled back")
+	}
+	s.done = true
+	e, t := s.e, s.e.Table
+	if _, err := e.w.WriteAt([]byte{1}, 14); err != nil {
+		return err
+	}
+	if err := syncTo(e.w); err != nil {
+		return err
+	}
+
+	var next int64
+	for _, c := range s.changes {
+		for _, m := range c.memos {
+			block := int64(0)
+			if len(m.memo) > 0 {
+				if next == 0 {
+					var err error
+					if next, err = t.memo.nextBlock(); err != nil {
+						return err
+					} else if next == 0 {
+						return fmt.Errorf("memo file header gives no free block")
+					}
+				}
+				if _, err := s.memo.WriteAt(m.memo, next*t.memo.blockSize); err != nil {
+					return err
+				}
+				block = next
+				next += int64(len(m.memo)) / t.memo.blockSize
+			}
+			if err := putMemoPointer(c.buf[m.pos:m.pos+int(m.f.Len)], block); err != nil {
+				return err
+			}
+		}
+	}
+	if next > 0 {
+		if _, err := s.memo.WriteAt(t.memo.putNextBlock(next), 0); err != nil {
+			return err
+		}
+		if err := syncTo(s.memo); err != nil {
+			return err
+		}
+	}
+
+	var before []Record // for the journal
+	if t.journal != nil {
+		for _, c := range s.changes {
+			var rec Record
+			if c.recno < t.nrec {
+				raw, err := t.readRaw(c.recno)
+				if err != nil {
+					return err
+				}
+				rec = t.decodeLeniently(c.recno, raw)
+			}
+			before = append(before, rec)
+		}
+	}
+	for _, c := range s.changes {
+		if _, err := e.w.WriteAt(c.buf, t.recordOffset(c.recno)); err != nil {
+			return err
+		}
+		t.count(MetricRecordsWritten, 1)
+	}
+	nrec := t.nrec + s.appends
+	if s.appends > 0 {
+		if _, err := e.w.WriteAt([]byte{0x1A}, t.recordOffset(nrec)); err != nil {
+			return err
+		}
+	}
+	if err := syncTo(e.w); err != nil {
+		return err
+	}
+
+	var count [4]byte
+	binary.LittleEndian.PutUint32(count[:], uint32(nrec))
+	if _, err := e.w.WriteAt(count[:], 4); err != nil {
+		return err
+	}
+	if err := e.touch(); err != nil {
+		return err
+	}
+	if _, err := e.w.WriteAt([]byte{0}, 14); err != nil {
+		return err
+	}
+	if err := syncTo(e.w); err != nil {
+		return err
+	}
+	for k := range before {
+		c := s.changes[k]
+		op := "update"
+		if c.recno >= t.nrec {
+			op = "append"
+		}
+		if err := t.record(op, c.recno, before[k], t.decodeLeniently(c.recno, c.buf[:t.datalen])); err != nil {
+			return err
+		}
+	}
+	if s.appends == 0 {
+		return nil
+	}
+	size := t.recordOffset(nrec) + 1
+	if t.size > size {
+		size = t.size
+	}
+	e.resize(nrec, size)
+	return nil
+}
+
+// Rollback discards the Session's changes, none of which have been written.
+func (s *Session) Rollback() {
+	s.done = true
+	s.changes = nil
+}
+
+// putMemoPointer stores the number of a memo's first block in the field raw:
+// as a little-endian uint32 in 4-byte Visual FoxPro fields, and as
+// right-aligned digits otherwise.  Block 0 is stored as a blank.
+func putMemoPointer(raw []byte, block int64) error {
+	if len(raw) == 4 {
+		binary.LittleEndian.PutUint32(raw, uint32(block))
+		return nil
+	}
+	s := ""
+	if block > 0 {
+		s = strconv.FormatInt(block, 10)
+	}
+	if len(s) > len(raw) {
+		return fmt.Errorf("memo block %d doesn't fit a %d byte field", block, len(raw))
+	}
+	for i := range raw {
+		raw[i] = ' '
+	}
+	copy(raw[len(raw)-len(s):], s)
+	return nil
+}
+
+// syncTo flushes w to stable storage if it can be, as an *os.File can.
+func syncTo(w interface{}) error {
+	if s, ok := w.(interface {
+		Sync() error
+	}); ok {
+		return s.Sync()
+	}
+	return nil
+}
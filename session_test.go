@@ -0,0 +1,168 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	dbt3 := make([]byte, 2*512)
+	binary.LittleEndian.PutUint32(dbt3, 2)
+	dbt3[16] = 0x03
+	copy(dbt3[512:], "hello\x1A\x1A")
+
+	dbt4 := make([]byte, 2*512)
+	binary.LittleEndian.PutUint32(dbt4, 2)
+	binary.LittleEndian.PutUint16(dbt4[20:], 512)
+	copy(dbt4[512:], []byte{0xFF, 0xFF, 0x08, 0x00, 8 + 5, 0, 0, 0})
+	copy(dbt4[520:], "hello")
+
+	fpt := make([]byte, 512+64)
+	binary.BigEndian.PutUint32(fpt, 9)
+	binary.BigEndian.PutUint16(fpt[6:], 64)
+	copy(fpt[512:], []byte{0, 0, 0, 1, 0, 0, 0, 5})
+	copy(fpt[520:], "hello")
+
+	for _, tc := range []struct {
+		name    string
+		version byte
+		memo    []byte
+		pointer string
+		next    uint32
+	}{
+		{"dBase III", 0x83, dbt3, "         1", 4},
+		{"dBase IV", 0x8B, dbt4, "         1", 4},
+		{"FoxPro", 0xF5, fpt, "         8", 11},
+	} {
+		f, err := ioutil.TempFile("", "dbf")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		f.Write(memoTable(tc.version, tc.pointer))
+		m, err := ioutil.TempFile("", "dbt")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		defer os.Remove(m.Name())
+		defer m.Close()
+		m.Write(tc.memo)
+
+		e, err := NewEditor(f, WithMemo(m))
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		s := e.Begin(m)
+		if err = s.Update(0, Record{"NOTES": "changed"}); err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if i, err := s.Append(Record{"ID": 2, "NOTES": "new"}); err != nil || i != 1 {
+			t.Fatalf("%s: expected record 1, got %d, %v", tc.name, i, err)
+		}
+		if rec, err := e.Record(0); err != nil || rec["NOTES"] != "hello" || e.Len() != 1 {
+			t.Fatalf("%s: changes visible before commit: %v, %v", tc.name, rec, err)
+		}
+		if err = s.Commit(); err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if err = s.Commit(); err == nil {
+			t.Fatalf("%s: expected an error committing twice", tc.name)
+		}
+
+		if e.Len() != 2 {
+			t.Fatalf("%s: expected 2 records, got %d", tc.name, e.Len())
+		}
+		for i, expected := range []string{"changed", "new"} {
+			if rec, err := e.Record(i); err != nil || rec["NOTES"] != expected {
+				t.Fatalf("%s: Record(%d) returned %v, %v", tc.name, i, rec, err)
+			}
+		}
+		header := make([]byte, 16)
+		m.ReadAt(header, 0)
+		next := binary.LittleEndian.Uint32(header)
+		if tc.version == 0xF5 {
+			next = binary.BigEndian.Uint32(header)
+		}
+		if next != tc.next {
+			t.Fatalf("%s: expected next free block %d, got %d", tc.name, tc.next, next)
+		}
+		f.ReadAt(header, 0)
+		if header[14] != 0 {
+			t.Fatalf("%s: transaction flag left set", tc.name)
+		}
+	}
+}
+
+func TestSessionRollback(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	data := memoTable(0x83, "          ")
+	f.Write(data)
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	s := e.Begin(nil)
+	if err = s.Update(0, Record{"NOTES": "no memo file"}); err == nil {
+		t.Fatalf("expected an error for a memo without a memo file")
+	}
+	if _, err = s.Append(Record{"ID": 2}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	s.Rollback()
+	if err = s.Commit(); err == nil {
+		t.Fatalf("expected an error committing a rolled back session")
+	}
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(got, data) || e.Len() != 1 {
+		t.Fatalf("rolled back session changed the table")
+	}
+}
+
+func TestSessionUpdateTwice(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.Write(buildTable([]Field{mustField("A", 'C', 3, 0), mustField("B", 'C', 3, 0)}, " aaabbb"))
+
+	e, err := NewEditor(f)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	s := e.Begin(nil)
+	for _, rec := range []Record{{"A": "xxx"}, {"B": "yyy"}} {
+		if err = s.Update(0, rec); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	i, err := s.Append(Record{"A": "ccc"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = s.Update(i, Record{"B": "ddd"}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = s.Commit(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for i, expected := range []Record{{"A": "xxx", "B": "yyy"}, {"A": "ccc", "B": "ddd"}} {
+		if rec, err := e.Record(i); err != nil || rec["A"] != expected["A"] || rec["B"] != expected["B"] {
+			t.Fatalf("Record(%d) returned %v, %v, expected %v", i, rec, err, expected)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package dbf
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Attributes pairs the records of a shapefile's .dbf with its shapes.  The
+// n-th shape in the .shp file (counting from 0) belongs to record n of the
+// table, whether or not that record has been deleted, so records are always
+// addressed by position and never by counting live records.
+type Attributes struct {
+	t *Table
+
+	mu  sync.Mutex                // guards ids
+	ids map[string]map[string]int // field name → normalized value → shape index
+}
+
+// NewAttributes prepares t, the attribute table of a shapefile, for lookups.
+func NewAttributes(t *Table) *Attributes {
+	return &Attributes{t: t, ids: make(map[string]map[string]int)}
+}
+
+// Len returns the number of shapes the table has attributes for.
+func (a *Attributes) Len() int {
+	return a.t.nrec
+}
+
+// Shape returns the attributes of shape i.  A deleted record is still
+// decoded, and reported through deleted, so that the caller can decide
+// whether to drop the shape as well.
+func (a *Attributes) Shape(i int) (rec Record, deleted bool, err error) {
+	buf, err := a.t.readRaw(i)
+	if err != nil {
+		return nil, false, err
+	}
+	rec, err = a.t.decodeRecord(i, buf)
+	return rec, buf[0] == '*', err
+}
+
+// Lookup finds the shape whose field has the value id, for joining
+// attributes to shapes on an ID column instead of by position.  Deleted
+// records never match.  If several records share an ID, the first wins.  The
+// index for each field is built on first use.
+func (a *Attributes) Lookup(field string, id interface{}) (shape int, rec Record, err error) {
+	a.mu.Lock()
+	index, ok := a.ids[field]
+	if !ok {
+		index = make(map[string]int)
+		err = a.t.Scan(func(i int, rec Record) error {
+			v, ok := rec[field]
+			if !ok {
+				return fmt.Errorf("table has no field %q", field)
+			}
+			if _, dup := index[normalize(v)]; !dup {
+				index[normalize(v)] = i
+			}
+			return nil
+		})
+		if err == nil {
+			a.ids[field] = index
+		}
+	}
+	a.mu.Unlock()
+	if err != nil {
+		return -1, nil, err
+	}
+
+	shape, ok = index[normalize(id)]
+	if !ok {
+		return -1, nil, fmt.Errorf("no shape has %s = %v", field, id)
+	}
+	rec, err = a.t.Record(shape)
+	return shape, rec, err
+}
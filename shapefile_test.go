@@ -0,0 +1,39 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAttributes(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)},
+		"  10alpha", "* 20bravo", "  30charl")
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	a := NewAttributes(tbl)
+	if a.Len() != 3 {
+		t.Fatalf("wrong Len(): got %d, expected 3", a.Len())
+	}
+
+	rec, deleted, err := a.Shape(1)
+	if err != nil || !deleted || rec["NAME"] != "bravo" {
+		t.Fatalf("Shape(1) returned %v, %v, %v", rec, deleted, err)
+	}
+	rec, deleted, err = a.Shape(2)
+	if err != nil || deleted || rec["NAME"] != "charl" {
+		t.Fatalf("Shape(2) returned %v, %v, %v", rec, deleted, err)
+	}
+
+	shape, rec, err := a.Lookup("ID", 30)
+	if err != nil || shape != 2 || rec["NAME"] != "charl" {
+		t.Fatalf("Lookup(ID, 30) returned %d, %v, %v", shape, rec, err)
+	}
+	if _, _, err = a.Lookup("ID", 20); err == nil {
+		t.Fatalf("expected deleted record not to match")
+	}
+	if _, _, err = a.Lookup("MISSING", 1); err == nil {
+		t.Fatalf("expected an error for a missing field")
+	}
+}
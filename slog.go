@@ -0,0 +1,26 @@
+//go:build go1.21
+// +build go1.21
+
+package dbf
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger makes the Table report table opens, failed record reads, the
+// repairs WithLenient makes and slow scans, see WithSlowScanThreshold, to l.
+// Routine events are logged at Info, problems at Warn or Error.
+func WithLogger(l *slog.Logger) Option {
+	return func(t *Table) {
+		t.logger = slogLogger{l}
+	}
+}
+
+type slogLogger struct {
+	*slog.Logger
+}
+
+func (l slogLogger) log(level int, msg string, args ...interface{}) {
+	l.Log(context.Background(), slog.Level(level), msg, args...)
+}
@@ -0,0 +1,52 @@
+//go:build go1.21
+// +build go1.21
+
+package dbf
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil))
+	r, err := NewReader(bytes.NewReader(testData), WithLogger(l))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !strings.Contains(buf.String(), `msg="dbf: opened table" records=8461 fields=3`) {
+		t.Fatalf("open wasn't logged, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	r.Read(1) // past the end of the test data
+	if !strings.Contains(buf.String(), "level=WARN") {
+		t.Fatalf("failed read wasn't logged, got: %s", buf.String())
+	}
+}
+
+func TestLoggerLenientAndSlowScan(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil))
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0)}, "   1", "?N/A")
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)), WithLogger(l), WithLenient(), WithSlowScanThreshold(time.Nanosecond))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = tbl.Scan(func(int, Record) error { return nil }); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, want := range []string{
+		`msg="dbf: read unexpected deleted flag as a live record" record=1 flag=63`,
+		`msg="dbf: read undecodable value as nil" record=1 field=ID`,
+		`msg="dbf: slow scan" records=2`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %s to be logged, got: %s", want, buf.String())
+		}
+	}
+}
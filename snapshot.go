@@ -0,0 +1,87 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+const snapshotAttempts = 5
+
+// Snapshot writes a byte-for-byte consistent copy of the table to dst, even
+// while another program keeps modifying it.  The header is read afresh, so
+// records appended since the table was opened are included.  The table is
+// copied to a temporary file and then read a second time; if the two reads
+// differ, the copy was torn by a concurrent write and is retried after a short
+// pause.  Only a verified copy is passed on to dst.
+func (t *Table) Snapshot(dst io.Writer) error {
+	tmp, err := ioutil.TempFile("", "dbf-snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	short := false
+	for attempt := 1; attempt <= snapshotAttempts; attempt++ {
+		n, consistent, err := t.snapshotTo(tmp)
+		short = err == io.ErrUnexpectedEOF // maybe records are still being appended
+		if err != nil && !short {
+			return err
+		}
+		if err == nil && consistent {
+			if _, err = tmp.Seek(0, 0); err != nil {
+				return err
+			}
+			_, err = io.CopyN(dst, tmp, n)
+			return err
+		}
+		t.log(levelInfo, "dbf: table changed while taking a snapshot, retrying", "attempt", attempt)
+		if _, err = tmp.Seek(0, 0); err != nil {
+			return err
+		}
+		if err = tmp.Truncate(0); err != nil {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * 10 * time.Millisecond)
+	}
+	if short {
+		return fmt.Errorf("table is shorter than its header describes")
+	}
+	return fmt.Errorf("table kept changing during %d snapshot attempts", snapshotAttempts)
+}
+
+// snapshotTo copies the table to w once, and reports whether a second read
+// found the same bytes.
+func (t *Table) snapshotTo(w io.Writer) (n int64, consistent bool, err error) {
+	raw := make([]byte, 32)
+	if err = readFullAt(t.src, raw, 0); err != nil {
+		return 0, false, err
+	}
+	var h header
+	if err = binary.Read(bytes.NewReader(raw), binary.LittleEndian, &h); err != nil {
+		return 0, false, err
+	}
+	length := int64(h.Headerlen) + int64(h.Nrec)*int64(h.Recordlen)
+
+	first := crc32.NewIEEE()
+	if n, err = io.Copy(io.MultiWriter(w, first), io.NewSectionReader(t.src, 0, length)); err != nil {
+		return n, false, err
+	} else if n < length {
+		return n, false, io.ErrUnexpectedEOF
+	}
+	if _, err = w.Write([]byte{0x1A}); err != nil {
+		return n, false, err
+	}
+
+	second := crc32.NewIEEE()
+	if _, err = io.Copy(second, io.NewSectionReader(t.src, 0, length)); err != nil {
+		return n, false, err
+	}
+	return n + 1, first.Sum32() == second.Sum32(), nil
+}
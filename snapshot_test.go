@@ -0,0 +1,58 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+// changingReaderAt simulates another program rewriting a byte of the table
+// the second time it's read.
+type changingReaderAt struct {
+	data  []byte
+	reads int
+	at    int
+}
+
+func (c *changingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off == 0 {
+		c.reads++
+		if c.reads == 4 { // the verification read of the first attempt
+			c.data[c.at] = 'X'
+		}
+	}
+	return bytes.NewReader(c.data).ReadAt(p, off)
+}
+
+func TestSnapshot(t *testing.T) {
+	table := buildTable([]Field{mustField("NAME", 'C', 5, 0)}, " alpha", " bravo")
+	var dst bytes.Buffer
+	r, err := NewReader(bytes.NewReader(table))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = r.Snapshot(&dst); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !bytes.Equal(dst.Bytes(), table) {
+		t.Fatalf("wrong snapshot:\n got %q\nwant %q", dst.Bytes(), table)
+	}
+	if err = reader.Snapshot(&dst); err == nil {
+		t.Fatalf("expected an error for the truncated test file")
+	}
+
+	src := &changingReaderAt{data: append([]byte(nil), table...), at: len(table) - 2}
+	tbl, err := OpenTable(src, int64(len(table)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	dst.Reset()
+	if err = tbl.Snapshot(&dst); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if src.reads < 5 {
+		t.Fatalf("expected the torn copy to be retried")
+	}
+	if !bytes.Equal(dst.Bytes(), src.data) {
+		t.Fatalf("wrong snapshot:\n got %q\nwant %q", dst.Bytes(), src.data)
+	}
+}
@@ -0,0 +1,171 @@
+package dbf
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+type sortEntry struct {
+	recno int
+	keys  []interface{}
+}
+
+// ScanSorted is like Scan, but calls fn in ascending order of the given key
+// fields, compared in turn.  Records with equal keys are visited in record
+// order, so the output is the same on every run, as diff-based consumers
+// require.  Only the keys are held in memory; records are read again as they
+// are visited.
+func (t *Table) ScanSorted(keys []string, fn func(i int, rec Record) error) error {
+	var entries []sortEntry
+	err := t.Scan(func(i int, rec Record) error {
+		e := sortEntry{i, make([]interface{}, len(keys))}
+		for k, name := range keys {
+			v, ok := rec[name]
+			if !ok {
+				return fmt.Errorf("table has no field %q", name)
+			}
+			e.keys[k] = t.sortKey(name, v)
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Stable(byKeys(entries))
+	for _, e := range entries {
+		rec, err := t.Record(e.recno)
+		if err != nil {
+			return &RecordError{e.recno, t.recordOffset(e.recno), err}
+		}
+		if err = fn(e.recno, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type byKeys []sortEntry
+
+func (s byKeys) Len() int      { return len(s) }
+func (s byKeys) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byKeys) Less(i, j int) bool {
+	for k := range s[i].keys {
+		if c := compareValues(s[i].keys[k], s[j].keys[k]); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// sortKey returns the value v of the named field as it is compared.  The
+// text of numeric fields read WithNumericStrings is sorted as the number it
+// holds, so that "9" comes before "10".
+func (t *Table) sortKey(name string, v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok || !t.numericStrings {
+		return v
+	}
+	if i := t.fieldIndex(name); i < 0 || t.fields[i].Type != 'N' && t.fields[i].Type != 'F' {
+		return v
+	}
+	if s == "" {
+		return nil
+	}
+	if r, ok := new(big.Rat).SetString(s); ok {
+		return r
+	}
+	return v
+}
+
+// compareValues orders two field values: numbers numerically and exactly,
+// anything else by its normalized text.
+func compareValues(a, b interface{}) int {
+	if ia, ok := toInt(a); ok {
+		if ib, ok := toInt(b); ok {
+			switch {
+			case ia < ib:
+				return -1
+			case ia > ib:
+				return 1
+			}
+			return 0
+		}
+	}
+	if fa, ok := a.(float64); ok {
+		if fb, ok := b.(float64); ok {
+			switch {
+			case fa < fb:
+				return -1
+			case fa > fb:
+				return 1
+			}
+			return 0
+		}
+	}
+	ra, aNum := toRat(a)
+	rb, bNum := toRat(b)
+	if aNum && bNum {
+		return ra.Cmp(rb)
+	}
+	sa, sb := normalize(a), normalize(b)
+	switch {
+	case sa < sb:
+		return -1
+	case sa > sb:
+		return 1
+	}
+	return 0
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// toRat returns the number v as an exact fraction.
+func toRat(v interface{}) (*big.Rat, bool) {
+	if n, ok := toInt(v); ok {
+		return new(big.Rat).SetInt64(n), true
+	}
+	switch v := v.(type) {
+	case float64:
+		r := new(big.Rat).SetFloat64(v)
+		return r, r != nil
+	case Currency:
+		return big.NewRat(int64(v), 1e4), true
+	case *big.Int:
+		return new(big.Rat).SetInt(v), true
+	case *big.Rat:
+		return v, true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case Currency:
+		return v.Float64(), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	}
+	return 0, false
+}
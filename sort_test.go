@@ -0,0 +1,67 @@
+package dbf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestScanSorted(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)},
+		"  10delta", "   9alpha", "* 11bravo", "  10alpha", "   9alpha")
+	tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	var order []int
+	collect := func(i int, rec Record) error {
+		order = append(order, i)
+		return nil
+	}
+	if err = tbl.ScanSorted([]string{"ID"}, collect); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := []int{1, 4, 0, 3}; !reflect.DeepEqual(order, expected) {
+		t.Fatalf("wrong order by ID: got %v, expected %v", order, expected)
+	}
+
+	order = nil
+	if err = tbl.ScanSorted([]string{"NAME", "ID"}, collect); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := []int{1, 4, 3, 0}; !reflect.DeepEqual(order, expected) {
+		t.Fatalf("wrong order by NAME, ID: got %v, expected %v", order, expected)
+	}
+
+	var buf bytes.Buffer
+	if err = tbl.WriteFixedWidth(&buf, FixedWidthOptions{SortBy: []string{"NAME"}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := "  9alpha\n 10alpha\n  9alpha\n 10delta\n"; buf.String() != expected {
+		t.Fatalf("wrong sorted export:\n%s", buf.String())
+	}
+}
+
+func TestScanSortedExact(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 19, 0)},
+		"    9007199254740993", "    9007199254740992", "                  10", "                   9")
+	var order []int
+	collect := func(i int, rec Record) error {
+		order = append(order, i)
+		return nil
+	}
+	for _, opts := range [][]Option{nil, {WithNumericStrings()}} {
+		tbl, err := OpenTable(bytes.NewReader(table), int64(len(table)), opts...)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		order = nil
+		if err = tbl.ScanSorted([]string{"ID"}, collect); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if expected := []int{3, 2, 1, 0}; !reflect.DeepEqual(order, expected) {
+			t.Fatalf("wrong order with %d options: got %v, expected %v", len(opts), order, expected)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// Stats summarizes the contents of a table.
+type Stats struct {
+	Records int          `json:"records"` // live records that could be decoded
+	Deleted int          `json:"deleted"`
+	Errors  int          `json:"errors"` // records that couldn't be decoded
+	Fields  []FieldStats `json:"fields"`
+
+	// What the statistics were computed from, to tell when they're stale.
+	Size   int64  `json:"size"`
+	Nrec   int    `json:"nrec"`
+	Header uint32 `json:"header"` // CRC-32 of the header, modification date included
+}
+
+// FieldStats summarizes the values of one field.
+type FieldStats struct {
+	Name  string `json:"name"`
+	Blank int    `json:"blank"`         // records in which the field is blank
+	Min   string `json:"min,omitempty"` // smallest value that isn't blank, normalized as by Record.Hash
+	Max   string `json:"max,omitempty"`
+}
+
+// Stats reads the whole table and computes its statistics.  Numbers are
+// compared numerically and everything else as text, as by ScanSorted.
+func (t *Table) Stats() (*Stats, error) {
+	s := &Stats{Size: t.size, Nrec: t.nrec, Fields: make([]FieldStats, len(t.fields))}
+	var err error
+	if s.Header, err = t.headerCRC(); err != nil {
+		return nil, err
+	}
+	if s.Deleted, err = t.DeletedCount(); err != nil {
+		return nil, err
+	}
+	min := make([]interface{}, len(t.fields))
+	max := make([]interface{}, len(t.fields))
+	for j := range t.fields {
+		s.Fields[j].Name = t.FieldName(j)
+	}
+
+	it := t.Iterate()
+	for {
+		rec, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if _, ok := err.(*RecordError); ok {
+			s.Errors++
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		s.Records++
+		pos := 1
+		for j, f := range t.fields {
+			raw := it.buf[pos : pos+int(f.Len)]
+			pos += int(f.Len)
+			v := rec[t.FieldName(j)]
+			if len(bytes.TrimSpace(raw)) == 0 {
+				s.Fields[j].Blank++
+				continue
+			}
+			if min[j] == nil || compareValues(v, min[j]) < 0 {
+				min[j] = v
+			}
+			if max[j] == nil || compareValues(v, max[j]) > 0 {
+				max[j] = v
+			}
+		}
+	}
+	for j := range t.fields {
+		s.Fields[j].Min, s.Fields[j].Max = normalize(min[j]), normalize(max[j])
+	}
+	return s, nil
+}
+
+func (t *Table) headerCRC() (uint32, error) {
+	buf := make([]byte, t.headerlen)
+	if err := readFullAt(t.src, buf, 0); err != nil {
+		return 0, err
+	}
+	return crc32.ChecksumIEEE(buf), nil
+}
+
+// CachedStats is like Stats, but keeps the statistics in a sidecar file,
+// such as the table's name with a .dbfstats extension, so that repeated runs
+// over a large read-only table only pay for the first pass once.  The
+// sidecar is used if it matches the table's size, record count and header,
+// which includes the modification date, and is rewritten otherwise.  Edits
+// that change none of those aren't noticed.
+func (t *Table) CachedStats(sidecar string) (*Stats, error) {
+	if data, err := ioutil.ReadFile(sidecar); err == nil {
+		var s Stats
+		crc, err := t.headerCRC()
+		if err != nil {
+			return nil, err
+		}
+		if json.Unmarshal(data, &s) == nil && s.Size == t.size && s.Nrec == t.nrec && s.Header == crc {
+			return &s, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	s, err := t.Stats()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(sidecar, data, 0644); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,63 @@
+package dbf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	tbl, err := OpenTable(bytes.NewReader(scanTable), int64(len(scanTable)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	s, err := tbl.Stats()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if s.Records != 2 || s.Deleted != 1 || s.Errors != 1 {
+		t.Fatalf("wrong counts: %+v", s)
+	}
+	if f := s.Fields[0]; f.Name != "ID" || f.Min != "1" || f.Max != "4" || f.Blank != 0 {
+		t.Fatalf("wrong stats for ID: %+v", f)
+	}
+	if f := s.Fields[1]; f.Min != "alpha" || f.Max != "delta" {
+		t.Fatalf("wrong stats for NAME: %+v", f)
+	}
+}
+
+func TestCachedStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.RemoveAll(dir)
+	sidecar := filepath.Join(dir, "scan.dbfstats")
+
+	tbl, _ := OpenTable(bytes.NewReader(scanTable), int64(len(scanTable)))
+	if _, err = tbl.CachedStats(sidecar); err != nil {
+		t.Fatalf("%s", err)
+	}
+	// Doctor the sidecar to prove that it's used while it's fresh.
+	ioutil.WriteFile(sidecar, bytes.Replace(mustRead(t, sidecar), []byte(`"records":2`), []byte(`"records":99`), 1), 0644)
+	if s, err := tbl.CachedStats(sidecar); err != nil || s.Records != 99 {
+		t.Fatalf("expected the cached stats, got %+v, %v", s, err)
+	}
+
+	changed := append([]byte(nil), scanTable...)
+	changed[3]++ // a different modification date
+	tbl, _ = OpenTable(bytes.NewReader(changed), int64(len(changed)))
+	if s, err := tbl.CachedStats(sidecar); err != nil || s.Records != 2 {
+		t.Fatalf("expected fresh stats for a modified table, got %+v, %v", s, err)
+	}
+}
+
+func mustRead(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return data
+}
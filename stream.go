@@ -0,0 +1,69 @@
+package dbf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// A StreamReader reads a table in a single pass from a source that can't
+// seek, such as an HTTP response body, a pipe or a gzip stream.  Only the
+// header and the record being decoded are held in memory.  Records are read
+// with Next, NextInto and RecNo, which work as an Iterator's.
+type StreamReader struct {
+	*Iterator
+}
+
+// NewStreamReader reads the header of the table in r, leaving r positioned
+// at the first record.  A gzip-compressed stream is detected, as by
+// Decompress, and inflated on the fly.  Memo fields need a memo file
+// supplied with WithMemo or WithMemoBytes.
+func NewStreamReader(r io.Reader, opts ...Option) (*StreamReader, error) {
+	dr, err := Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(dr, 64*1024)
+	head := make([]byte, 32)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return nil, err
+	}
+	headerlen := int(binary.LittleEndian.Uint16(head[8:10]))
+	if headerlen < len(head)+1 {
+		return nil, fmt.Errorf("header length %d is too short", headerlen)
+	}
+	head = append(head, make([]byte, headerlen-len(head))...)
+	if _, err := io.ReadFull(br, head[32:]); err != nil {
+		return nil, err
+	}
+	t, err := newTable(memSource(head), int64(len(head)), opts)
+	if err != nil {
+		return nil, err
+	}
+	t.checkLayout()
+	t.log(levelInfo, "dbf: opened stream", "records", t.nrec, "fields", len(t.fields))
+	return &StreamReader{&Iterator{t: t, r: br, buf: make([]byte, t.recordlen)}}, nil
+}
+
+// Len returns the number of records the header promises, deleted ones
+// included.
+func (s *StreamReader) Len() int {
+	return s.t.nrec
+}
+
+// ModDate returns the table's modification date, as Table.ModDate does.
+func (s *StreamReader) ModDate() (int, int, int) {
+	return s.t.ModDate()
+}
+
+// FieldNames returns the names of the table's fields, as Table.FieldNames
+// does.
+func (s *StreamReader) FieldNames() []string {
+	return s.t.FieldNames()
+}
+
+// Fields describes the table's fields, as Table.Fields does.
+func (s *StreamReader) Fields() []FieldInfo {
+	return s.t.Fields()
+}
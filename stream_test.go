@@ -0,0 +1,64 @@
+package dbf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestStreamReader(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(scanTable)
+	zw.Close()
+	zr, err := gzip.NewReader(&gz)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	s, err := NewStreamReader(zr)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if s.Len() != 4 || len(s.FieldNames()) != 2 {
+		t.Fatalf("wrong header: %d records, fields %v", s.Len(), s.FieldNames())
+	}
+	if rec, err := s.Next(); err != nil || rec["NAME"] != "alpha" {
+		t.Fatalf("expected record 0, got %v, %v", rec, err)
+	}
+	if _, err := s.Next(); err == nil || s.RecNo() != 2 {
+		t.Fatalf("expected an error for record 2, got %v at %d", err, s.RecNo())
+	}
+	if rec, err := s.Next(); err != nil || rec["NAME"] != "delta" {
+		t.Fatalf("expected record 3, got %v, %v", rec, err)
+	}
+	if _, err := s.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+
+	if _, err = NewStreamReader(bytes.NewReader(scanTable[:40])); err == nil {
+		t.Fatalf("expected an error for a truncated header")
+	}
+}
+
+func TestStreamReaderShortRecordLength(t *testing.T) {
+	data := append([]byte(nil), scanTable...)
+	data[10], data[11] = 0, 0
+	if _, err := NewStreamReader(bytes.NewReader(data)); err == nil {
+		t.Fatalf("expected an error for a record length of 0")
+	}
+	var warnings []Anomaly
+	s, err := NewStreamReader(bytes.NewReader(data), WithLenient(), WithWarnings(func(a Anomaly) {
+		warnings = append(warnings, a)
+	}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(warnings) != 1 || warnings[0].Severity != SeverityError || warnings[0].Offset != 10 {
+		t.Fatalf("expected a warning about the record length, got %v", warnings)
+	}
+	if rec, err := s.Next(); err != nil || rec["NAME"] != "alpha" {
+		t.Fatalf("expected record 0 at the fields' length, got %v, %v", rec, err)
+	}
+}
@@ -0,0 +1,476 @@
+package dbf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// A Table describes a DBF file: its schema and header metadata, and the
+// source its records are read from.  A Table never changes once opened and
+// doesn't track a read position, so it is safe for concurrent use; state for
+// walking through the records lives in a Cursor instead.
+type Table struct {
+	src              io.ReaderAt
+	size             int64
+	version          byte
+	ldid             byte // language driver ID
+	year, month, day int
+	nrec             int
+	fields           []Field
+	names            []string // of level 7 fields, whose names are too long for a Field
+	descOffsets      []int    // of each field's descriptor in the header
+	descLen          int      // of each descriptor, 32 bytes or 48 in a level 7 header
+	headerlen        uint16   // in bytes
+	recordlen        uint16   // length of each record, in bytes
+	headerRecordlen  uint16   // as given by the header, which WithLenient may have overruled
+	datalen          int      // bytes of each record covered by fields, deleted flag included
+	logger           logger
+	slowScan         time.Duration // 0 for defaultSlowScan
+	metrics          Metrics
+	crypts           map[string]FieldCrypt
+	redactions       []redaction
+	epochs           map[string]time.Time // of fields holding day numbers
+	numericStrings   bool
+	bigNumbers       bool
+	codes            map[string]Codes
+	lenient          bool
+	computed         []computed
+	provenance       string // column holding each record's Provenance, if any
+	source           string // name of the table in its records' Provenance
+	formatters       map[string]Formatter
+	typeFormatters   map[reflect.Type]Formatter
+	selected         []bool // fields a Select view decodes, nil for all
+	memo             *memoFile
+	indexSrcs        []indexSource
+	indexes          []*Index
+	charset          *Charset
+	detectCharset    bool
+	limits           Limits
+	warnFn           func(Anomaly)
+	closers          []io.Closer // files opened by the Table itself
+	latch            *latch      // shared with the Editor writing the table, if any
+	optErr           error       // the first invalid Option, returned by OpenTable
+	journal          *journal
+}
+
+// An Option configures a Table as it is opened.
+type Option func(*Table)
+
+// OpenTable reads the header of the size-byte table in r.
+func OpenTable(r io.ReaderAt, size int64, opts ...Option) (*Table, error) {
+	t, err := newTable(r, size, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.checkLayout()
+	t.checkSize()
+	t.log(levelInfo, "dbf: opened table", "records", t.nrec, "fields", len(t.fields))
+	return t, nil
+}
+
+// newTable applies opts and reads the header, and those of the memo and
+// index files if there are any.
+func newTable(r io.ReaderAt, size int64, opts []Option) (*Table, error) {
+	t := &Table{src: r, size: size}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.optErr != nil {
+		return nil, t.optErr
+	}
+	if err := t.readHeader(io.NewSectionReader(r, 0, size)); err != nil {
+		t.log(levelError, "dbf: can't open table", "err", err)
+		return nil, err
+	}
+	if t.memo != nil && t.hasMemo() {
+		if err := t.memo.open(t.version); err != nil {
+			t.log(levelError, "dbf: can't open memo file", "err", err)
+			return nil, err
+		}
+	}
+	if err := t.openIndexes(); err != nil {
+		t.log(levelError, "dbf: can't open index", "err", err)
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Table) readHeader(r io.ReadSeeker) error {
+	var h header
+	err := binary.Read(r, binary.LittleEndian, &h)
+	if err == io.EOF {
+		return fmt.Errorf("file is empty")
+	} else if err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("header is truncated: %s", err)
+	} else if err != nil {
+		return err
+	}
+	// The descriptors end with a 0x0D terminator, which is normally the
+	// last byte of the header.  Visual FoxPro follows it with a 263-byte
+	// backlink to the table's database container.
+	eoh := int(h.Headerlen) - 1
+	var fields []Field
+	level7 := false
+	switch h.Version {
+	case 0x03, 0x83, 0x8B, 0xF5, 0xE5: // the memo variants share the dBase III layout
+	case 0x04, 0x8C:
+		// dBase 7 tables normally have a level 7 header, but some tools
+		// write the dBase III layout with the same version byte.
+		hdr := make([]byte, h.Headerlen)
+		if _, err := r.Seek(0, 0); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return fmt.Errorf("header is truncated: %s", io.ErrUnexpectedEOF)
+		}
+		fields, t.names, level7, err = readLevel7(hdr, h.Version)
+		if (!level7 || err != nil) && (eoh-0x20)%32 == 0 && hdr[eoh] == 0x0D {
+			fields, t.names, level7, err = nil, nil, false, nil
+		} else if err != nil {
+			return err
+		} else if !level7 {
+			return fmt.Errorf("dBase 7 header has neither the level 7 nor the dBase III layout")
+		}
+	case 0x30, 0x31, 0x32:
+		eoh -= 263
+	default:
+		return fmt.Errorf("unexepected file version: %d\n", h.Version)
+	}
+	t.version = h.Version
+	if _, err := r.Seek(29, 0); err != nil {
+		return err
+	}
+	var ldid [1]byte
+	if _, err := io.ReadFull(r, ldid[:]); err != nil {
+		return fmt.Errorf("header is truncated: %s", io.ErrUnexpectedEOF)
+	}
+	t.ldid = ldid[0]
+	if t.detectCharset && t.charset == nil {
+		t.charset = CharsetFor(t.ldid)
+	}
+
+	if !level7 {
+		if _, err := r.Seek(0x20, 0); err != nil {
+			return err
+		}
+		for offset := 0x20; offset < eoh; offset += 32 {
+			f := Field{}
+			if err = binary.Read(r, binary.LittleEndian, &f); err != nil {
+				return fmt.Errorf("header is truncated in the field descriptor at offset %d: %s", offset, io.ErrUnexpectedEOF)
+			}
+			if err = f.validate(h.Version); err != nil {
+				return err
+			}
+			fields = append(fields, f)
+		}
+
+		br := bufio.NewReader(r)
+		if b, err := br.ReadByte(); err == io.EOF {
+			return fmt.Errorf("header is truncated before its terminator at offset %d: %s", eoh, io.ErrUnexpectedEOF)
+		} else if err != nil {
+			return err
+		} else if b != 0x0D {
+			return fmt.Errorf("Header was supposed to end at offset %d, but found byte %#x there instead of expected byte 0x0D\n", eoh, b)
+		}
+	}
+
+	t.year, t.month, t.day = 1900+int(h.Year), int(h.Month), int(h.Day)
+	t.nrec = int(h.Nrec)
+	t.fields = fields
+	t.descLen = 32
+	first := 0x20
+	if level7 {
+		t.descLen, first = level7Descriptor, level7Fields
+	}
+	t.descOffsets = make([]int, len(fields))
+	for i := range fields {
+		t.descOffsets[i] = first + i*t.descLen
+	}
+	t.datalen = 1
+	for _, f := range fields {
+		t.datalen += int(f.Len)
+	}
+	t.headerlen, t.recordlen, t.headerRecordlen = h.Headerlen, h.Recordlen, h.Recordlen
+
+	// Records shorter than their fields would overlap.  In lenient mode the
+	// fields are trusted over the header.
+	if int(h.Recordlen) < t.datalen {
+		if !t.lenient || t.datalen > 0xFFFF {
+			return fmt.Errorf("header gives a record length of %d bytes, but the fields take up %d", h.Recordlen, t.datalen)
+		}
+		t.recordlen = uint16(t.datalen)
+		t.log(levelWarn, "dbf: overruled short record length", "header", h.Recordlen, "fields", t.datalen)
+	}
+	return nil
+}
+
+func (t *Table) ModDate() (int, int, int) {
+	return t.year, t.month, t.day
+}
+
+// Len returns the number of records in the table, deleted ones included.
+func (t *Table) Len() int {
+	return t.nrec
+}
+
+func (t *Table) FieldName(i int) (name string) {
+	if t.names != nil {
+		return t.names[i]
+	}
+	return strings.TrimRight(string(t.fields[i].Name[:]), "\x00")
+}
+
+func (t *Table) FieldNames() (names []string) {
+	for i := range t.fields {
+		if t.isSelected(i) {
+			names = append(names, t.FieldName(i))
+		}
+	}
+	if t.provenance != "" {
+		names = append(names, t.provenance)
+	}
+	for _, c := range t.computed {
+		names = append(names, c.name)
+	}
+	return
+}
+
+// FieldInfo describes a field of a table.
+type FieldInfo struct {
+	Name        string
+	Type        byte   // type code, such as 'C' or 'N'
+	Len         int    // width in bytes
+	Decimals    int    // decimal places of numeric fields
+	NumericType string // narrowest Go type for the values, see Field.NumericType
+}
+
+// Fields describes the table's fields, in file order.  Computed columns
+// aren't included.
+func (t *Table) Fields() []FieldInfo {
+	var infos []FieldInfo
+	for i, f := range t.fields {
+		if t.isSelected(i) {
+			infos = append(infos, FieldInfo{t.FieldName(i), f.Type, int(f.Len), int(f.DecimalPlaces), f.NumericType()})
+		}
+	}
+	return infos
+}
+
+// Schema returns a copy of the table's field descriptors, for instance to
+// create a Writer for a table of the same layout.
+func (t *Table) Schema() []Field {
+	var fields []Field
+	for i, f := range t.fields {
+		if t.isSelected(i) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// recordOffset returns the position of record i in the file.
+func (t *Table) recordOffset(i int) int64 {
+	return int64(t.headerlen) + int64(t.recordlen)*int64(i)
+}
+
+// Record reads and decodes record i, counting from 0.  Deleted records are
+// reported as ErrDeleted.
+func (t *Table) Record(i int) (rec Record, err error) {
+	rec, err = t.read(i)
+	if err != nil && err != ErrDeleted {
+		t.log(levelWarn, "dbf: can't read record", "record", i, "err", err)
+	}
+	return rec, err
+}
+
+func (t *Table) read(i int) (rec Record, err error) {
+	buf, err := t.readRaw(i)
+	if err != nil {
+		return nil, err
+	}
+	return t.parse(i, buf)
+}
+
+// parse checks the deleted flag of raw record i and decodes it.
+func (t *Table) parse(i int, buf []byte) (Record, error) {
+	if buf[0] == '*' {
+		return nil, ErrDeleted
+	} else if buf[0] != ' ' && !t.lenient {
+		t.count(MetricDecodeErrors, 1)
+		return nil, fmt.Errorf("record %d contained an unexpected value in the deleted flag: %#x", i, buf[0])
+	} else if buf[0] != ' ' {
+		t.log(levelWarn, "dbf: read unexpected deleted flag as a live record", "record", i, "flag", buf[0])
+	}
+	if buf[0] != ' ' && t.warnFn != nil {
+		t.warn(SeverityWarning, t.recordOffset(i), i, "", "unexpected deleted flag %#x, read as a live record", buf[0])
+	}
+	t.checkRecord(i, buf)
+	return t.decodeRecord(i, buf)
+}
+
+// RawRecord returns the deleted flag and fields of record i exactly as
+// stored, for instance to write it back unchanged with Writer.WriteFrom.
+// Encrypted fields are returned still encrypted.
+func (t *Table) RawRecord(i int) ([]byte, error) {
+	buf, err := t.readRaw(i)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf...), nil
+}
+
+// readRaw returns the bytes of record i, deleted flag included.  For tables
+// held in memory they are a slice of the table itself and must not be
+// modified.
+func (t *Table) readRaw(i int) ([]byte, error) {
+	if i < 0 || i >= t.nrec {
+		return nil, fmt.Errorf("record %d is out of range, table has %d records", i, t.nrec)
+	}
+	if m, ok := t.src.(memSource); ok {
+		off := t.recordOffset(i)
+		if off+int64(t.datalen) > int64(len(m)) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return m[off : off+int64(t.datalen)], nil
+	}
+	buf := make([]byte, t.datalen)
+	src := t.src
+	if t.latch != nil {
+		src = latchedReaderAt{src, t.latch}
+	}
+	if err := readFullAt(src, buf, t.recordOffset(i)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readFullAt fills buf from r at off.  Unlike a bare ReadAt, it doesn't
+// report io.EOF for a read that ends exactly at the end of the file.
+func readFullAt(r io.ReaderAt, buf []byte, off int64) error {
+	n, err := r.ReadAt(buf, off)
+	if n == len(buf) {
+		return nil
+	} else if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// decodeRecord decodes the fields of a raw record, ignoring its deleted flag.
+func (t *Table) decodeRecord(recno int, buf []byte) (rec Record, err error) {
+	rec = make(Record)
+	pos, size := 1, 0
+	for i, f := range t.fields {
+		name, raw := t.FieldName(i), buf[pos:pos+int(f.Len)]
+		pos += int(f.Len)
+		if !t.isSelected(i) {
+			continue
+		}
+		if c, ok := t.crypts[name]; ok {
+			if raw, err = c.Decrypt(raw); err != nil {
+				t.count(MetricDecodeErrors, 1)
+				return nil, err
+			}
+		}
+		limit := t.limits.limit(name, f)
+		if f.isMemo() {
+			rec[name], err = t.readMemo(raw, limit)
+		} else {
+			rec[name], err = decode(t.version, f, raw)
+			if f.Type == 'N' || f.Type == 'F' {
+				rec[name], err = t.number(name, raw, rec[name], err)
+			}
+			if err != nil && t.lenient {
+				t.count(MetricDecodeErrors, 1)
+				t.log(levelWarn, "dbf: read undecodable value as nil", "record", recno, "field", name, "err", err)
+				if t.warnFn != nil {
+					t.warn(SeverityError, t.recordOffset(recno)+int64(pos-int(f.Len)), recno, name, "%s", err)
+				}
+				rec[name], err = nil, nil
+			}
+		}
+		if e, ok := err.(*LimitError); ok {
+			e.Field = name
+		}
+		if err != nil {
+			t.count(MetricDecodeErrors, 1)
+			return nil, err
+		}
+		if s, ok := rec[name].(string); ok && t.charset != nil && (f.Type == 'C' || f.Type == 'M') {
+			rec[name] = t.charset.Decode([]byte(s))
+		}
+		n := valueSize(rec[name], f)
+		size += n
+		if limit > 0 && n > limit {
+			err = &LimitError{name, n, limit}
+		} else if t.limits.Record > 0 && size > t.limits.Record {
+			err = &LimitError{"", size, t.limits.Record}
+		}
+		if err != nil {
+			t.count(MetricDecodeErrors, 1)
+			return nil, err
+		}
+		if codes, ok := t.codes[name]; ok {
+			rec[name] = codes.value(raw, rec[name])
+		}
+	}
+	t.redact(rec)
+	if t.provenance != "" {
+		rec[t.provenance] = t.Provenance(recno)
+	}
+	if err = t.addComputed(rec); err != nil {
+		t.count(MetricDecodeErrors, 1)
+		return nil, err
+	}
+	t.count(MetricRecordsRead, 1)
+	t.count(MetricBytesRead, int64(t.recordlen))
+	return rec, nil
+}
+
+// A Cursor walks through the records of a Table in order.  Each Cursor keeps
+// its own position, so several can iterate over the same Table at once, but a
+// single Cursor must not be shared between goroutines.
+type Cursor struct {
+	t    *Table
+	next int // record to be read by the next call to Next
+}
+
+// NewCursor returns a Cursor positioned before the first record.
+func (t *Table) NewCursor() *Cursor {
+	return &Cursor{t: t}
+}
+
+// Next returns the next record that isn't deleted, or io.EOF once all records
+// have been read.  A record that can't be decoded is reported as a
+// *RecordError; the Cursor moves past it, so iteration can carry on.
+func (c *Cursor) Next() (Record, error) {
+	for c.next < c.t.nrec {
+		i := c.next
+		c.next++
+		rec, err := c.t.Record(i)
+		if err == ErrDeleted {
+			c.t.count(MetricDeletedSkipped, 1)
+			continue
+		} else if err != nil {
+			return nil, &RecordError{i, c.t.recordOffset(i), err}
+		}
+		return rec, nil
+	}
+	return nil, io.EOF
+}
+
+// RecNo returns the number of the record most recently returned by Next, or
+// -1 if Next hasn't been called.
+func (c *Cursor) RecNo() int {
+	return c.next - 1
+}
+
+// Seek positions the Cursor so that Next continues from record i.
+func (c *Cursor) Seek(i int) {
+	c.next = i
+}
@@ -0,0 +1,159 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	tbl, err := OpenTable(bytes.NewReader(scanTable), int64(len(scanTable)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if tbl.Len() != 4 {
+		t.Fatalf("wrong Len(): got %d, expected 4", tbl.Len())
+	}
+
+	c := tbl.NewCursor()
+	var recnos []int
+	var failed []int
+	for {
+		_, err := c.Next()
+		if err == io.EOF {
+			break
+		} else if e, ok := err.(*RecordError); ok {
+			failed = append(failed, e.Record)
+			continue
+		} else if err != nil {
+			t.Fatalf("%s", err)
+		}
+		recnos = append(recnos, c.RecNo())
+	}
+	if len(recnos) != 2 || recnos[0] != 0 || recnos[1] != 3 {
+		t.Fatalf("wrong records returned: %v", recnos)
+	}
+	if len(failed) != 1 || failed[0] != 2 {
+		t.Fatalf("wrong records failed: %v", failed)
+	}
+
+	c.Seek(3)
+	if rec, err := c.Next(); err != nil || rec["NAME"] != "delta" {
+		t.Fatalf("Next() after Seek(3) returned %v, %v", rec, err)
+	}
+}
+
+func TestConcurrentCursors(t *testing.T) {
+	tbl := reader.Table
+	var wg sync.WaitGroup
+	for n := 0; n < 4; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec, err := tbl.NewCursor().Next()
+			if err != nil || rec["Name"] != "Abbotsbury" {
+				t.Errorf("Next() returned %v, %v", rec, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestVersions(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	plain := buildTable(fields, "   1alpha")
+	headerlen := 32 + 32*len(fields) + 1
+
+	// Visual FoxPro puts a 263-byte backlink between the header and the
+	// records.
+	vfp := append(append(append([]byte(nil), plain[:headerlen]...), make([]byte, 263)...), plain[headerlen:]...)
+	binary.LittleEndian.PutUint16(vfp[8:], uint16(headerlen+263))
+
+	for _, tc := range []struct {
+		version byte
+		data    []byte
+	}{
+		{0x03, plain}, {0x04, plain}, {0x83, plain}, {0x8B, plain}, {0xF5, plain},
+		{0x30, vfp}, {0x31, vfp}, {0x32, vfp},
+	} {
+		data := append([]byte(nil), tc.data...)
+		data[0] = tc.version
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("version %#02x: %s", tc.version, err)
+		}
+		if rec, err := tbl.Record(0); err != nil || rec["NAME"] != "alpha" {
+			t.Fatalf("version %#02x: Record(0) returned %v, %v", tc.version, rec, err)
+		}
+	}
+
+	level7 := append([]byte(nil), plain...)
+	level7[0] = 0x04
+	binary.LittleEndian.PutUint16(level7[8:], 68+48+1)
+	if _, err := OpenTable(bytes.NewReader(level7), int64(len(level7))); err == nil {
+		t.Fatalf("expected an error for a malformed level 7 header")
+	}
+	plain[0] = 0x02
+	if _, err := OpenTable(bytes.NewReader(plain), int64(len(plain))); err == nil {
+		t.Fatalf("expected an error for an unknown version")
+	}
+}
+
+func TestEmptyTable(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	data := buildTable(fields)
+	headerlen := len(data) - 1
+
+	// A table with no records may or may not end with an end-of-file marker.
+	for _, data := range [][]byte{data, data[:headerlen]} {
+		r, err := NewReaderFromBytes(data)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if _, err = r.Iterate().Next(); err != io.EOF {
+			t.Fatalf("expected io.EOF from Iterator, got %v", err)
+		}
+		if _, err = r.NewCursor().Next(); err != io.EOF {
+			t.Fatalf("expected io.EOF from Cursor, got %v", err)
+		}
+		if recs, err := r.ReadAll(); err != nil || len(recs) != 0 {
+			t.Fatalf("expected no records, got %v, %v", recs, err)
+		}
+		s, err := NewStreamReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if _, err = s.Next(); err != io.EOF {
+			t.Fatalf("expected io.EOF from StreamReader, got %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err = r.ToCSV(&buf); err != nil || buf.String() != "ID,NAME\n" {
+			t.Fatalf("expected just a header row, got %q, %v", buf.String(), err)
+		}
+		buf.Reset()
+		footer := func(n int) string { return fmt.Sprintf("%d records", n) }
+		if err = r.WriteFixedWidth(&buf, FixedWidthOptions{ColumnNames: true, Footer: footer}); err != nil || buf.String() != "ID NAME \n0 records\n" {
+			t.Fatalf("expected just column names and a footer, got %q, %v", buf.String(), err)
+		}
+	}
+
+	// A file that ends before its header does isn't a table, empty or not.
+	for _, tc := range []struct {
+		data []byte
+		err  string
+	}{
+		{nil, "file is empty"},
+		{data[:20], "header is truncated"},
+		{data[:40], "header is truncated"},
+		{data[:headerlen-1], "header is truncated"},
+	} {
+		if _, err := NewReaderFromBytes(tc.data); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%d bytes: expected an error containing %q, got %v", len(tc.data), tc.err, err)
+		}
+	}
+}
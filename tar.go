@@ -0,0 +1,86 @@
+package dbf
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// TarTable is a table found inside a tar archive.
+type TarTable struct {
+	*Reader
+	Name string // path of the .dbf member within the archive
+	Memo []byte // contents of the matching .dbt or .fpt member, if present
+}
+
+// ReadTar walks a tar stream, the usual shape of old FoxPro backups, and opens
+// every .dbf member it contains.  Compressed archives (.tar.gz) are inflated
+// transparently.  A .dbt or .fpt member sharing a .dbf member's base name is
+// attached to it as its memo file; two such members, a .dbt and an .fpt, are
+// an error, since either could be the right one.  Members are buffered in
+// memory, since a tar stream can't be read out of order.  Errors name the
+// member they concern.
+func ReadTar(r io.Reader) ([]*TarTable, error) {
+	r, err := Decompress(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	dbfs := make(map[string][]byte)
+	memos := make(map[string][]byte)
+	memoNames := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		ext := strings.ToLower(path.Ext(hdr.Name))
+		base := strings.ToLower(strings.TrimSuffix(hdr.Name, path.Ext(hdr.Name)))
+		switch ext {
+		case ".dbf", ".dbt", ".fpt":
+		default:
+			continue
+		}
+		buf, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", hdr.Name, err)
+		}
+		if ext != ".dbf" {
+			if other, ok := memoNames[base]; ok {
+				return nil, fmt.Errorf("%s: %s is also a memo file for the same table", hdr.Name, other)
+			}
+			memos[base], memoNames[base] = buf, hdr.Name
+			continue
+		}
+		dbfs[hdr.Name] = buf
+		names = append(names, hdr.Name)
+	}
+
+	// Tables are only opened once the whole archive has been read, since a
+	// memo file may come after its table.
+	var tables []*TarTable
+	for _, name := range names {
+		memo := memos[strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))]
+		var opts []Option
+		if memo != nil {
+			opts = append(opts, WithMemoBytes(memo))
+		}
+		dbr, err := NewReaderFromBytes(dbfs[name], opts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		tables = append(tables, &TarTable{Reader: dbr, Name: name, Memo: memo})
+	}
+	return tables, nil
+}
@@ -0,0 +1,69 @@
+package dbf
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// tarMember is a file to be put in a test archive.
+type tarMember struct {
+	name string
+	body []byte
+}
+
+func buildTar(members ...tarMember) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, m := range members {
+		tw.WriteHeader(&tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.body)), Typeflag: tar.TypeReg})
+		tw.Write(m.body)
+	}
+	tw.Close()
+	return &buf
+}
+
+func TestReadTar(t *testing.T) {
+	memo := []byte("memo contents")
+	tables, err := ReadTar(buildTar(
+		tarMember{"backup/README.TXT", []byte("hello")},
+		tarMember{"backup/CUST.DBF", testData},
+		tarMember{"backup/cust.fpt", memo},
+	))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(tables))
+	}
+	if tables[0].Name != "backup/CUST.DBF" || !bytes.Equal(tables[0].Memo, memo) {
+		t.Fatalf("wrong table: got %s with memo %q", tables[0].Name, tables[0].Memo)
+	}
+	if rec, err := tables[0].Read(0); err != nil || rec["Name"] != "Abbotsbury" {
+		t.Fatalf("Read(0) returned %v, %v", rec, err)
+	}
+}
+
+func TestReadTarErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		members []tarMember
+		member  string
+	}{
+		{"two memo files", []tarMember{
+			{"backup/CUST.DBF", testData},
+			{"backup/CUST.DBT", []byte("memo")},
+			{"backup/cust.fpt", []byte("memo")},
+		}, "backup/cust.fpt"},
+		{"broken table", []tarMember{
+			{"backup/CUST.DBF", testData},
+			{"backup/ORDERS.DBF", testData[:10]},
+		}, "backup/ORDERS.DBF"},
+	} {
+		_, err := ReadTar(buildTar(tc.members...))
+		if err == nil || !strings.HasPrefix(err.Error(), tc.member+": ") {
+			t.Errorf("%s: expected an error naming %s, got %v", tc.name, tc.member, err)
+		}
+	}
+}
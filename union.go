@@ -0,0 +1,246 @@
+package dbf
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// A Union presents several tables sharing one schema, such as one file per
+// month, as a single logical table.  Records are visited table by table, in
+// the order the tables were added, and each carries its provenance: the name
+// of its source and its record number within it.
+type Union struct {
+	names  []string
+	tables []*Table
+}
+
+// Add appends a table to the union under the given name, typically its file
+// name.  Its fields must match those of the tables already added in name,
+// type, width and decimal places.
+func (u *Union) Add(name string, t *Table) error {
+	if len(u.tables) > 0 {
+		if err := sameSchema(u.tables[0], t); err != nil {
+			return fmt.Errorf("%s doesn't match %s: %s", name, u.names[0], err)
+		}
+	}
+	u.names = append(u.names, name)
+	u.tables = append(u.tables, t)
+	return nil
+}
+
+func sameSchema(a, b *Table) error {
+	if len(a.fields) != len(b.fields) {
+		return fmt.Errorf("%d fields instead of %d", len(b.fields), len(a.fields))
+	}
+	for i, f := range a.fields {
+		g := b.fields[i]
+		if a.FieldName(i) != b.FieldName(i) || f.Type != g.Type || f.Len != g.Len || f.DecimalPlaces != g.DecimalPlaces {
+			return fmt.Errorf("field %d is %s %c(%d,%d) instead of %s %c(%d,%d)", i,
+				b.FieldName(i), g.Type, g.Len, g.DecimalPlaces, a.FieldName(i), f.Type, f.Len, f.DecimalPlaces)
+		}
+	}
+	return nil
+}
+
+// Schema returns the shared schema, for instance to create a Writer that
+// exports the union to a single file.  It is nil for an empty union.
+func (u *Union) Schema() []Field {
+	if len(u.tables) == 0 {
+		return nil
+	}
+	return u.tables[0].Schema()
+}
+
+// Len returns the total number of records in the union, deleted ones
+// included.
+func (u *Union) Len() int {
+	n := 0
+	for _, t := range u.tables {
+		n += t.nrec
+	}
+	return n
+}
+
+// Scan calls fn for every record in the union, skipping deleted records, with
+// the name of its source table and its record number there.  Filtering is left
+// to fn.  Like Table.Scan, it stops at the first record that can't be decoded,
+// or as soon as fn returns an error, and returns that error.
+func (u *Union) Scan(fn func(source string, i int, rec Record) error) error {
+	it := u.Iterate()
+	for {
+		rec, err := it.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", it.Source(), err)
+		}
+		if err = fn(it.Source(), it.RecNo(), rec); err != nil {
+			return err
+		}
+	}
+}
+
+// A UnionIterator streams through the records of a Union.
+type UnionIterator struct {
+	u   *Union
+	cur int // index of the table being read
+	it  *Iterator
+	eof bool // Next has returned io.EOF
+}
+
+// Iterate returns a UnionIterator positioned before the first record.
+func (u *Union) Iterate() *UnionIterator {
+	it := &UnionIterator{u: u}
+	if len(u.tables) > 0 {
+		it.it = u.tables[0].Iterate()
+	}
+	return it
+}
+
+// Next returns the next record that isn't deleted, moving on to the next
+// table as each one is exhausted, or io.EOF once all tables have been read.
+// Errors are reported as by Iterator.Next.
+func (it *UnionIterator) Next() (Record, error) {
+	for it.it != nil {
+		rec, err := it.it.Next()
+		if err != io.EOF {
+			return rec, err
+		}
+		if it.cur+1 == len(it.u.tables) {
+			break
+		}
+		it.cur++
+		it.it = it.u.tables[it.cur].Iterate()
+	}
+	it.eof = true
+	return nil, io.EOF
+}
+
+// Source returns the name of the table the most recent record came from.
+func (it *UnionIterator) Source() string {
+	if it.it == nil {
+		return ""
+	}
+	return it.u.names[it.cur]
+}
+
+// Provenance returns the provenance of the most recent record, with the name
+// its table was added to the union under as the source.  It returns the zero
+// Provenance before the first record and once Next has returned io.EOF.
+func (it *UnionIterator) Provenance() Provenance {
+	if it.it == nil || it.eof || it.RecNo() < 0 {
+		return Provenance{}
+	}
+	p := it.u.tables[it.cur].Provenance(it.RecNo())
+	p.Source = it.Source()
+	return p
+}
+
+// RecNo returns the number of the most recent record within its source table.
+func (it *UnionIterator) RecNo() int {
+	if it.it == nil {
+		return -1
+	}
+	return it.it.RecNo()
+}
+
+// ToCSV exports the records of the union that aren't deleted as Table.ToCSV
+// does, with an extra first column, named column, holding the name of each
+// record's source table.  Cells are formatted by the formatters of the
+// table each record comes from.  The union's tables are exported in one
+// pass, so CSVCheckpoints and CSVResume, whose record numbers belong to a
+// single table, aren't supported.
+func (u *Union) ToCSV(w io.Writer, column string, opts ...CSVOption) error {
+	c := csvConfig{comma: ','}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.save != nil || c.resume != (Checkpoint{}) {
+		return fmt.Errorf("a union's export can't be checkpointed")
+	}
+	if c.mapping == nil && len(u.tables) > 0 {
+		c.mapping = MappingFor(u.tables[0])
+	}
+	for i, t := range u.tables {
+		if err := c.mapping.Check(t); err != nil {
+			return fmt.Errorf("%s: %s", u.names[i], err)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = c.comma
+	if !c.noHeader {
+		if err := cw.Write(append([]string{column}, c.mapping.Columns()...)); err != nil {
+			return err
+		}
+	}
+	it := u.Iterate()
+	cells := make([]string, len(c.mapping)+1)
+	for {
+		rec, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%s: %s", it.Source(), err)
+		}
+		t := u.tables[it.cur]
+		row, err := c.mapping.Row(rec)
+		if err != nil {
+			return fmt.Errorf("%s: %s", it.Source(), &RecordError{it.RecNo(), t.recordOffset(it.RecNo()), err})
+		}
+		cells[0] = it.Source()
+		for i, v := range row {
+			cells[i+1] = c.cell(t, c.mapping[i].Field, v)
+		}
+		if err = cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteFixedWidth exports the records of the union that aren't deleted as
+// Table.WriteFixedWidth does, with an extra first column, named column and
+// width bytes wide, holding the name of each record's source table, padded
+// on the right.  A name longer than width is an error.  opts.SortBy orders
+// records within each table, and opts.Footer is given the union's total.
+func (u *Union) WriteFixedWidth(w io.Writer, column string, width int, opts FixedWidthOptions) error {
+	for _, name := range u.names {
+		if len(name) > width {
+			return fmt.Errorf("source %q is longer than the %d bytes of its column", name, width)
+		}
+	}
+	eol := opts.LineEnding
+	if eol == "" {
+		eol = "\n"
+	}
+	bw := bufio.NewWriter(w)
+	if opts.Header != "" {
+		bw.WriteString(opts.Header + eol)
+	}
+	if opts.ColumnNames {
+		if len(column) > width {
+			column = column[:width]
+		}
+		fmt.Fprintf(bw, "%-*s", width, column)
+		if len(u.tables) > 0 {
+			u.tables[0].writeColumnNames(bw, opts)
+		}
+		bw.WriteString(eol)
+	}
+	total := 0
+	for i, t := range u.tables {
+		n, err := t.writeFixedWidthRecords(bw, opts, []byte(fmt.Sprintf("%-*s", width, u.names[i])))
+		if err != nil {
+			return fmt.Errorf("%s: %s", u.names[i], err)
+		}
+		total += n
+	}
+	if opts.Footer != nil {
+		bw.WriteString(opts.Footer(total) + eol)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,89 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	jan := buildTable(fields, "   1alpha", "*  2bravo")
+	feb := buildTable(fields, "   3charl", "   4delta")
+	var u Union
+	for _, m := range []struct {
+		name string
+		data []byte
+	}{{"jan.dbf", jan}, {"feb.dbf", feb}} {
+		tbl, err := OpenTable(bytes.NewReader(m.data), int64(len(m.data)))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if err = u.Add(m.name, tbl); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if u.Len() != 4 {
+		t.Fatalf("expected 4 records, got %d", u.Len())
+	}
+
+	var seen []string
+	err := u.Scan(func(source string, i int, rec Record) error {
+		seen = append(seen, fmt.Sprintf("%s:%d:%s", source, i, rec["NAME"]))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := "jan.dbf:0:alpha feb.dbf:0:charl feb.dbf:1:delta"; strings.Join(seen, " ") != expected {
+		t.Fatalf("expected %s, got %v", expected, seen)
+	}
+
+	other := buildTable([]Field{mustField("ID", 'N', 4, 0), mustField("NAME", 'C', 5, 0)})
+	tbl, err := OpenTable(bytes.NewReader(other), int64(len(other)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = u.Add("mar.dbf", tbl); err == nil {
+		t.Fatalf("expected an error adding a table with a different schema")
+	}
+}
+
+func TestUnionExport(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}
+	var u Union
+	for _, m := range []struct {
+		name string
+		data []byte
+	}{{"jan.dbf", buildTable(fields, "   1alpha", "*  2bravo")}, {"feb.dbf", buildTable(fields, "   3charl")}} {
+		tbl, err := OpenTable(bytes.NewReader(m.data), int64(len(m.data)))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		u.Add(m.name, tbl)
+	}
+
+	var csv bytes.Buffer
+	if err := u.ToCSV(&csv, "SOURCE"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := "SOURCE,ID,NAME\njan.dbf,1,alpha\nfeb.dbf,3,charl\n"; csv.String() != expected {
+		t.Fatalf("expected CSV %q, got %q", expected, csv.String())
+	}
+	if err := u.ToCSV(&csv, "SOURCE", CSVResume(Checkpoint{1, 10})); err == nil {
+		t.Fatalf("expected an error resuming a union's export")
+	}
+
+	var fixed bytes.Buffer
+	footer := func(n int) string { return fmt.Sprintf("%d records", n) }
+	if err := u.WriteFixedWidth(&fixed, "SOURCE", 8, FixedWidthOptions{ColumnNames: true, Footer: footer}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := "SOURCE  ID NAME \njan.dbf   1alpha\nfeb.dbf   3charl\n2 records\n"; fixed.String() != expected {
+		t.Fatalf("expected fixed-width output %q, got %q", expected, fixed.String())
+	}
+	if err := u.WriteFixedWidth(&fixed, "SOURCE", 4, FixedWidthOptions{}); err == nil {
+		t.Fatalf("expected an error for a source longer than its column")
+	}
+}
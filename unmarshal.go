@@ -0,0 +1,193 @@
+package dbf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// scanner matches database/sql's Scanner, implemented by its Null types.
+type scanner interface {
+	Scan(src interface{}) error
+}
+
+// ReadInto decodes record i into the struct v points to.  Columns are matched
+// to struct fields by the rules of SchemaFromStruct, so a `dbf:"CUSTNAME"`
+// tag picks the column and `dbf:"-"` skips the field; columns without a
+// matching field, and fields without a matching column, are ignored.
+//
+// Values are converted to the field's type where that can be done without
+// loss.  Pointer fields are left nil for unknown values, and fields whose
+// type implements FieldUnmarshaler or database/sql's Scanner decode
+// themselves.  Fields tagged with an encoding option are decoded from that
+// code page.  Deleted records are reported as ErrDeleted, as by Record.
+func (t *Table) ReadInto(i int, v interface{}) error {
+	buf, err := t.readRaw(i)
+	if err != nil {
+		return err
+	}
+	rec, err := t.parse(i, buf)
+	if err != nil {
+		return err
+	}
+	return t.unmarshal(rec, buf, v)
+}
+
+// NextInto is like Next, but decodes the record into the struct v points to,
+// as ReadInto does.
+func (it *Iterator) NextInto(v interface{}) error {
+	rec, err := it.Next()
+	if err != nil {
+		return err
+	}
+	if err = it.t.unmarshal(rec, it.buf, v); err != nil {
+		return &RecordError{it.RecNo(), it.t.recordOffset(it.RecNo()), err}
+	}
+	return nil
+}
+
+// unmarshal copies the values of rec, whose raw bytes are in buf, into the
+// struct v points to.
+func (t *Table) unmarshal(rec Record, buf []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("can't decode a record into %T, expected a pointer to a struct", v)
+	}
+	raw := make(map[string][]byte)
+	pos := 1
+	for i, f := range t.fields {
+		raw[t.FieldName(i)] = buf[pos : pos+int(f.Len)]
+		pos += int(f.Len)
+	}
+
+	sv := rv.Elem()
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if sf.PkgPath != "" { // unexported
+			continue
+		}
+		name := columnName(sf)
+		val, ok := rec[name]
+		if name == "" || !ok {
+			continue
+		}
+		fv := sv.Field(i)
+		u, isUnmarshaler := fv.Addr().Interface().(FieldUnmarshaler)
+		cs := tagCharset(sf)
+		if (isUnmarshaler || cs != nil) && raw[name] != nil {
+			b := raw[name]
+			if c, ok := t.crypts[name]; ok {
+				var err error
+				if b, err = c.Decrypt(b); err != nil {
+					return fmt.Errorf("field %s: %s", name, err)
+				}
+			}
+			if isUnmarshaler {
+				if err := u.UnmarshalDBF(t.fields[t.fieldIndex(name)], b); err != nil {
+					return fmt.Errorf("field %s: %s", name, err)
+				}
+				continue
+			}
+			val = strings.TrimSpace(cs.Decode(b))
+		}
+		if err := setValue(fv, val); err != nil {
+			return fmt.Errorf("field %s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// fieldIndex returns the position of the named field, or -1.
+func (t *Table) fieldIndex(name string) int {
+	for i := range t.fields {
+		if t.FieldName(i) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// setValue stores a decoded field value in fv, converting it as needed.  A
+// blank date, which decodes to the zero Time, is stored as NULL: a nil
+// pointer, or an sql.NullTime that isn't valid.
+func setValue(fv reflect.Value, v interface{}) error {
+	if d, ok := v.(time.Time); ok && d.IsZero() {
+		v = nil
+	}
+	if s, ok := fv.Addr().Interface().(scanner); ok {
+		return s.Scan(widen(v)) // database/sql's drivers deliver int64
+	}
+	if fv.Kind() == reflect.Ptr {
+		if v == nil {
+			fv.Set(reflect.Zero(fv.Type()))
+			return nil
+		}
+		p := reflect.New(fv.Type().Elem())
+		if err := setValue(p.Elem(), v); err != nil {
+			return err
+		}
+		fv.Set(p)
+		return nil
+	}
+	if v == nil {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+
+	v = widen(v)
+	switch val := v.(type) {
+	case string:
+		if fv.Kind() == reflect.String {
+			fv.SetString(val)
+			return nil
+		}
+	case []byte:
+		if fv.Type() == reflect.TypeOf(val) {
+			fv.SetBytes(val)
+			return nil
+		}
+	case bool:
+		if fv.Kind() == reflect.Bool {
+			fv.SetBool(val)
+			return nil
+		}
+	case time.Time:
+		if fv.Type() == timeType {
+			fv.Set(reflect.ValueOf(val))
+			return nil
+		}
+	case int64:
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if !fv.OverflowInt(val) {
+				fv.SetInt(val)
+				return nil
+			}
+			return fmt.Errorf("%d overflows %s", val, fv.Type())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if val >= 0 && !fv.OverflowUint(uint64(val)) {
+				fv.SetUint(uint64(val))
+				return nil
+			}
+			return fmt.Errorf("%d overflows %s", val, fv.Type())
+		case reflect.Float32, reflect.Float64:
+			fv.SetFloat(float64(val))
+			return nil
+		}
+	case float64:
+		switch fv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			fv.SetFloat(val)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if val == float64(int64(val)) && !fv.OverflowInt(int64(val)) {
+				fv.SetInt(int64(val))
+				return nil
+			}
+			return fmt.Errorf("%v doesn't fit %s", val, fv.Type())
+		}
+	}
+	return fmt.Errorf("can't store %v (%T) in a %s", v, v, fv.Type())
+}
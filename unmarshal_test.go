@@ -0,0 +1,72 @@
+package dbf
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (c *cents) UnmarshalDBF(f Field, raw []byte) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(raw)), 64)
+	*c = cents(v*100 + 0.5)
+	return err
+}
+
+type customer struct {
+	ID      int16
+	Name    string `dbf:"CUSTNAME"`
+	Since   time.Time
+	Active  *bool
+	Balance cents
+	Region  sql.NullString
+	Note    string `dbf:"-"`
+}
+
+var customerTable = buildTable([]Field{
+	mustField("ID", 'N', 5, 0),
+	mustField("CUSTNAME", 'C', 6, 0),
+	mustField("SINCE", 'D', 8, 0),
+	mustField("ACTIVE", 'L', 1, 0),
+	mustField("BALANCE", 'N', 8, 2),
+	mustField("REGION", 'C', 2, 0),
+	mustField("NOTE", 'C', 4, 0),
+},
+	"    42acme  20110726T   12.34EUnote",
+	" 99999bogus 20110726?    0.00  note",
+)
+
+func TestReadInto(t *testing.T) {
+	tbl, err := OpenTable(bytes.NewReader(customerTable), int64(len(customerTable)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	var c customer
+	if err = tbl.ReadInto(0, &c); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if c.ID != 42 || c.Name != "acme" || !c.Since.Equal(time.Date(2011, 7, 26, 0, 0, 0, 0, time.UTC)) ||
+		c.Active == nil || !*c.Active || c.Balance != 1234 || c.Region.String != "EU" || !c.Region.Valid || c.Note != "" {
+		t.Fatalf("wrong struct %+v", c)
+	}
+	if err = tbl.ReadInto(1, &c); err == nil {
+		t.Fatalf("expected an error for an ID that overflows int16")
+	}
+	if err = tbl.ReadInto(0, c); err == nil {
+		t.Fatalf("expected an error decoding into a non-pointer")
+	}
+
+	it := tbl.Iterate()
+	if err = it.NextInto(&c); err != nil || c.Name != "acme" {
+		t.Fatalf("NextInto returned %+v, %v", c, err)
+	}
+	if err = it.NextInto(&c); err == nil {
+		t.Fatalf("expected an error for an ID that overflows int16")
+	}
+	if err = it.NextInto(&c); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
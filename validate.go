@@ -0,0 +1,100 @@
+package dbf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Severity grades an Anomaly.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"    // harmless deviation from the spec
+	SeverityWarning Severity = "warning" // data is readable but suspect
+	SeverityError   Severity = "error"   // data is lost or can't be decoded
+)
+
+// An Anomaly is a single deviation from the file format.
+type Anomaly struct {
+	Severity Severity `json:"severity"`
+	Offset   int64    `json:"offset"`          // position in the file, in bytes
+	Record   int      `json:"record"`          // record number, or -1 for the header
+	Field    string   `json:"field,omitempty"` // offending field, if any
+	Message  string   `json:"message"`
+}
+
+// An AnomalyReport lists everything Validate found wrong with a table, in
+// file order.  It marshals cleanly to JSON for data-quality audits.
+type AnomalyReport struct {
+	Anomalies []Anomaly `json:"anomalies"`
+}
+
+func (rep *AnomalyReport) add(sev Severity, offset int64, record int, field, format string, args ...interface{}) {
+	rep.Anomalies = append(rep.Anomalies, Anomaly{sev, offset, record, field, fmt.Sprintf(format, args...)})
+}
+
+// Validate reads the whole table and reports every deviation it finds: an
+// implausible modification date, a record length shorter than the fields,
+// which only a table opened WithLenient gets past, a record count that
+// doesn't match the file size, a missing end-of-file marker, unexpected
+// deleted flags and field values that can't be decoded.  The returned error
+// is only for I/O failures; a table that's merely malformed is described by
+// the report.
+func (t *Table) Validate() (*AnomalyReport, error) {
+	rep := &AnomalyReport{Anomalies: []Anomaly{}}
+	if t.month < 1 || t.month > 12 || t.day < 1 || t.day > 31 {
+		rep.add(SeverityWarning, 1, -1, "", "implausible modification date %d-%02d-%02d", t.year, t.month, t.day)
+	}
+
+	if int(t.headerRecordlen) < t.datalen {
+		rep.add(SeverityError, 10, -1, "", "header gives a record length of %d bytes, but the fields take up %d", t.headerRecordlen, t.datalen)
+	}
+
+	size := t.size
+	nrec := t.nrec
+	end := t.recordOffset(nrec)
+	switch {
+	case size < end:
+		nrec = 0
+		if size > int64(t.headerlen) && t.recordlen > 0 {
+			nrec = int((size - int64(t.headerlen)) / int64(t.recordlen))
+		}
+		rep.add(SeverityError, size, -1, "", "file is truncated: header promises %d records, but only %d are present", t.nrec, nrec)
+	case size == end:
+		rep.add(SeverityInfo, size, -1, "", "missing end-of-file marker 0x1A")
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(t.src, int64(t.headerlen), size-int64(t.headerlen)))
+	buf := make([]byte, t.recordlen)
+	for i := 0; i < nrec; i++ {
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		offset := t.recordOffset(i)
+		if buf[0] != ' ' && buf[0] != '*' {
+			rep.add(SeverityError, offset, i, "", "unexpected deleted flag %#x", buf[0])
+		}
+		pos := 1
+		for j, f := range t.fields {
+			if _, err := decode(t.version, f, buf[pos:pos+int(f.Len)]); err != nil {
+				rep.add(SeverityError, offset+int64(pos), i, t.FieldName(j), "%s", err)
+			}
+			pos += int(f.Len)
+		}
+	}
+
+	if size > end {
+		eof, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if eof != 0x1A {
+			rep.add(SeverityWarning, end, -1, "", "expected end-of-file marker 0x1A, found %#x", eof)
+		}
+		if size > end+1 {
+			rep.add(SeverityWarning, end+1, -1, "", "%d bytes of unexpected data after the end of the table", size-end-1)
+		}
+	}
+	return rep, nil
+}
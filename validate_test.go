@@ -0,0 +1,53 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0)}, "   1", "?  2", " N/A")
+	r, err := NewReader(bytes.NewReader(append(table, "junk"...)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rep, err := r.Validate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	b, _ := json.Marshal(rep)
+	expected := `{"anomalies":[` +
+		`{"severity":"error","offset":69,"record":1,"message":"unexpected deleted flag 0x3f"},` +
+		`{"severity":"error","offset":74,"record":2,"field":"ID","message":"strconv.ParseInt: parsing \"N/A\": invalid syntax"},` +
+		`{"severity":"warning","offset":78,"record":-1,"message":"4 bytes of unexpected data after the end of the table"}]}`
+	if string(b) != expected {
+		t.Fatalf("wrong report:\n got %s\nwant %s", b, expected)
+	}
+
+	rep, err = reader.Validate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(rep.Anomalies) != 1 || rep.Anomalies[0].Severity != SeverityError {
+		t.Fatalf("expected the truncated test file to be reported, got %+v", rep.Anomalies)
+	}
+}
+
+func TestValidateShortRecordLength(t *testing.T) {
+	table := buildTable([]Field{mustField("ID", 'N', 3, 0)}, "   1", "   2")
+	binary.LittleEndian.PutUint16(table[10:], 0)
+	table = table[:len(table)-3] // truncate the last record
+	r, err := NewReaderFromBytes(table, WithLenient())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rep, err := r.Validate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(rep.Anomalies) != 2 || rep.Anomalies[0].Offset != 10 || rep.Anomalies[1].Severity != SeverityError {
+		t.Fatalf("expected the record length and the truncation to be reported, got %+v", rep.Anomalies)
+	}
+}
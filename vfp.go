@@ -0,0 +1,129 @@
+package dbf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// A Currency is an amount in ten-thousandths of a unit, the fixed-point
+// representation Visual FoxPro uses for currency (Y) fields.
+type Currency int64
+
+// Float64 returns the amount in whole units.
+func (c Currency) Float64() float64 {
+	return float64(c) / 1e4
+}
+
+// String formats the amount with its four decimal places, as in "-12.5000".
+func (c Currency) String() string {
+	sign, n := "", uint64(c)
+	if c < 0 {
+		sign, n = "-", uint64(-c)
+	}
+	frac := strconv.FormatUint(n%1e4+1e4, 10)[1:]
+	return sign + strconv.FormatUint(n/1e4, 10) + "." + frac
+}
+
+// isVFP reports whether the table is a Visual FoxPro one, whose binary field
+// types are stored little-endian.
+func isVFP(version byte) bool {
+	return version >= 0x30 && version <= 0x32
+}
+
+// vfpLen gives the width of each Visual FoxPro binary field type.
+var vfpLen = map[byte]int{'I': 4, 'B': 8, 'Y': 8, 'T': 8}
+
+// decodeVFP decodes the binary field types of Visual FoxPro: I becomes an
+// int32, B (double) a float64, Y a Currency and T (datetime) a time.Time in
+// UTC.  A datetime of zero bytes is blank, and decodes to the zero Time as a
+// blank D field does.
+func decodeVFP(f Field, raw []byte) (interface{}, error) {
+	if len(raw) != vfpLen[f.Type] {
+		return nil, fmt.Errorf("field type '%c' must be %d bytes wide, not %d", f.Type, vfpLen[f.Type], len(raw))
+	}
+	switch f.Type {
+	case 'I':
+		return int32(binary.LittleEndian.Uint32(raw)), nil
+	case 'B':
+		return math.Float64frombits(binary.LittleEndian.Uint64(raw)), nil
+	case 'Y':
+		return Currency(binary.LittleEndian.Uint64(raw)), nil
+	}
+	// A datetime is the Julian day number followed by the milliseconds
+	// since midnight.
+	day := int32(binary.LittleEndian.Uint32(raw))
+	ms := int32(binary.LittleEndian.Uint32(raw[4:]))
+	if day == 0 && ms == 0 {
+		return time.Time{}, nil
+	}
+	return JulianEpoch.AddDate(0, 0, int(day)).Add(time.Duration(ms) * time.Millisecond), nil
+}
+
+// encodeVFP is the inverse of decodeVFP.  nil is stored as zero bytes.  A
+// datetime is stored as its wall-clock time, whatever its location, as a D
+// field stores its date.
+func encodeVFP(f Field, v interface{}) ([]byte, error) {
+	buf := make([]byte, vfpLen[f.Type])
+	if len(buf) != int(f.Len) {
+		return nil, fmt.Errorf("field type '%c' must be %d bytes wide, not %d", f.Type, len(buf), f.Len)
+	}
+	if v == nil {
+		return buf, nil
+	}
+	switch f.Type {
+	case 'I':
+		n, ok := widen(v).(int64)
+		if !ok {
+			return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+		} else if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("%d overflows field type '%c'", n, f.Type)
+		}
+		binary.LittleEndian.PutUint32(buf, uint32(n))
+	case 'B':
+		x, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+		}
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(x))
+	case 'Y':
+		var c Currency
+		switch v := widen(v).(type) {
+		case Currency:
+			c = v
+		case int64:
+			if v > math.MaxInt64/10000 || v < math.MinInt64/10000 {
+				return nil, fmt.Errorf("%d overflows field type '%c'", v, f.Type)
+			}
+			c = Currency(v * 1e4)
+		case float64:
+			x := math.Floor(v*1e4 + 0.5)
+			if math.IsNaN(x) || x >= math.MaxInt64 || x < math.MinInt64 {
+				return nil, fmt.Errorf("%v overflows field type '%c'", v, f.Type)
+			}
+			c = Currency(x)
+		default:
+			return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+		}
+		binary.LittleEndian.PutUint64(buf, uint64(c))
+	case 'T':
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("can't store a %T in field type '%c'", v, f.Type)
+		} else if t.IsZero() {
+			return buf, nil
+		}
+		wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		secs := wall.Unix() - JulianEpoch.Unix()
+		day := secs / 86400
+		if secs < 0 && secs%86400 != 0 {
+			day--
+		}
+		ms := (secs-day*86400)*1000 + int64(wall.Nanosecond())/1e6
+		binary.LittleEndian.PutUint32(buf, uint32(day))
+		binary.LittleEndian.PutUint32(buf[4:], uint32(ms))
+	}
+	return buf, nil
+}
@@ -0,0 +1,112 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestVFPTypes(t *testing.T) {
+	fields := []Field{mustField("QTY", 'I', 4, 0), mustField("RATE", 'B', 8, 2),
+		mustField("PRICE", 'Y', 8, 4), mustField("AT", 'T', 8, 0)}
+	at := time.Date(1999, 12, 31, 23, 59, 58, 0, time.UTC)
+	values := [][]interface{}{
+		{int32(-7), 0.125, Currency(123450), at},
+		{int32(math.MaxInt32), -2.5, Currency(-5), time.Time{}},
+	}
+
+	var records []string
+	for _, vals := range values {
+		rec := []byte{' '}
+		for i, f := range fields {
+			b, err := encode(0x30, f, vals[i])
+			if err != nil {
+				t.Fatalf("encoding %v: %s", vals[i], err)
+			}
+			rec = append(rec, b...)
+		}
+		records = append(records, string(rec))
+	}
+	plain := buildTable(fields, records...)
+	headerlen := 32 + 32*len(fields) + 1
+	data := append(append(append([]byte(nil), plain[:headerlen]...), make([]byte, 263)...), plain[headerlen:]...)
+	binary.LittleEndian.PutUint16(data[8:], uint16(headerlen+263))
+	data[0] = 0x30
+
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for i, vals := range values {
+		rec, err := tbl.Record(i)
+		if err != nil {
+			t.Fatalf("Record(%d): %s", i, err)
+		}
+		for j := range fields {
+			name := tbl.FieldName(j)
+			if !reflect.DeepEqual(rec[name], vals[j]) {
+				t.Errorf("Record(%d)[%s] = %#v, expected %#v", i, name, rec[name], vals[j])
+			}
+		}
+	}
+	if raw := records[0][1:5]; raw != "\xF9\xFF\xFF\xFF" {
+		t.Errorf("-7 stored as % x, expected little-endian two's complement", raw)
+	}
+
+	rec, _ := tbl.Record(0)
+	if price, err := rec.GetFloat("PRICE"); err != nil || price != 12.345 {
+		t.Errorf("GetFloat(PRICE) returned %v, %v, expected 12.345", price, err)
+	}
+
+	// A datetime is stored as its wall-clock time, as a date is.
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	f := fields[3]
+	b, err := encodeVFP(f, time.Date(1999, 12, 31, 23, 59, 58, 0, zone))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got, _ := decodeVFP(f, b); got != at {
+		t.Errorf("datetime in UTC-5 decoded as %v, expected %v", got, at)
+	}
+
+	// A dBase III table doesn't know the binary types.
+	if _, err := OpenTable(bytes.NewReader(plain), int64(len(plain))); err == nil {
+		t.Errorf("expected an error for binary types in a dBase III table")
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	for _, tc := range []struct {
+		c    Currency
+		want string
+	}{
+		{123450, "12.3450"}, {-5, "-0.0005"}, {0, "0.0000"}, {math.MinInt64, "-922337203685477.5808"},
+	} {
+		if got := tc.c.String(); got != tc.want {
+			t.Errorf("Currency(%d).String() = %q, expected %q", int64(tc.c), got, tc.want)
+		}
+	}
+
+	f := mustField("PRICE", 'Y', 8, 4)
+	for _, v := range []interface{}{12.345, 12, Currency(123450)} {
+		b, err := encodeVFP(f, v)
+		if err != nil {
+			t.Fatalf("encoding %v: %s", v, err)
+		}
+		want := Currency(123450)
+		if _, ok := v.(int); ok {
+			want = 120000
+		}
+		if got, _ := decodeVFP(f, b); got != want {
+			t.Errorf("%v round-tripped to %v, expected %v", v, got, want)
+		}
+	}
+	for _, v := range []interface{}{int64(math.MaxInt64 / 1000), -1e15, math.NaN()} {
+		if _, err := encodeVFP(f, v); err == nil {
+			t.Errorf("expected %v to overflow a currency field", v)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// WithWarnings makes the table report, through fn, the anomalies it puts up
+// with while reading instead of absorbing them silently: record lengths
+// that don't match the fields, a missing end-of-file marker, trailing junk
+// and numbers padded on the wrong side.  Counting them gives operators a
+// measure of upstream data quality.  fn is called from whichever goroutine
+// is reading, so it must be safe for concurrent use if the table is shared.
+func WithWarnings(fn func(Anomaly)) Option {
+	return func(t *Table) {
+		t.warnFn = fn
+	}
+}
+
+// WithLenient makes the table read dirty data rather than reject it: a field
+// value that can't be decoded, such as "N/A" in a numeric field, reads as
+// nil instead of failing the whole record, a record whose deleted flag is
+// neither ' ' nor '*' is read as a live record, and a header whose record
+// length is shorter than its fields is overruled by the fields.  Each such
+// repair is reported through WithWarnings, which can collect them:
+//
+//	var rep dbf.AnomalyReport
+//	t, err := dbf.Open(name, dbf.WithLenient(), dbf.WithWarnings(func(a dbf.Anomaly) {
+//		rep.Anomalies = append(rep.Anomalies, a)
+//	}))
+//
+// Values too large for the table's Limits are still an error.
+func WithLenient() Option {
+	return func(t *Table) {
+		t.lenient = true
+	}
+}
+
+func (t *Table) warn(sev Severity, offset int64, record int, field, format string, args ...interface{}) {
+	t.warnFn(Anomaly{sev, offset, record, field, fmt.Sprintf(format, args...)})
+}
+
+// checkLayout warns about the shape of the records once the header is read.
+func (t *Table) checkLayout() {
+	if t.warnFn == nil {
+		return
+	}
+	if int(t.headerRecordlen) < t.datalen {
+		t.warn(SeverityError, 10, -1, "", "header gives a record length of %d bytes, but the fields take up %d; using %d", t.headerRecordlen, t.datalen, t.recordlen)
+	} else if int(t.headerRecordlen) != t.datalen {
+		t.warn(SeverityWarning, 10, -1, "", "header gives a record length of %d bytes, but the fields take up %d", t.headerRecordlen, t.datalen)
+	}
+}
+
+// checkSize warns when the file's size doesn't match what the header
+// promises.  Streams have no size to check.
+func (t *Table) checkSize() {
+	if t.warnFn == nil {
+		return
+	}
+	end := t.recordOffset(t.nrec)
+	switch {
+	case t.size < end:
+		t.warn(SeverityError, t.size, -1, "", "file is truncated: header promises %d records, which take up %d bytes", t.nrec, end)
+	case t.size == end:
+		t.warn(SeverityInfo, t.size, -1, "", "missing end-of-file marker 0x1A")
+	default:
+		var eof [1]byte
+		if readFullAt(t.src, eof[:], end) == nil && eof[0] != 0x1A {
+			t.warn(SeverityWarning, end, -1, "", "expected end-of-file marker 0x1A, found %#x", eof[0])
+		}
+		if t.size > end+1 {
+			t.warn(SeverityWarning, end+1, -1, "", "%d bytes of unexpected data after the end of the table", t.size-end-1)
+		}
+	}
+}
+
+// checkRecord warns about values in raw record i that decode, but aren't
+// stored the way the format prescribes.
+func (t *Table) checkRecord(i int, buf []byte) {
+	if t.warnFn == nil {
+		return
+	}
+	pos := 1
+	for j, f := range t.fields {
+		raw := buf[pos : pos+int(f.Len)]
+		if (f.Type == 'N' || f.Type == 'F') && len(bytes.TrimSpace(raw)) > 0 && raw[len(raw)-1] == ' ' {
+			t.warn(SeverityInfo, t.recordOffset(i)+int64(pos), i, t.FieldName(j), "numeric value %q isn't right-aligned", raw)
+		}
+		pos += int(f.Len)
+	}
+}
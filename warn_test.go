@@ -0,0 +1,62 @@
+package dbf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWarnings(t *testing.T) {
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}, "   1alpha", " 2  bravo")
+	data = append(data, "junk"...)
+	var warnings []Anomaly
+	tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)), WithWarnings(func(a Anomaly) {
+		warnings = append(warnings, a)
+	}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for i := 0; i < tbl.Len(); i++ {
+		if _, err = tbl.Record(i); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if a := warnings[0]; a.Record != -1 || a.Offset != int64(len(data)-4) {
+		t.Errorf("expected a warning about trailing junk, got %+v", a)
+	}
+	if a := warnings[1]; a.Record != 1 || a.Field != "ID" || a.Severity != SeverityInfo {
+		t.Errorf("expected a warning about a padded number, got %+v", a)
+	}
+}
+
+func TestLenient(t *testing.T) {
+	data := buildTable([]Field{mustField("ID", 'N', 3, 0), mustField("NAME", 'C', 5, 0)}, " N/Aalpha", "?  2bravo")
+	if _, err := NewReaderFromBytes(data); err != nil {
+		t.Fatalf("%s", err)
+	}
+	var warnings []Anomaly
+	r, err := NewReaderFromBytes(data, WithLenient(), WithWarnings(func(a Anomaly) {
+		warnings = append(warnings, a)
+	}))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec, err := r.Read(0)
+	if err != nil || rec["ID"] != nil || rec["NAME"] != "alpha" {
+		t.Fatalf("Read(0) returned %v, %v", rec, err)
+	}
+	if rec, err = r.Read(1); err != nil || rec["ID"] != int64(2) {
+		t.Fatalf("Read(1) returned %v, %v", rec, err)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+	if a := warnings[0]; a.Record != 0 || a.Field != "ID" || a.Severity != SeverityError || a.Offset != r.recordOffset(0)+1 {
+		t.Errorf("expected a warning about an unparsable number, got %+v", a)
+	}
+	if a := warnings[1]; a.Record != 1 || a.Field != "" || a.Offset != r.recordOffset(1) {
+		t.Errorf("expected a warning about a deleted flag, got %+v", a)
+	}
+}
@@ -0,0 +1,604 @@
+package dbf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// A Writer creates a dBase III table, or a Visual FoxPro one.  Records are
+// written as they come, and the record count in the header is filled in by
+// Close.
+type Writer struct {
+	w         io.WriteSeeker
+	fields    []Field
+	names     []string
+	version   byte
+	nrec      uint32
+	recordlen uint16
+	codes     map[string]Codes
+	crypts    map[string]FieldCrypt
+	verify    bool
+	metrics   Metrics
+	overflow  Overflow
+	rounding  Rounding
+	nulls     NullPolicy
+	nullFlags int      // index of the _NullFlags field, or -1
+	file      *os.File // owned by the Writer, if made by Create
+	rename    string   // name the file gets on Close, in Atomic mode
+	failed    error    // first error from a write
+	closed    bool
+}
+
+// Field counts allowed by dBase and, with ClipperFields, by Clipper, whose
+// tables differ only in having a longer header.
+const (
+	maxFields        = 255
+	maxClipperFields = 1024
+)
+
+// A WriterOption configures NewWriter.
+type WriterOption func(*writerConfig)
+
+type writerConfig struct {
+	maxFields int
+	codes     map[string]Codes
+	crypts    map[string]FieldCrypt
+	verify    bool
+	metrics   Metrics
+	atomic    bool
+	overflow  Overflow
+	rounding  Rounding
+	nulls     NullPolicy
+	version   byte
+}
+
+// An Overflow says what a Writer does with a value too long for its field.
+type Overflow int
+
+const (
+	// OverflowError rejects the record with a *FieldError.  It is the
+	// default.
+	OverflowError Overflow = iota
+	// OverflowFit cuts text down to the width of its field and rounds
+	// numbers to as few decimal places as it takes to fit.  A number whose
+	// whole part doesn't fit is still an error.
+	OverflowFit
+)
+
+// WriteOverflow sets what the Writer does with values too long for their
+// fields.
+func WriteOverflow(o Overflow) WriterOption {
+	return func(c *writerConfig) {
+		c.overflow = o
+	}
+}
+
+// A Rounding says how a Writer rounds floats to the decimal places of a
+// numeric field.
+type Rounding int
+
+const (
+	// RoundNearest rounds the float's exact binary value to the nearest
+	// decimal, ties to even, as strconv does; 2.675, which is stored as
+	// 2.67499999..., becomes 2.67.  It is the default.
+	RoundNearest Rounding = iota
+	// RoundHalfUp rounds the shortest decimal that reads back as the float,
+	// with halves away from zero, as dBase's ROUND does: 2.675 becomes 2.68
+	// and -2.675 becomes -2.68.
+	RoundHalfUp
+	// RoundDown truncates the shortest decimal that reads back as the float
+	// toward zero: 2.679 becomes 2.67.
+	RoundDown
+)
+
+// WriteRounding sets how the Writer rounds floats to the decimal places of
+// their field.
+func WriteRounding(r Rounding) WriterOption {
+	return func(c *writerConfig) {
+		c.rounding = r
+	}
+}
+
+// round formats v with dec decimal places, rounded as r says.
+func (r Rounding) round(v float64, dec int) string {
+	if r == RoundNearest || math.IsNaN(v) || math.IsInf(v, 0) {
+		return strconv.FormatFloat(v, 'f', dec, 64)
+	}
+	x, _ := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(dec)), nil)
+	x.Mul(x, new(big.Rat).SetInt(scale))
+	q, m := new(big.Int).QuoRem(x.Num(), x.Denom(), new(big.Int)) // toward zero
+	if r == RoundHalfUp && new(big.Int).Lsh(m.Abs(m), 1).Cmp(x.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(x.Sign())))
+	}
+	return new(big.Rat).SetFrac(q, scale).FloatString(dec)
+}
+
+// A NullPolicy says how a Writer stores nil values.
+type NullPolicy int
+
+const (
+	// NullBlank stores nil as a blank field, or '?' in a logical field.
+	// It is the default.
+	NullBlank NullPolicy = iota
+	// NullZero stores nil as 0 in numeric fields, for consumers that read
+	// blank numbers as an error, and as a blank in other fields.
+	NullZero
+	// NullFlags marks every field nullable and records which values are
+	// nil in a _NullFlags field, as Visual FoxPro does, so that nil can be
+	// told apart from a blank value.  It needs VisualFoxPro.  The fields
+	// themselves are stored blank, and this package reads them back as
+	// such, returning the flags as the value of _NullFlags.
+	NullFlags
+)
+
+// WriteNulls sets how the Writer stores nil values.
+func WriteNulls(p NullPolicy) WriterOption {
+	return func(c *writerConfig) {
+		c.nulls = p
+	}
+}
+
+// A FieldError reports a value a Writer couldn't store in its field,
+// because it is of the wrong type or doesn't fit.
+type FieldError struct {
+	Field string
+	Value interface{}
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s: %s", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// writerTypes are the built-in field types a Writer can write, provided the
+// table's version supports them.  Memo fields need a memo file, which it
+// doesn't write.
+const writerTypes = "CNFDLTIBY"
+
+// VisualFoxPro makes NewWriter create a Visual FoxPro table, version 0x30,
+// which can hold datetime (T), integer (I), double (B) and currency (Y)
+// fields besides the character, numeric, date and logical fields of dBase
+// III.  Datetimes are stored as a Julian day number and milliseconds since
+// midnight, as dates are stored as YYYYMMDD, and zero times as blanks.
+func VisualFoxPro() WriterOption {
+	return func(c *writerConfig) {
+		c.version = 0x30
+	}
+}
+
+// ClipperFields lets NewWriter create tables with up to 1024 fields, as
+// Clipper does, instead of the 255 dBase allows.  This package reads such
+// tables, but dBase and most other software don't.
+func ClipperFields() WriterOption {
+	return func(c *writerConfig) {
+		c.maxFields = maxClipperFields
+	}
+}
+
+// NewWriter writes the header and field descriptors of a new table to w.
+// The schema can be built with NewField or SchemaFromStruct; field offsets
+// are filled in by the Writer.
+func NewWriter(w io.WriteSeeker, fields []Field, opts ...WriterOption) (*Writer, error) {
+	c := newWriterConfig(opts)
+	if c.atomic {
+		return nil, fmt.Errorf("Atomic needs a Writer made by Create")
+	}
+	return newWriter(w, fields, c)
+}
+
+func newWriterConfig(opts []WriterOption) writerConfig {
+	c := writerConfig{maxFields: maxFields, version: 0x03}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+func newWriter(w io.WriteSeeker, fields []Field, c writerConfig) (*Writer, error) {
+	if issues := Lint(fields, c.version); len(issues) > 0 {
+		return nil, fmt.Errorf("invalid schema: %s", issues[0])
+	}
+	for _, f := range fields {
+		if _, registered := registeredType(c.version, f.Type); !registered && strings.IndexByte(writerTypes, f.Type) < 0 {
+			return nil, fmt.Errorf("invalid schema: the Writer can't write fields of type '%c'", f.Type)
+		}
+	}
+	if len(fields) > c.maxFields {
+		return nil, fmt.Errorf("invalid schema: %d fields, more than the maximum of %d", len(fields), c.maxFields)
+	}
+	if _, ok := w.(io.ReaderAt); c.verify && !ok {
+		return nil, fmt.Errorf("StrictFoxPro needs a writer that can be read back, such as an *os.File")
+	}
+	if c.nulls == NullFlags && !isVFP(c.version) {
+		return nil, fmt.Errorf("NullFlags needs a VisualFoxPro table")
+	}
+	wr := &Writer{
+		w:         w,
+		version:   c.version,
+		recordlen: 1,
+		codes:     c.codes,
+		crypts:    c.crypts,
+		verify:    c.verify,
+		metrics:   c.metrics,
+		overflow:  c.overflow,
+		rounding:  c.rounding,
+		nulls:     c.nulls,
+		nullFlags: -1,
+	}
+	if c.nulls == NullFlags {
+		// Every field is nullable, with one bit of a trailing _NullFlags
+		// field each.
+		flags := Field{Type: '0', Len: uint8((len(fields) + 7) / 8)}
+		copy(flags.Name[:], "_NullFlags")
+		fields = append(fields[:len(fields):len(fields)], flags)
+		wr.nullFlags = len(fields) - 1
+	}
+	for _, f := range fields {
+		f.Offset = uint32(wr.recordlen)
+		wr.recordlen += uint16(f.Len)
+		wr.fields = append(wr.fields, f)
+		wr.names = append(wr.names, strings.TrimRight(string(f.Name[:]), "\x00"))
+	}
+	if err := wr.writeHeader(); err != nil {
+		return nil, err
+	}
+	for i, f := range wr.fields {
+		var desc bytes.Buffer
+		binary.Write(&desc, binary.LittleEndian, f)
+		b := desc.Bytes()
+		switch {
+		case i == wr.nullFlags:
+			b[18] = 0x05 // system field, binary
+		case wr.nullFlags >= 0:
+			b[18] = 0x02 // nullable
+		}
+		if _, err := w.Write(b); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := w.Write([]byte{0x0D}); err != nil {
+		return nil, err
+	}
+	if isVFP(wr.version) {
+		if _, err := w.Write(make([]byte, 263)); err != nil { // no database container
+			return nil, err
+		}
+	}
+	return wr, nil
+}
+
+// writeHeader writes the fixed 32-byte part of the header at the start of
+// the file.
+func (wr *Writer) writeHeader() error {
+	if _, err := wr.w.Seek(0, 0); err != nil {
+		return err
+	}
+	now := time.Now()
+	headerlen := 32 + 32*len(wr.fields) + 1
+	if isVFP(wr.version) {
+		headerlen += 263
+	}
+	h := header{
+		Version:   wr.version,
+		Year:      uint8(now.Year() - 1900),
+		Month:     uint8(now.Month()),
+		Day:       uint8(now.Day()),
+		Nrec:      wr.nrec,
+		Headerlen: uint16(headerlen),
+		Recordlen: wr.recordlen,
+	}
+	if err := binary.Write(wr.w, binary.LittleEndian, h); err != nil {
+		return err
+	}
+	_, err := wr.w.Write(make([]byte, 32-binary.Size(h)))
+	return err
+}
+
+// Write appends rec to the table.  Fields missing from rec are left blank;
+// values of the wrong type for their field, such as a string that isn't a
+// date in a date field or NaN in a numeric one, and values that don't fit
+// are reported as a *FieldError, and nothing is written.  WriteOverflow can
+// have values that don't fit shortened instead.
+func (wr *Writer) Write(rec Record) error {
+	return wr.WriteFrom(nil, rec)
+}
+
+// WriteFrom appends rec to the table, preserving the bytes of orig, the raw
+// record it was read from, as returned by Table.RawRecord.  Fields whose
+// value is unchanged from orig are copied as they were stored, padding,
+// leading zeros and sign formatting included, and the deleted flag is kept,
+// so that a record read and written back unchanged is identical byte for
+// byte.  Only modified fields are re-encoded.  With a nil orig, WriteFrom
+// is the same as Write.
+func (wr *Writer) WriteFrom(orig []byte, rec Record) error {
+	err := wr.writeFrom(orig, rec)
+	if err != nil && wr.failed == nil {
+		wr.failed = err
+	}
+	return err
+}
+
+func (wr *Writer) writeFrom(orig []byte, rec Record) error {
+	if orig != nil && len(orig) < int(wr.recordlen) {
+		return fmt.Errorf("original record is %d bytes long, expected %d", len(orig), wr.recordlen)
+	}
+	buf := make([]byte, 1, wr.recordlen)
+	buf[0] = ' '
+	if orig != nil {
+		buf[0] = orig[0]
+	}
+	var nulls []byte
+	if wr.nullFlags >= 0 {
+		nulls = make([]byte, wr.fields[wr.nullFlags].Len)
+	}
+	for i, f := range wr.fields {
+		v := rec[wr.names[i]]
+		switch {
+		case i == wr.nullFlags:
+			buf = append(buf, nulls...)
+			continue
+		case v == nil && wr.nulls == NullFlags:
+			nulls[i/8] |= 1 << uint(i%8)
+		case v == nil && wr.nulls == NullZero && (f.Type == 'N' || f.Type == 'F'):
+			v = 0.0
+		}
+		codes, crypt := wr.codes[wr.names[i]], wr.crypts[wr.names[i]]
+		if orig != nil {
+			stored := orig[len(buf) : len(buf)+int(f.Len)]
+			raw, err := stored, error(nil)
+			if crypt != nil {
+				raw, err = crypt.Decrypt(stored)
+			}
+			if err == nil {
+				if old, err := decode(wr.version, f, raw); err == nil && sameValue(codes.value(raw, old), v) {
+					buf = append(buf, stored...)
+					continue
+				}
+			}
+		}
+		if codes != nil {
+			v = codes.code(v)
+		}
+		if x, ok := v.(float64); ok && wr.rounding != RoundNearest && (f.Type == 'N' || f.Type == 'F') {
+			v = wr.rounding.round(x, int(f.DecimalPlaces))
+		}
+		if wr.overflow == OverflowFit {
+			v = fit(f, v)
+		}
+		b, err := encode(wr.version, f, v)
+		if err == nil && crypt != nil {
+			if b, err = crypt.Encrypt(b); err == nil && len(b) != int(f.Len) {
+				err = fmt.Errorf("encrypted value is %d bytes long, not %d", len(b), f.Len)
+			}
+		}
+		if err != nil {
+			return &FieldError{wr.names[i], v, err}
+		}
+		buf = append(buf, b...)
+	}
+	return wr.WriteRaw(buf)
+}
+
+// fit shortens v, if need be, to fit field f, as OverflowFit does.  Values
+// that can't be shortened are returned as they are.
+func fit(f Field, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if f.Type != 'C' || len(v) <= int(f.Len) {
+			return v
+		}
+		n := int(f.Len)
+		for n > 0 && !utf8.RuneStart(v[n]) {
+			n--
+		}
+		return v[:n]
+	case float64:
+		if f.Type != 'N' && f.Type != 'F' || math.IsNaN(v) || math.IsInf(v, 0) {
+			return v
+		}
+		for dec := int(f.DecimalPlaces); dec >= 0; dec-- {
+			if s := strconv.FormatFloat(v, 'f', dec, 64); len(s) <= int(f.Len) {
+				return s
+			}
+		}
+	}
+	return v
+}
+
+// sameValue reports whether a and b are the same field value, counting
+// integers of different types but the same value as equal.
+func sameValue(a, b interface{}) bool {
+	return reflect.DeepEqual(widen(a), widen(b))
+}
+
+// widen converts the integer types field values come in to int64.
+func widen(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	}
+	return v
+}
+
+// WriteRaw appends a record already encoded for the table's schema,
+// deleted flag included.
+func (wr *Writer) WriteRaw(raw []byte) error {
+	var err error
+	if wr.closed {
+		err = fmt.Errorf("write to closed Writer")
+	} else if len(raw) != int(wr.recordlen) {
+		err = fmt.Errorf("raw record is %d bytes long, expected %d", len(raw), wr.recordlen)
+	} else {
+		_, err = wr.w.Write(raw)
+	}
+	if err != nil {
+		if wr.failed == nil {
+			wr.failed = err
+		}
+		return err
+	}
+	wr.nrec++
+	if wr.metrics != nil {
+		wr.metrics.Add(MetricRecordsWritten, 1)
+	}
+	return nil
+}
+
+// Flush patches the current record count into the header, so that the
+// table written so far can be loaded if the program stops before Close.  If
+// the underlying writer has a Sync method, as *os.File does, the records are
+// synced before the header and the header after them, so that after a crash
+// the header never counts records that didn't reach the disk.  The table
+// lacks its end-of-file marker until Close, which readers tolerate.
+func (wr *Writer) Flush() error {
+	if wr.closed {
+		return fmt.Errorf("flush of closed Writer")
+	}
+	s, canSync := wr.w.(interface {
+		Sync() error
+	})
+	if canSync {
+		if err := s.Sync(); err != nil {
+			return err
+		}
+	}
+	if err := wr.writeHeader(); err != nil {
+		return err
+	}
+	if _, err := wr.w.Seek(0, 2); err != nil {
+		return err
+	}
+	if canSync {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close writes the end-of-file marker and patches the record count and
+// modification date into the header.  It doesn't close the underlying
+// writer, except for a Writer made by Create, which closes its file.
+func (wr *Writer) Close() error {
+	if wr.closed {
+		return nil
+	}
+	wr.closed = true
+	err := wr.finish()
+	if wr.file == nil {
+		return err
+	}
+	if err == nil && wr.rename != "" && wr.failed != nil {
+		err = fmt.Errorf("not renaming a table with a failed write: %s", wr.failed)
+	} else if err == nil && wr.rename != "" {
+		err = wr.file.Sync()
+	}
+	if cerr := wr.file.Close(); err == nil {
+		err = cerr
+	}
+	if wr.rename != "" {
+		if err == nil {
+			err = os.Rename(wr.file.Name(), wr.rename)
+		}
+		if err != nil {
+			os.Remove(wr.file.Name())
+		}
+	}
+	return err
+}
+
+// Abort abandons the table without finishing it.  A Writer made by Create
+// closes its file, and in Atomic mode removes it, leaving the named file as
+// it was; other Writers leave the underlying writer as it is.  Deferring
+// Abort, then calling Close once every record has been written, publishes
+// the table only if the program got that far.  Abort after Close does
+// nothing.
+func (wr *Writer) Abort() error {
+	if wr.closed {
+		return nil
+	}
+	wr.closed = true
+	if wr.file == nil {
+		return nil
+	}
+	err := wr.file.Close()
+	if wr.rename != "" {
+		if rerr := os.Remove(wr.file.Name()); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// finish completes the table Close is closing.
+func (wr *Writer) finish() error {
+	if _, err := wr.w.Write([]byte{0x1A}); err != nil {
+		return err
+	}
+	if err := wr.writeHeader(); err != nil {
+		return err
+	}
+	if wr.verify {
+		return wr.verifyFoxPro()
+	}
+	_, err := wr.w.Seek(0, 2)
+	return err
+}
+
+// A RecordTemplate is a record pre-filled with a default for every field of
+// a schema, so that callers appending many records only have to fill in the
+// fields that differ.
+type RecordTemplate struct {
+	rec Record
+}
+
+// NewRecordTemplate builds a template for the schema.  Fields named in
+// defaults take that value; every other field gets the value a blank field
+// decodes to.  A default that doesn't fit its field is an error.
+func NewRecordTemplate(fields []Field, defaults Record) (*RecordTemplate, error) {
+	rec := make(Record, len(fields))
+	for _, f := range fields {
+		name := strings.TrimRight(string(f.Name[:]), "\x00")
+		v, ok := defaults[name]
+		if !ok {
+			var err error
+			if v, err = decode(0x03, f, bytes.Repeat([]byte{' '}, int(f.Len))); err != nil {
+				return nil, fmt.Errorf("field %s: %s", name, err)
+			}
+		} else if _, err := encode(0x03, f, v); err != nil {
+			return nil, fmt.Errorf("default for field %s: %s", name, err)
+		}
+		rec[name] = v
+	}
+	for name := range defaults {
+		if _, ok := rec[name]; !ok {
+			return nil, fmt.Errorf("default given for unknown field %s", name)
+		}
+	}
+	return &RecordTemplate{rec}, nil
+}
+
+// New returns a fresh copy of the template's record, which the caller is
+// free to modify.
+func (t *RecordTemplate) New() Record {
+	rec := make(Record, len(t.rec))
+	for k, v := range t.rec {
+		rec[k] = v
+	}
+	return rec
+}
@@ -0,0 +1,427 @@
+package dbf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeSeeker is an in-memory io.WriteSeeker.
+type writeSeeker struct {
+	buf []byte
+	pos int
+}
+
+func (ws *writeSeeker) Write(p []byte) (int, error) {
+	if need := ws.pos + len(p); need > len(ws.buf) {
+		ws.buf = append(ws.buf, make([]byte, need-len(ws.buf))...)
+	}
+	copy(ws.buf[ws.pos:], p)
+	ws.pos += len(p)
+	return len(p), nil
+}
+
+func (ws *writeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += int64(ws.pos)
+	case io.SeekEnd:
+		offset += int64(len(ws.buf))
+	}
+	ws.pos = int(offset)
+	return offset, nil
+}
+
+func TestWriter(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 5, 0), mustField("NAME", 'C', 10, 0), mustField("AMOUNT", 'N', 8, 2)}
+	var ws writeSeeker
+	w, err := NewWriter(&ws, fields)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	records := []Record{
+		{"ID": int64(1), "NAME": "alpha", "AMOUNT": 1.5},
+		{"ID": int64(22), "NAME": "bravo", "AMOUNT": -20.25},
+	}
+	for _, rec := range records {
+		if err = w.Write(rec); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	if err = w.Write(Record{"NAME": "much too long"}); err == nil {
+		t.Fatalf("expected an error for an over-long value")
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	if ws.buf[len(ws.buf)-1] != 0x1A {
+		t.Fatalf("missing end-of-file marker")
+	}
+	r, err := NewReader(bytes.NewReader(ws.buf))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if r.Length != 2 {
+		t.Fatalf("wrong Length: got %d, expected 2", r.Length)
+	}
+	for i, expected := range records {
+		actual, err := r.Read(uint16(i))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("Read(%d) returned %#v, expected %#v", i, actual, expected)
+		}
+	}
+	if rep, _ := r.Validate(); len(rep.Anomalies) != 0 {
+		t.Fatalf("written table has anomalies: %+v", rep.Anomalies)
+	}
+}
+
+func TestWriterFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w, err := NewWriter(f, []Field{mustField("NAME", 'C', 10, 0)})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	w.Write(Record{"NAME": "alpha"})
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	tbl, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer tbl.Close()
+	if rec, err := tbl.Record(0); err != nil || rec["NAME"] != "alpha" {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	f, err := ioutil.TempFile("", "dbf")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w, err := NewWriter(f, []Field{mustField("NAME", 'C', 10, 0)})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	w.Write(Record{"NAME": "alpha"})
+	if err = w.Flush(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	w.Write(Record{"NAME": "bravo"}) // not flushed
+	tbl, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer tbl.Close()
+	if tbl.Len() != 1 {
+		t.Fatalf("expected the flushed record only, got %d", tbl.Len())
+	}
+	if rec, err := tbl.Record(0); err != nil || rec["NAME"] != "alpha" {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Flush(); err == nil {
+		t.Fatalf("expected an error flushing a closed Writer")
+	}
+}
+
+func TestWriterInvalidSchema(t *testing.T) {
+	var ws writeSeeker
+	if _, err := NewWriter(&ws, []Field{mustField("ID", 'N', 3, 0), mustField("ID", 'C', 3, 0)}); err == nil {
+		t.Fatalf("expected an error for duplicate field names")
+	}
+}
+
+func TestWriterClipperFields(t *testing.T) {
+	var fields []Field
+	for i := 0; i < 300; i++ {
+		fields = append(fields, mustField(fmt.Sprintf("F%d", i), 'N', 3, 0))
+	}
+	var ws writeSeeker
+	if _, err := NewWriter(&ws, fields); err == nil {
+		t.Fatalf("expected an error for 300 fields without ClipperFields")
+	}
+	w, err := NewWriter(&ws, fields, ClipperFields())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"F0": 1, "F299": 299}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	r, err := NewReaderFromBytes(ws.buf)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if n := len(r.Fields()); n != 300 {
+		t.Fatalf("expected 300 fields, got %d", n)
+	}
+	if rec, err := r.Read(0); err != nil || rec["F0"] != int64(1) || rec["F150"] != int64(0) || rec["F299"] != int64(299) {
+		t.Fatalf("Read(0) returned %v, %v", rec, err)
+	}
+}
+
+func TestRecordTemplate(t *testing.T) {
+	fields := []Field{mustField("ID", 'N', 5, 0), mustField("REGION", 'C', 4, 0), mustField("ACTIVE", 'L', 1, 0)}
+	tmpl, err := NewRecordTemplate(fields, Record{"REGION": "EU"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	rec := tmpl.New()
+	rec["ID"] = 7
+	if expected := (Record{"ID": 7, "REGION": "EU", "ACTIVE": nil}); !reflect.DeepEqual(rec, expected) {
+		t.Fatalf("expected %v, got %v", expected, rec)
+	}
+	if rec = tmpl.New(); rec["ID"] != int64(0) {
+		t.Fatalf("expected a fresh record from New, got %v", rec)
+	}
+
+	if _, err = NewRecordTemplate(fields, Record{"REGION": "too long"}); err == nil {
+		t.Fatalf("expected an error for a default that doesn't fit")
+	}
+	if _, err = NewRecordTemplate(fields, Record{"NOPE": 1}); err == nil {
+		t.Fatalf("expected an error for a default of an unknown field")
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	odd := buildTable([]Field{
+		mustField("ID", 'N', 5, 0),
+		mustField("NAME", 'C', 8, 0),
+		mustField("AMOUNT", 'N', 8, 2),
+		mustField("DAY", 'D', 8, 0),
+		mustField("OK", 'L', 1, 0),
+		mustField("RATE", 'F', 6, 0),
+	},
+		" 00042  lead     +12.520110726t1.5   ",
+		"*-0001x       -0.10           ?    .5",
+		"     7               000000000       ",
+	)
+	for _, data := range [][]byte{odd, testData} {
+		tbl, err := OpenTable(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		var ws writeSeeker
+		w, err := NewWriter(&ws, tbl.fields)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		for i := 0; i < tbl.Len(); i++ {
+			raw, err := tbl.RawRecord(i)
+			if err != nil {
+				break // testData is cut short of the records its header promises
+			}
+			rec, err := tbl.decodeRecord(i, raw)
+			if err != nil {
+				t.Fatalf("record %d: %s", i, err)
+			}
+			before := len(ws.buf)
+			if err = w.WriteFrom(raw, rec); err != nil {
+				t.Fatalf("record %d: %s", i, err)
+			}
+			if written := ws.buf[before:]; !bytes.Equal(written, raw) {
+				t.Fatalf("record %d: read %q, wrote back %q", i, raw, written)
+			}
+		}
+	}
+
+	tbl, _ := OpenTable(bytes.NewReader(odd), int64(len(odd)))
+	raw, _ := tbl.RawRecord(0)
+	rec, _ := tbl.decodeRecord(0, raw)
+	rec["NAME"] = "changed"
+	var ws writeSeeker
+	w, _ := NewWriter(&ws, tbl.fields)
+	before := len(ws.buf)
+	if err := w.WriteFrom(raw, rec); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if expected := " 00042changed    +12.520110726t1.5   "; string(ws.buf[before:]) != expected {
+		t.Fatalf("expected only NAME to change, got %q", ws.buf[before:])
+	}
+}
+
+func TestWriterWrongTypes(t *testing.T) {
+	fields := []Field{mustField("C", 'C', 5, 0), mustField("N", 'N', 8, 2), mustField("D", 'D', 8, 0), mustField("L", 'L', 1, 0)}
+	wr, err := NewWriter(&writeSeeker{}, fields)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, rec := range []Record{
+		{"C": 12}, {"C": true},
+		{"N": true}, {"N": "abc"}, {"N": math.NaN()}, {"N": math.Inf(1)}, {"N": "Inf"}, {"N": "1e5"}, {"N": "0x1p4"}, {"N": "."}, {"N": time.Now()},
+		{"D": "hello"}, {"D": 20110726},
+		{"L": "yes"}, {"L": 1},
+	} {
+		if err := wr.Write(rec); err == nil {
+			t.Errorf("expected an error writing %v", rec)
+		}
+	}
+	for _, rec := range []Record{
+		{"C": "abc", "N": "12.50", "D": "20110726", "L": "T"},
+		{"C": "", "N": int64(3), "D": time.Date(2011, 7, 26, 0, 0, 0, 0, time.UTC), "L": false},
+		{"N": " ", "D": ""},
+	} {
+		if err := wr.Write(rec); err != nil {
+			t.Errorf("writing %v: %s", rec, err)
+		}
+	}
+}
+
+func TestWriterOverflow(t *testing.T) {
+	fields := []Field{mustField("NAME", 'C', 5, 0), mustField("AMOUNT", 'N', 5, 2)}
+	var ws writeSeeker
+	w, err := NewWriter(&ws, fields)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = w.Write(Record{"NAME": "much too long"})
+	if e, ok := err.(*FieldError); !ok || e.Field != "NAME" {
+		t.Fatalf("expected a *FieldError for NAME, got %v", err)
+	}
+
+	ws = writeSeeker{}
+	if w, err = NewWriter(&ws, fields, WriteOverflow(OverflowFit)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"NAME": "Zürich", "AMOUNT": 123.456}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"AMOUNT": 123456.0}); err == nil {
+		t.Fatalf("expected an error for a number whose whole part doesn't fit")
+	}
+	w.Close()
+	if got := string(ws.buf[len(ws.buf)-11 : len(ws.buf)-1]); got != "Züri123.5" {
+		t.Fatalf("wrong fitted record %q", got)
+	}
+}
+
+func TestWriterVisualFoxPro(t *testing.T) {
+	fields := []Field{mustField("DAY", 'D', 8, 0), mustField("STAMP", 'T', 8, 0), mustField("N", 'I', 4, 0)}
+	if _, err := NewWriter(&writeSeeker{}, fields); err == nil {
+		t.Fatalf("expected an error for VFP fields in a dBase III table")
+	}
+	var ws writeSeeker
+	w, err := NewWriter(&ws, fields, VisualFoxPro())
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	noon := time.Date(2000, 1, 1, 12, 0, 0, 5e6, time.UTC)
+	if err = w.Write(Record{"DAY": noon, "STAMP": noon, "N": 7}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"DAY": time.Time{}, "STAMP": time.Time{}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	records := ws.buf[len(ws.buf)-1-2*21 : len(ws.buf)-1]
+	want := append([]byte(" 20000101"), 0x59, 0x68, 0x25, 0x00, 0x05, 0x2E, 0x93, 0x02, 7, 0, 0, 0)
+	want = append(want, "         "...)
+	want = append(want, make([]byte, 12)...)
+	if !bytes.Equal(records, want) {
+		t.Fatalf("wrong records:\n got % x\nwant % x", records, want)
+	}
+	tbl, err := OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec, err := tbl.Record(0); err != nil || !rec["STAMP"].(time.Time).Equal(noon) || widen(rec["N"]) != int64(7) {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+	if rep, err := tbl.CheckFoxPro(); err != nil || len(rep.Anomalies) != 0 {
+		t.Fatalf("CheckFoxPro returned %+v, %v", rep, err)
+	}
+}
+
+func TestWriterRounding(t *testing.T) {
+	fields := []Field{mustField("AMOUNT", 'N', 7, 2)}
+	for _, c := range []struct {
+		rounding Rounding
+		want     string
+	}{
+		{RoundNearest, "   2.67  -2.67   0.12"},
+		{RoundHalfUp, "   2.68  -2.68   0.13"},
+		{RoundDown, "   2.67  -2.67   0.12"},
+	} {
+		var ws writeSeeker
+		w, err := NewWriter(&ws, fields, WriteRounding(c.rounding))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		for _, v := range []float64{2.675, -2.675, 0.125} {
+			if err = w.Write(Record{"AMOUNT": v}); err != nil {
+				t.Fatalf("%s", err)
+			}
+		}
+		w.Close()
+		records := string(ws.buf[len(ws.buf)-1-3*8 : len(ws.buf)-1])
+		if got := records[1:8] + records[9:16] + records[17:]; got != c.want {
+			t.Errorf("rounding %d: got %q, want %q", c.rounding, got, c.want)
+		}
+	}
+}
+
+func TestWriterNulls(t *testing.T) {
+	var ws writeSeeker
+	w, err := NewWriter(&ws, []Field{mustField("AMOUNT", 'N', 5, 2)}, WriteNulls(NullZero))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	w.Write(Record{})
+	w.Close()
+	if got := string(ws.buf[len(ws.buf)-6 : len(ws.buf)-1]); got != " 0.00" {
+		t.Fatalf("wrong zeroed value %q", got)
+	}
+
+	fields := []Field{mustField("NAME", 'C', 3, 0), mustField("N", 'N', 3, 0), mustField("STAMP", 'T', 8, 0)}
+	if _, err = NewWriter(&writeSeeker{}, fields, WriteNulls(NullFlags)); err == nil {
+		t.Fatalf("expected an error for NullFlags in a dBase III table")
+	}
+	ws = writeSeeker{}
+	if w, err = NewWriter(&ws, fields, VisualFoxPro(), WriteNulls(NullFlags)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err = w.Write(Record{"N": 5}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	w.Close()
+	tbl, err := OpenTable(bytes.NewReader(ws.buf), int64(len(ws.buf)))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if rec, err := tbl.Record(0); err != nil || !bytes.Equal(rec["_NullFlags"].([]byte), []byte{0x05}) {
+		t.Fatalf("Record(0) returned %v, %v", rec, err)
+	}
+	if rep, err := tbl.CheckFoxPro(); err != nil || len(rep.Anomalies) != 0 {
+		t.Fatalf("CheckFoxPro returned %+v, %v", rep, err)
+	}
+}